@@ -1,10 +1,23 @@
 package api
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
 // Aws is the configuration structure for the AWS vault provider
 type Aws struct {
 	// Description (Optional) A description to provide to the secret
 	Description string `yaml:"description,omitempty"`
 
+	// Endpoint (Optional) The URL of the Secrets Manager endpoint such as a VPC endpoint or a local emulator
+	Endpoint string `yaml:"endpoint,omitempty"`
+
 	// Region (Required) The region where AWS Secrets Manager is hosted
 	Region string `yaml:"region,omitempty"`
 
@@ -37,13 +50,73 @@ type GCP struct {
 
 // HCVault is the configuration structure for the Hashicorp Vault provider
 type HCVault struct {
-	// EnvironmentTokenName (Required) The name of the environment variable that currently holds
-	// the Vault token
+	// AuthMethod (Optional) The auth method to log in with: 'token', 'approle', 'jwt',
+	// 'kubernetes', 'cert' or 'userpass'. If omitted 'token' is used instead
+	AuthMethod string `yaml:"authMethod,omitempty"`
+
+	// AuthMount (Optional) The path the auth method is mounted at
+	// if omitted the name of the auth method is used instead
+	AuthMount string `yaml:"authMount,omitempty"`
+
+	// AuthRole (Optional) The role to log in as with the 'jwt' and 'kubernetes' auth methods,
+	// or the name of the certificate role with the 'cert' auth method
+	AuthRole string `yaml:"authRole,omitempty"`
+
+	// CACert (Optional) The path to the PEM encoded CA certificate that verifies the certificate
+	// of Vault. If omitted VAULT_CACERT is used instead
+	CACert string `yaml:"caCert,omitempty"`
+
+	// ClientCert (Optional) The path to the PEM encoded client certificate that's presented to Vault
+	// such as for the 'cert' auth method. If omitted VAULT_CLIENT_CERT is used instead
+	ClientCert string `yaml:"clientCert,omitempty"`
+
+	// ClientKey (Optional) The path to the PEM encoded private key of the client certificate
+	// if omitted VAULT_CLIENT_KEY is used instead
+	ClientKey string `yaml:"clientKey,omitempty"`
+
+	// EnvironmentTokenName (Optional) The name of the environment variable that currently holds
+	// the Vault token for the 'token' auth method. If omitted or empty VAULT_TOKEN is used instead
 	EnvironmentTokenName string `yaml:"environmentTokenName,omitempty"`
 
+	// InsecureSkipVerify (Optional) Skip the verification of the certificate of Vault
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify,omitempty"`
+
+	// JWTEnv (Optional) The name of the environment variable that holds the JWT for the 'jwt'
+	// or 'kubernetes' auth method
+	JWTEnv string `yaml:"jwtEnv,omitempty"`
+
+	// JWTFile (Optional) The path to the file that holds the JWT for the 'jwt' or 'kubernetes'
+	// auth method. The 'kubernetes' auth method uses the service account token if omitted
+	JWTFile string `yaml:"jwtFile,omitempty"`
+
 	// KeyVaultPath (Required) The name of the Key Vault store inside of Vault
 	KeyVaultPath string `yaml:"keyVaultPath,omitempty"`
 
+	// KVVersion (Optional) The version of the KV secrets engine of the mount, '1' or '2'
+	// if omitted the version is detected instead
+	KVVersion string `yaml:"kvVersion,omitempty"`
+
+	// Namespace (Optional) The Vault Enterprise namespace of the mount and the auth method
+	// if omitted VAULT_NAMESPACE is used instead
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// PasswordEnv (Optional) The name of the environment variable that holds the password
+	// for the 'userpass' auth method
+	PasswordEnv string `yaml:"passwordEnv,omitempty"`
+
+	// RoleId (Optional) The role ID for the 'approle' auth method
+	RoleId string `yaml:"roleId,omitempty"`
+
+	// RoleIdFile (Optional) The path to the file that holds the role ID for the 'approle' auth method
+	RoleIdFile string `yaml:"roleIdFile,omitempty"`
+
+	// SecretIdEnv (Optional) The name of the environment variable that holds the secret ID
+	// for the 'approle' auth method
+	SecretIdEnv string `yaml:"secretIdEnv,omitempty"`
+
+	// SecretIdFile (Optional) The path to the file that holds the secret ID for the 'approle' auth method
+	SecretIdFile string `yaml:"secretIdFile,omitempty"`
+
 	// SecretName (Optional) The name of the secret stored inside of Vault
 	// if omitted Terracreds will use the hostname value instead
 	SecretName string `yaml:"secretName,omitempty"`
@@ -51,20 +124,168 @@ type HCVault struct {
 	// SecretPath (Required) The path to the secret itself inside of Vault
 	SecretPath string `yaml:"secretPath,omitempty"`
 
-	// VaultUri (Required) The URL of the Vault instance including its port
+	// TLSServerName (Optional) The name that's used to verify the certificate of Vault
+	// if omitted VAULT_TLS_SERVER_NAME or the host of VaultUri is used instead
+	TLSServerName string `yaml:"tlsServerName,omitempty"`
+
+	// TokenFile (Optional) The path to the file that holds the Vault token for the 'token' auth
+	// method such as the sink of a Vault Agent. It takes precedence over EnvironmentTokenName
+	TokenFile string `yaml:"tokenFile,omitempty"`
+
+	// Username (Optional) The username for the 'userpass' auth method
+	Username string `yaml:"username,omitempty"`
+
+	// VaultUri (Optional) The URL of the Vault instance including its port
+	// if omitted VAULT_ADDR is used instead
 	VaultUri string `yaml:"vaultUri,omitempty"`
 }
 
+// Profile is the configuration structure for a named set of vault provider settings and host rules
+// that replace the top-level ones when the profile is selected
+type Profile struct {
+	Providers `yaml:",inline"`
+	Hosts     []Host `yaml:"hosts,omitempty"`
+}
+
+// Providers holds the configuration blocks for the vault providers
+type Providers struct {
+	Aws        Aws     `yaml:"aws,omitempty"`
+	Azure      Azure   `yaml:"azure,omitempty"`
+	Chain      Chain   `yaml:"chain,omitempty"`
+	HashiVault HCVault `yaml:"hcvault,omitempty"`
+	GCP        GCP     `yaml:"gcp,omitempty"`
+
+	// Extra holds the configuration blocks of vault providers that don't have a field above,
+	// such as providers registered by a fork of Terracreds, keyed by the provider's block key
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// Blocks returns the non-empty configuration blocks keyed by their key in the configuration file
+func (providers *Providers) Blocks() (map[string]map[string]interface{}, error) {
+	bytes, err := yaml.Marshal(providers)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	err = yaml.Unmarshal(bytes, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make(map[string]map[string]interface{}, len(values))
+	for key, value := range values {
+		block, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the '%s' block must be a map of settings", key)
+		}
+
+		if len(block) == 0 {
+			continue
+		}
+
+		blocks[key] = make(map[string]interface{}, len(block))
+		for name, setting := range block {
+			blocks[key][fmt.Sprintf("%v", name)] = setting
+		}
+	}
+
+	return blocks, nil
+}
+
+// Block returns the configuration block with the key such as 'aws', or nil when it isn't set
+func (providers *Providers) Block(key string) (map[string]interface{}, error) {
+	blocks, err := providers.Blocks()
+	if err != nil {
+		return nil, err
+	}
+
+	return blocks[key], nil
+}
+
+// SetBlock replaces the configuration block with the key. An empty block removes it
+func (providers *Providers) SetBlock(key string, block map[string]interface{}) error {
+	blocks, err := providers.Blocks()
+	if err != nil {
+		return err
+	}
+
+	delete(blocks, key)
+	if len(block) > 0 {
+		blocks[key] = block
+	}
+
+	bytes, err := yaml.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+
+	updated := Providers{}
+	err = yaml.Unmarshal(bytes, &updated)
+	if err != nil {
+		return err
+	}
+
+	*providers = updated
+	return nil
+}
+
+// Chain is the configuration structure for an ordered fallback chain of vault providers
+type Chain struct {
+	// Providers (Required) The names of the vault providers in the order they are read from.
+	// Valid names are the providers registered with the vault package, as returned by
+	// vault.Names, and each provider other than 'keyring' needs its own configuration block
+	Providers []string `yaml:"providers,omitempty"`
+
+	// Primary (Optional) The name of the vault provider that secrets are written to.
+	// If omitted the first provider in the chain is used instead
+	Primary string `yaml:"primary,omitempty"`
+}
+
 // Config struct for terracreds custom configuration
 type Config struct {
-	Logging    Logging  `yaml:"logging"`
-	Aws        Aws      `yaml:"aws,omitempty"`
-	Azure      Azure    `yaml:"azure,omitempty"`
-	HashiVault HCVault  `yaml:"hcvault,omitempty"`
-	GCP        GCP      `yaml:"gcp,omitempty"`
-	Secrets    []string `yaml:"secrets,omitempty"`
+	Logging   Logging `yaml:"logging"`
+	Providers `yaml:",inline"`
+	Hosts     []Host              `yaml:"hosts,omitempty"`
+	Profiles  map[string]*Profile `yaml:"profiles,omitempty"`
+	Secrets   []string            `yaml:"secrets,omitempty"`
+
+	// Strict (Optional) Return an error from 'get' when no credential is found
+	// instead of the empty '{}' response Terraform expects for unknown hosts
+	Strict bool `yaml:"strict,omitempty"`
+
+	// Timeouts (Optional) The maximum time each vault operation may take
+	Timeouts Timeouts `yaml:"timeouts,omitempty"`
+}
+
+// Host is the configuration structure for a rule that routes matching hostnames
+// to a vault provider
+type Host struct {
+	// Pattern (Required) A glob pattern matched against the normalized hostname
+	// such as '*.tfe.corp.example' or 'app.terraform.io'
+	Pattern string `yaml:"pattern"`
+
+	// SecretName (Optional) A template for the name of the secret where '{{.Hostname}}'
+	// is replaced by the hostname. If omitted Terracreds will use the hostname value instead
+	SecretName string `yaml:"secretName,omitempty"`
+
+	// Providers (Optional) The vault provider block used for matching hostnames.
+	// If omitted the local operating system's credential vault is used instead
+	Providers `yaml:",inline"`
+}
+
+// Timeouts is the configuration structure for the maximum time a vault operation may take such
+// as '10s' or '1m'. An operation that's omitted or set to zero uses DefaultTimeout instead
+type Timeouts struct {
+	Create time.Duration `yaml:"create,omitempty"`
+	Delete time.Duration `yaml:"delete,omitempty"`
+	Get    time.Duration `yaml:"get,omitempty"`
+	List   time.Duration `yaml:"list,omitempty"`
 }
 
+// DefaultTimeout is the maximum time a vault operation may take when no timeout is configured
+const DefaultTimeout = 30 * time.Second
+
 // Logging struct defines the parameters for logging
 type Logging struct {
 	Enabled bool   `yaml:"enabled"`
@@ -75,3 +296,125 @@ type Logging struct {
 type CredentialResponse struct {
 	Token string `json:"token"`
 }
+
+// UnmarshalYAML decodes the configuration file. Blocks of vault providers that don't have a field
+// in Providers are kept in Providers.Extra
+func (config *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	return unmarshalProviders(unmarshal, (*plain)(config), &config.Providers)
+}
+
+// MarshalYAML encodes the configuration file including the blocks in Providers.Extra
+func (config Config) MarshalYAML() (interface{}, error) {
+	type plain Config
+	return marshalProviders(plain(config), config.Providers.Extra)
+}
+
+// UnmarshalYAML decodes a host rule. Blocks of vault providers that don't have a field
+// in Providers are kept in Providers.Extra
+func (host *Host) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Host
+	return unmarshalProviders(unmarshal, (*plain)(host), &host.Providers)
+}
+
+// MarshalYAML encodes a host rule including the blocks in Providers.Extra
+func (host Host) MarshalYAML() (interface{}, error) {
+	type plain Host
+	return marshalProviders(plain(host), host.Providers.Extra)
+}
+
+// UnmarshalYAML decodes a profile. Blocks of vault providers that don't have a field
+// in Providers are kept in Providers.Extra
+func (profile *Profile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Profile
+	return unmarshalProviders(unmarshal, (*plain)(profile), &profile.Providers)
+}
+
+// MarshalYAML encodes a profile including the blocks in Providers.Extra
+func (profile Profile) MarshalYAML() (interface{}, error) {
+	type plain Profile
+	return marshalProviders(plain(profile), profile.Providers.Extra)
+}
+
+// unmarshalProviders decodes a value that embeds Providers. The yaml package ignores the inline
+// Extra map of an embedded struct, so Providers is decoded on its own and the keys that belong
+// to the other fields of the value are removed from Extra
+func unmarshalProviders(unmarshal func(interface{}) error, value interface{}, providers *Providers) error {
+	err := unmarshal(value)
+	if err != nil {
+		return err
+	}
+
+	err = unmarshal(providers)
+	if err != nil {
+		return err
+	}
+
+	for key := range fieldKeys(reflect.TypeOf(value).Elem()) {
+		delete(providers.Extra, key)
+	}
+
+	if len(providers.Extra) == 0 {
+		providers.Extra = nil
+	}
+
+	return nil
+}
+
+// marshalProviders encodes a value that embeds Providers followed by the blocks in extra
+func marshalProviders(value interface{}, extra map[string]interface{}) (interface{}, error) {
+	if len(extra) == 0 {
+		return value, nil
+	}
+
+	bytes, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.MapSlice
+	err = yaml.Unmarshal(bytes, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		node = append(node, yaml.MapItem{Key: key, Value: extra[key]})
+	}
+
+	return node, nil
+}
+
+// fieldKeys returns the keys of the fields of a struct type including the fields of inline structs
+func fieldKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+
+		if len(tag) > 1 && tag[1] == "inline" {
+			if field.Type.Kind() == reflect.Struct {
+				for key := range fieldKeys(field.Type) {
+					keys[key] = true
+				}
+			}
+
+			continue
+		}
+
+		key := tag[0]
+		if key == "" {
+			key = strings.ToLower(field.Name)
+		}
+
+		keys[key] = true
+	}
+
+	return keys
+}
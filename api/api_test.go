@@ -0,0 +1,102 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const extraConfig = `logging:
+  enabled: false
+  path: ""
+aws:
+  region: us-west-2
+hosts:
+- pattern: '*.corp.example'
+  acme:
+    url: https://acme.corp.example
+profiles:
+  dev:
+    acme:
+      url: https://acme.dev.example
+acme:
+  url: https://acme.example
+`
+
+func TestConfigExtraProviders(t *testing.T) {
+	var cfg Config
+	err := yaml.Unmarshal([]byte(extraConfig), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Aws.Region != "us-west-2" {
+		t.Errorf("Aws.Region is '%s' expected 'us-west-2'", cfg.Aws.Region)
+	}
+
+	if len(cfg.Extra) != 1 {
+		t.Fatalf("Extra is %v expected only the 'acme' block", cfg.Extra)
+	}
+
+	block, err := cfg.Providers.Block("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if block["url"] != "https://acme.example" {
+		t.Errorf("acme.url is '%v' expected 'https://acme.example'", block["url"])
+	}
+
+	if len(cfg.Hosts) != 1 || cfg.Hosts[0].Extra["acme"] == nil {
+		t.Errorf("expected the host rule to keep its 'acme' block: %v", cfg.Hosts)
+	}
+
+	if cfg.Profiles["dev"] == nil || cfg.Profiles["dev"].Extra["acme"] == nil {
+		t.Errorf("expected the profile to keep its 'acme' block: %v", cfg.Profiles)
+	}
+
+	bytes, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(string(bytes), "acme:") != 3 {
+		t.Errorf("expected 3 'acme' blocks to be written:\n%s", bytes)
+	}
+
+	if !strings.HasPrefix(string(bytes), "logging:") {
+		t.Errorf("expected the order of the fields to be kept:\n%s", bytes)
+	}
+}
+
+func TestProvidersSetBlock(t *testing.T) {
+	providers := Providers{}
+
+	err := providers.SetBlock("hcvault", map[string]interface{}{"vaultUri": "http://localhost:8200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = providers.SetBlock("acme", map[string]interface{}{"url": "https://acme.example"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if providers.HashiVault.VaultUri != "http://localhost:8200" {
+		t.Errorf("HashiVault.VaultUri is '%s' expected 'http://localhost:8200'", providers.HashiVault.VaultUri)
+	}
+
+	if providers.Extra["acme"] == nil {
+		t.Errorf("expected the 'acme' block to be kept in Extra: %v", providers.Extra)
+	}
+
+	err = providers.SetBlock("acme", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(providers.Extra) != 0 {
+		t.Errorf("expected the 'acme' block to be removed: %v", providers.Extra)
+	}
+}
@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/tonedefdev/terracreds/api"
+	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/tonedefdev/terracreds/pkg/vault"
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v2"
 )
@@ -16,6 +20,7 @@ type Config struct {
 	Cfg                  *api.Config
 	ConfigFile           ConfigFile
 	DefaultReplaceString string
+	Profile              string
 	TerraCreds           TerraCreds
 	SecretNames          []string
 	Version              string
@@ -48,15 +53,7 @@ func (cmd *Config) NewCommandConfig() *cli.Command {
 				Value:    false,
 			},
 		},
-		Subcommands: []*cli.Command{
-			cmd.newCommandAws(),
-			cmd.newCommandAzure(),
-			cmd.newCommandGcp(),
-			cmd.newCommandHashi(),
-			cmd.newCommandLogging(),
-			cmd.newCommandSecrets(),
-			cmd.newCommandView(),
-		},
+		Subcommands: cmd.newConfigSubcommands(),
 		Action: func(c *cli.Context) error {
 			err := cmd.newCommandActionReset(c)
 			return err
@@ -66,14 +63,34 @@ func (cmd *Config) NewCommandConfig() *cli.Command {
 	return config
 }
 
-// newCommandActionReset resets the configuration file to only leverage the local vault
-func (cmd *Config) newCommandActionReset(c *cli.Context) error {
-	if c.Bool("use-local-vault-only") {
-		newCfg := api.Config{
-			Logging: cmd.Cfg.Logging,
-			Secrets: cmd.Cfg.Secrets,
+// newConfigSubcommands returns the config subcommands sorted by name, including a subcommand for
+// each registered vault provider that has a configuration block
+func (cmd *Config) newConfigSubcommands() []*cli.Command {
+	subcommands := []*cli.Command{
+		cmd.newCommandChain(),
+		cmd.newCommandHosts(),
+		cmd.newCommandLogging(),
+		cmd.newCommandSecrets(),
+		cmd.newCommandStrict(),
+		cmd.newCommandView(),
+	}
+
+	for _, provider := range vault.Registered() {
+		if provider.Key != "" {
+			subcommands = append(subcommands, cmd.newCommandProvider(provider))
 		}
+	}
+
+	sort.Slice(subcommands, func(i, j int) bool {
+		return subcommands[i].Name < subcommands[j].Name
+	})
+
+	return subcommands
+}
 
+// newCommandActionReset resets the configuration file, or the selected profile, to only leverage the local vault
+func (cmd *Config) newCommandActionReset(c *cli.Context) error {
+	if c.Bool("use-local-vault-only") {
 		if !c.Bool("force") {
 			const verbiage = "This will reset the configuration to only use the local operating system's credential vault. Any configuration values for a cloud provider vault will be permanently lost!"
 			fmt.Fprintf(color.Output, "%s: %s\n\n    Enter 'yes' to continue or press 'enter' or 'return' to cancel: ", color.YellowString("WARNING"), verbiage)
@@ -81,20 +98,12 @@ func (cmd *Config) newCommandActionReset(c *cli.Context) error {
 			fmt.Print("\n")
 
 			if cmd.Confirm == "yes" {
-				err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-				if err != nil {
-					helpers.CheckError(err)
-				}
-
+				err := cmd.resetProviders()
 				return err
 			}
 		}
 
-		err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-		if err != nil {
-			helpers.CheckError(err)
-		}
-
+		err := cmd.resetProviders()
 		return err
 	}
 
@@ -102,218 +111,163 @@ func (cmd *Config) newCommandActionReset(c *cli.Context) error {
 	return err
 }
 
-// newCommandAws instantiates the command used to setup the AWS configuration
-func (cmd *Config) newCommandAws() *cli.Command {
-	awsConfig := &cli.Command{
-		Name:  "aws",
-		Usage: "AWS Secrets Manager provider configuration settings",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "description",
-				Usage:    "A description to provide to the secret",
-				Required: false,
-			},
-			&cli.StringFlag{
-				Name:     "region",
-				Usage:    "The region where AWS Secrets Manager is hosted",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "secret-name",
-				Usage:    "The friendly name of the secret stored in AWS Secrets Manager. If omitted Terracreds will use the hostname value instead",
-				Value:    "",
-				Required: false,
-			},
-		},
+// resetProviders removes the vault provider settings and host rules from the configuration
+func (cmd *Config) resetProviders() error {
+	*cmd.hostRules() = nil
+
+	err := cmd.writeProviders(func(providers *api.Providers) error {
+		*providers = api.Providers{}
+		return nil
+	})
+
+	return err
+}
+
+// newCommandProvider instantiates the command used to setup the configuration of a vault provider
+func (cmd *Config) newCommandProvider(provider *vault.Provider) *cli.Command {
+	providerConfig := &cli.Command{
+		Name:  provider.Name,
+		Usage: provider.Usage,
+		Flags: providerFlags(provider),
 		Action: func(c *cli.Context) error {
-			err := cmd.newCommandActionAws(c)
+			err := cmd.newCommandActionProvider(c, provider)
 			return err
 		},
 	}
 
-	return awsConfig
+	return providerConfig
 }
 
-// newCommandActionAws sets the AWS configuration and writes it to the config file
-func (cmd *Config) newCommandActionAws(c *cli.Context) error {
-	newCfg := api.Config{
-		Aws: api.Aws{
-			Description: c.String("description"),
-			Region:      c.String("region"),
-			SecretName:  c.String("secret-name"),
-		},
-		Logging: cmd.Cfg.Logging,
-		Secrets: cmd.Cfg.Secrets,
-	}
-
-	err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-	if err != nil {
-		helpers.CheckError(err)
-	}
+// newCommandActionProvider sets the configuration of a vault provider and writes it to the config file
+func (cmd *Config) newCommandActionProvider(c *cli.Context, provider *vault.Provider) error {
+	err := cmd.writeProviders(func(providers *api.Providers) error {
+		return providers.SetBlock(provider.Key, providerConfig(c, provider))
+	})
 
 	return err
 }
 
-// newCommandAzure instantiates the command used to setup the Azure configuration
-func (cmd *Config) newCommandAzure() *cli.Command {
-	azureConfig := &cli.Command{
-		Name:  "azure",
-		Usage: "Azure Key Vault provider configuration settings",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "secret-name",
-				Usage:    "The name of the secret stored in Azure Key Vault. If omitted Terracreds will use the hostname value instead",
-				Value:    "",
-				Required: false,
-			},
-			&cli.StringFlag{
-				Name:     "subscription-id",
-				Aliases:  []string{"id"},
-				Usage:    "The subscription ID where the Key Vault instance has been created",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "vault-uri",
-				Usage:    "The FQDN of the Azure Key Vault resource",
-				Required: true,
-			},
-		},
-		Action: func(c *cli.Context) error {
-			err := cmd.newCommandActionAzure(c)
-			return err
-		},
+// providerFlags returns the flags used to configure a vault provider
+func providerFlags(provider *vault.Provider) []cli.Flag {
+	var flags []cli.Flag
+	for _, flag := range provider.Flags {
+		if flag.Bool {
+			flags = append(flags, &cli.BoolFlag{
+				Name:     flag.Name,
+				Aliases:  flag.Aliases,
+				Usage:    flag.Usage,
+				Required: flag.Required,
+			})
+
+			continue
+		}
+
+		flags = append(flags, &cli.StringFlag{
+			Name:     flag.Name,
+			Aliases:  flag.Aliases,
+			Usage:    flag.Usage,
+			Required: flag.Required,
+		})
 	}
 
-	return azureConfig
+	return flags
 }
 
-// newCommandActionAzure sets the Azure configuration and writes it to the config file
-func (cmd *Config) newCommandActionAzure(c *cli.Context) error {
-	newCfg := api.Config{
-		Azure: api.Azure{
-			SecretName:     c.String("secret-name"),
-			SubscriptionId: c.String("subscription-id"),
-			VaultUri:       c.String("vault-uri"),
-		},
-		Logging: cmd.Cfg.Logging,
-		Secrets: cmd.Cfg.Secrets,
-	}
+// providerConfig returns the configuration block of a vault provider set by the command's flags.
+// Flags that aren't set are left out of the block
+func providerConfig(c *cli.Context, provider *vault.Provider) map[string]interface{} {
+	block := make(map[string]interface{})
+	for _, flag := range provider.Flags {
+		if !c.IsSet(flag.Name) {
+			continue
+		}
 
-	err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-	if err != nil {
-		helpers.CheckError(err)
+		if flag.Bool {
+			block[flag.Key] = c.Bool(flag.Name)
+			continue
+		}
+
+		if value := c.String(flag.Name); value != "" {
+			block[flag.Key] = value
+		}
 	}
 
-	return err
+	return block
 }
 
-// newCommandGcp instantiates the command used to setup the GCP configuration
-func (cmd *Config) newCommandGcp() *cli.Command {
-	gcpConfig := &cli.Command{
-		Name:  "gcp",
-		Usage: "Google Cloud Provider Secrets Manager configuration settings",
+// newCommandChain instantiates the command used to setup the fallback chain of vault providers
+func (cmd *Config) newCommandChain() *cli.Command {
+	chainConfig := &cli.Command{
+		Name:  "chain",
+		Usage: "Configure an ordered fallback chain of vault providers. Reads return the first provider that has the secret and writes go to the primary provider",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "project-id",
-				Usage:    "The name of the GCP project where the Secrets Manager has been created",
+				Name:     "providers",
+				Usage:    fmt.Sprintf("A comma separated list of vault providers in the order they are read from. Valid providers are %s", strings.Join(vault.Names(), ", ")),
 				Required: true,
 			},
 			&cli.StringFlag{
-				Name:     "secret-id",
-				Usage:    "The name of the secret identifier in GCP Secrets Manager. If omitted Terracreds will use the hostname value instead",
+				Name:     "primary",
+				Usage:    "The vault provider that secrets are written to. If omitted the first provider in the chain is used instead",
 				Value:    "",
 				Required: false,
 			},
 		},
 		Action: func(c *cli.Context) error {
-			err := cmd.newCommandActionGcp(c)
+			err := cmd.newCommandActionChain(c)
 			return err
 		},
 	}
 
-	return gcpConfig
+	return chainConfig
 }
 
-// newCommandActionGcp sets the GCP configuration and writes it to the config file
-func (cmd *Config) newCommandActionGcp(c *cli.Context) error {
-	newCfg := api.Config{
-		GCP: api.GCP{
-			ProjectId: c.String("project-id"),
-			SecretId:  c.String("secret-id"),
-		},
-		Logging: cmd.Cfg.Logging,
-		Secrets: cmd.Cfg.Secrets,
+// newCommandActionChain validates the fallback chain and writes it to the config file. The configuration
+// blocks of the other providers are kept since the chain reads from each of them
+func (cmd *Config) newCommandActionChain(c *cli.Context) error {
+	chain := api.Chain{
+		Primary: strings.TrimSpace(c.String("primary")),
 	}
 
-	err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-	if err != nil {
-		helpers.CheckError(err)
+	for _, name := range strings.Split(c.String("providers"), ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			chain.Providers = append(chain.Providers, name)
+		}
 	}
 
-	return err
-}
+	if len(chain.Providers) == 0 {
+		err := &errors.CustomError{
+			Message: "The chain needs at least one vault provider",
+			Level:   "ERROR",
+		}
 
-// newCommandHashi instantiates the command to setup the Hashi Vault configuration
-func (cmd *Config) newCommandHashi() *cli.Command {
-	hashiConfig := &cli.Command{
-		Name:  "hashicorp",
-		Usage: "HashiCorp Vault provider configuration settings",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "environment-token-name",
-				Usage:    "The name of the environment variable that currently holds the Vault token",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "key-vault-path",
-				Usage:    "The name of the Key Vault store inside of Vault",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "secret-name",
-				Usage:    "The name of the secret stored inside of Vault. If omitted Terracreds will use the hostname value instead",
-				Value:    "",
-				Required: false,
-			},
-			&cli.StringFlag{
-				Name:     "secret-path",
-				Usage:    "The path of the secret itself inside of the vault",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "vault-uri",
-				Usage:    "The URL of the Vault instance including its port",
-				Required: true,
-			},
-		},
-		Action: func(c *cli.Context) error {
-			err := cmd.newCommandActionHashi(c)
-			return err
-		},
+		return err
 	}
 
-	return hashiConfig
-}
+	for _, name := range chain.Providers {
+		if !slices.Contains(vault.Names(), name) {
+			err := &errors.CustomError{
+				Message: fmt.Sprintf("The vault provider '%s' is not supported. Use one of %s", name, strings.Join(vault.Names(), ", ")),
+				Level:   "ERROR",
+			}
 
-// newCommandActionHashi sets the Hashi Vault configuration and writes it to the config file
-func (cmd *Config) newCommandActionHashi(c *cli.Context) error {
-	newCfg := api.Config{
-		HashiVault: api.HCVault{
-			EnvironmentTokenName: c.String("environment-token-name"),
-			KeyVaultPath:         c.String("key-vault-path"),
-			SecretName:           c.String("secret-name"),
-			SecretPath:           c.String("secret-path"),
-			VaultUri:             c.String("vault-uri"),
-		},
-		Logging: cmd.Cfg.Logging,
-		Secrets: cmd.Cfg.Secrets,
+			return err
+		}
 	}
 
-	err := helpers.WriteConfig(cmd.ConfigFile.Path, &newCfg)
-	if err != nil {
-		helpers.CheckError(err)
+	if chain.Primary != "" && !slices.Contains(chain.Providers, chain.Primary) {
+		err := &errors.CustomError{
+			Message: fmt.Sprintf("The primary vault provider '%s' must be part of the chain", chain.Primary),
+			Level:   "ERROR",
+		}
+
+		return err
 	}
 
+	cmd.activeProviders().Chain = chain
+
+	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
 	return err
 }
 
@@ -355,10 +309,6 @@ func (cmd *Config) newCommandActionLogging(c *cli.Context) error {
 	}
 
 	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
-	if err != nil {
-		helpers.CheckError(err)
-	}
-
 	return err
 }
 
@@ -390,10 +340,35 @@ func (cmd *Config) newCommandActionSecrets(c *cli.Context) error {
 	cmd.Cfg.Secrets = secretValues
 
 	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
-	if err != nil {
-		helpers.CheckError(err)
+	return err
+}
+
+// newCommandStrict instantiates the command to configure strict mode
+func (cmd *Config) newCommandStrict() *cli.Command {
+	strictConfig := &cli.Command{
+		Name:  "strict",
+		Usage: "Configure whether 'get' returns an error instead of an empty credential when no secret is found for a host",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:     "enabled",
+				Usage:    "Enable strict mode. Use '--enabled=false' to disable it",
+				Required: false,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := cmd.newCommandActionStrict(c)
+			return err
+		},
 	}
 
+	return strictConfig
+}
+
+// newCommandActionStrict sets strict mode and writes it to the config file
+func (cmd *Config) newCommandActionStrict(c *cli.Context) error {
+	cmd.Cfg.Strict = c.Bool("enabled")
+
+	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
 	return err
 }
 
@@ -415,7 +390,7 @@ func (cmd *Config) newCommandView() *cli.Command {
 func (cmd *Config) newCommandActionView(c *cli.Context) error {
 	bytes, err := yaml.Marshal(&cmd.Cfg)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
 	print(string(bytes))
@@ -3,14 +3,39 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
 	"github.com/tonedefdev/terracreds/api"
+	"github.com/tonedefdev/terracreds/pkg/vault"
 	"github.com/urfave/cli/v2"
 	"github.com/zalando/go-keyring"
 )
 
+// acmeVault is a vault provider registered outside of the vault package
+type acmeVault struct {
+	vault.Keyring
+	Url string
+}
+
+func init() {
+	vault.Register(&vault.Provider{
+		Name:  "acme",
+		Key:   "acme",
+		Title: "the ACME vault",
+		Usage: "ACME vault provider configuration settings",
+		Flags: []vault.Flag{
+			{Name: "url", Key: "url", Usage: "The URL of the ACME vault", Required: true},
+			{Name: "secret-name", Key: "secretName", Usage: "The name of the secret"},
+		},
+		SecretNameKey: "secretName",
+		New: func(config vault.ProviderConfig, secretName string) (vault.TerraVault, error) {
+			return &acmeVault{Keyring: vault.Keyring{SecretName: secretName}, Url: config.String("url")}, nil
+		},
+	})
+}
+
 func app() *cli.App {
 	app := cli.NewApp()
 	return app
@@ -141,3 +166,133 @@ func TestActionReset(t *testing.T) {
 	args = append(args, "config", "--use-local-vault-only", "--force")
 	app.Run(args)
 }
+
+func TestNewCommandActionChain(t *testing.T) {
+	app := app()
+	terracreds := config()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "chain", "--providers=keyring,hashicorp", "--primary=keyring")
+	app.Run(args)
+
+	args = os.Args[0:1]
+	args = append(args, "config", "hashicorp", "--environment-token-name=test", "--key-vault-path=kv", "--secret-path=test", "--vault-uri=http://localhost:8200")
+	app.Run(args)
+
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+
+	if len(terracreds.Cfg.Chain.Providers) != 2 {
+		t.Fatalf("Chain.Providers is %v expected [keyring hashicorp]", terracreds.Cfg.Chain.Providers)
+	}
+
+	if terracreds.Cfg.HashiVault.VaultUri != "http://localhost:8200" {
+		t.Errorf("HashiVault.VaultUri is '%s' expected 'http://localhost:8200'", terracreds.Cfg.HashiVault.VaultUri)
+	}
+
+	terraVault, err := terracreds.NewTerraVault("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, ok := terraVault.(*vault.Chain)
+	if !ok {
+		t.Fatalf("expected a fallback chain got %T", terraVault)
+	}
+
+	if len(chain.Links) != 2 || chain.Primary != "keyring" {
+		t.Errorf("expected the chain to read from 2 providers and write to 'keyring'")
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "config", "--use-local-vault-only", "--force")
+	app.Run(args)
+}
+
+func TestNewCommandActionChainSpaces(t *testing.T) {
+	app := app()
+	terracreds := config()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "chain", "--providers=keyring, hashicorp,", "--primary= keyring")
+	err := app.Run(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+
+	expected := []string{"keyring", "hashicorp"}
+	if !reflect.DeepEqual(terracreds.Cfg.Chain.Providers, expected) || terracreds.Cfg.Chain.Primary != "keyring" {
+		t.Errorf("expected the providers %q with the primary 'keyring' got %q with '%s'", expected, terracreds.Cfg.Chain.Providers, terracreds.Cfg.Chain.Primary)
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "config", "chain", "--providers= , ")
+	err = app.Run(args)
+	if err == nil {
+		t.Errorf("expected a chain without providers to be rejected")
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "config", "--use-local-vault-only", "--force")
+	app.Run(args)
+}
+
+func TestNewCommandActionRegisteredProvider(t *testing.T) {
+	app := app()
+	terracreds := config()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "acme", "--url=https://acme.example", "--secret-name=acme-token")
+	err := app.Run(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = terracreds.LoadConfig(terracreds.ConfigFile.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	terraVault, err := terracreds.NewTerraVault("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acme, ok := terraVault.(*acmeVault)
+	if !ok {
+		t.Fatalf("expected the ACME vault got %T", terraVault)
+	}
+
+	if acme.Url != "https://acme.example" || acme.SecretName != "acme-token" {
+		t.Errorf("the ACME vault has the url '%s' and secret name '%s'", acme.Url, acme.SecretName)
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "config", "--use-local-vault-only", "--force")
+	app.Run(args)
+}
+
+func TestLoadConfigUnknownProvider(t *testing.T) {
+	terracreds := config()
+	terracreds.ConfigFile.Path = filepath.Join(t.TempDir(), "config.yaml")
+
+	err := os.WriteFile(terracreds.ConfigFile.Path, []byte("unknown:\n  url: https://unknown.example\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = terracreds.LoadConfig(terracreds.ConfigFile.Path)
+	if err == nil {
+		t.Errorf("expected an error for the 'unknown' block")
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"os"
 	"os/user"
 
 	"github.com/tonedefdev/terracreds/pkg/errors"
@@ -40,7 +39,7 @@ func (cmd *Config) NewCommandCreate() *cli.Command {
 
 // newCommandActionCreate creates the secret based on the OS and type of vault
 func (cmd *Config) newCommandActionCreate(c *cli.Context) error {
-	if len(os.Args) == 2 {
+	if c.NumFlags() == 0 {
 		err := &errors.CustomError{
 			Message: "No secret name or secret was specified. Use 'terracreds create -h' to print help info",
 			Level:   "ERROR",
@@ -50,16 +49,32 @@ func (cmd *Config) newCommandActionCreate(c *cli.Context) error {
 		return err
 	}
 
-	terraVault := cmd.NewTerraVault(c.String("name"))
-	name := GetSecretName(cmd.Cfg, c.String("name"))
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	terraVault, err := cmd.NewTerraVault(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	name, err := GetSecretName(cfg, c.String("name"))
+	if err != nil {
+		return err
+	}
 
 	user, err := user.Current()
-	helpers.CheckError(err)
+	if err != nil {
+		return err
+	}
 
-	err = cmd.TerraCreds.Create(cmd.Cfg, name, c.String("secret"), user, terraVault)
+	err = cmd.TerraCreds.Create(c.Context, cfg, name, c.String("secret"), user, terraVault)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
+	logChainAnswer(cfg, terraVault, name)
+
 	return nil
 }
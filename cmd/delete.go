@@ -2,13 +2,15 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"os/user"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/tonedefdev/terracreds/api"
 	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/tonedefdev/terracreds/pkg/vault"
 	"github.com/urfave/cli/v2"
 )
 
@@ -24,6 +26,22 @@ func (cmd *Config) NewCommandDelete() *cli.Command {
 				Value:   "place_holder",
 				Usage:   "The name of the Terraform Automation and Collaboration Software server's hostname or the name of the secret. This is also the display name of the credential object",
 			},
+			&cli.BoolFlag{
+				Name:  "soft-delete",
+				Usage: "Soft delete the versions of the secret the credential is stored in, including its other keys, so they can be restored with 'terracreds restore'. If '--versions' is omitted the latest version is deleted",
+			},
+			&cli.BoolFlag{
+				Name:  "destroy",
+				Usage: "Permanently destroy the versions set with '--versions' of the secret the credential is stored in, including its other keys",
+			},
+			&cli.BoolFlag{
+				Name:  "all-versions",
+				Usage: "Permanently delete every version and the metadata of the secret the credential is stored in, including its other keys",
+			},
+			&cli.StringFlag{
+				Name:  "versions",
+				Usage: "A comma separated list of the versions to soft delete or destroy such as '1,2'",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			err := cmd.newCommandActionDelete(c)
@@ -36,7 +54,7 @@ func (cmd *Config) NewCommandDelete() *cli.Command {
 
 // newCommandActionDelete deletes the secret based on the type of vault
 func (cmd *Config) newCommandActionDelete(c *cli.Context) error {
-	if len(os.Args) == 2 {
+	if c.NumFlags() == 0 && c.NArg() == 0 {
 		err := &errors.CustomError{
 			Message: "No secret name was specified. Use 'terracreds delete -h' to print help info",
 			Level:   "ERROR",
@@ -46,24 +64,155 @@ func (cmd *Config) newCommandActionDelete(c *cli.Context) error {
 		return err
 	}
 
-	if !strings.Contains(os.Args[2], "-n") && !strings.Contains(os.Args[2], "--name") {
-		msg := fmt.Sprintf("A secret name was not expected here: '%s'", os.Args[2])
+	if !c.IsSet("name") {
+		msg := fmt.Sprintf("A secret name was not expected here: '%s'", c.Args().First())
 		helpers.Logging(cmd.Cfg, msg, "WARNING")
-		fmt.Fprintf(color.Output, "%s: %s Did you mean `terracreds delete --name/-n %s'?\n", color.YellowString("WARNING"), msg, os.Args[2])
+		fmt.Fprintf(color.Output, "%s: %s Did you mean `terracreds delete --name/-n %s'?\n", color.YellowString("WARNING"), msg, c.Args().First())
 		return nil
 	}
 
-	terraVault := cmd.NewTerraVault(c.String("name"))
-	name := GetSecretName(cmd.Cfg, c.String("name"))
-	method := os.Args[1]
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	terraVault, err := cmd.NewTerraVault(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	name, err := GetSecretName(cfg, c.String("name"))
+	if err != nil {
+		return err
+	}
+	method := c.Command.Name
 
 	user, err := user.Current()
-	helpers.CheckError(err)
+	if err != nil {
+		return err
+	}
 
-	err = cmd.TerraCreds.Delete(cmd.Cfg, method, name, user, terraVault)
+	if c.Bool("soft-delete") || c.Bool("destroy") || c.Bool("all-versions") || c.IsSet("versions") {
+		return cmd.deleteVersions(c, cfg, terraVault, name)
+	}
+
+	err = cmd.TerraCreds.Delete(c.Context, cfg, method, name, user, terraVault)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
+	logChainAnswer(cfg, terraVault, name)
+
 	return err
 }
+
+// deleteVersions soft deletes or destroys versions of the secret, or deletes all of them,
+// with a vault provider that keeps the versions of its secrets
+func (cmd *Config) deleteVersions(c *cli.Context, cfg *api.Config, terraVault vault.TerraVault, name string) error {
+	operations := 0
+	for _, flag := range []string{"soft-delete", "destroy", "all-versions"} {
+		if c.Bool(flag) {
+			operations++
+		}
+	}
+
+	if operations != 1 || (c.IsSet("versions") && c.Bool("all-versions")) || (c.Bool("destroy") && !c.IsSet("versions")) {
+		err := &errors.CustomError{
+			Message: "Use one of '--soft-delete', '--destroy' or '--all-versions'. '--versions' is required with '--destroy', optional with '--soft-delete' and not allowed with '--all-versions'",
+			Level:   "ERROR",
+		}
+
+		helpers.Logging(cmd.Cfg, err.Message, err.Level)
+		return err
+	}
+
+	versions, err := cmd.parseVersions(c.String("versions"))
+	if err != nil {
+		return err
+	}
+
+	versioner, err := cmd.findVersioner(terraVault)
+	if err != nil {
+		return err
+	}
+
+	var action string
+	switch {
+	case c.Bool("soft-delete"):
+		action = "soft deleted"
+		err = versioner.SoftDelete(c.Context, versions)
+	case c.Bool("destroy"):
+		action = "destroyed"
+		err = versioner.Destroy(c.Context, versions)
+	default:
+		action = "deleted with its metadata"
+		err = versioner.DeleteMetadata(c.Context)
+	}
+
+	if err != nil {
+		helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
+		return err
+	}
+
+	msg := fmt.Sprintf("every version of the secret that stores '%s' was %s", name, action)
+	if len(versions) > 0 {
+		msg = fmt.Sprintf("the versions %s of the secret that stores '%s' were %s", c.String("versions"), name, action)
+	} else if !c.Bool("all-versions") {
+		msg = fmt.Sprintf("the latest version of the secret that stores '%s' was %s", name, action)
+	}
+
+	helpers.Logging(cfg, fmt.Sprintf("- %s", msg), "INFO")
+	fmt.Fprintf(color.Output, "%s: %s%s\n", color.GreenString("SUCCESS"), strings.ToUpper(msg[:1]), msg[1:])
+	return nil
+}
+
+// findVersioner returns the vault itself, or the primary vault of a fallback chain, when it
+// keeps the versions of its secrets
+func (cmd *Config) findVersioner(terraVault vault.TerraVault) (vault.Versioner, error) {
+	if chain, ok := terraVault.(*vault.Chain); ok {
+		for _, link := range chain.Links {
+			if link.Name == chain.Primary {
+				terraVault = link.Vault
+			}
+		}
+	}
+
+	versioner, ok := terraVault.(vault.Versioner)
+	if !ok {
+		err := &errors.CustomError{
+			Message: "The configured vault provider doesn't keep the versions of its secrets. Only the 'hashicorp' provider with a KV version 2 mount supports versions",
+			Level:   "ERROR",
+		}
+
+		helpers.Logging(cmd.Cfg, err.Message, err.Level)
+		return nil, err
+	}
+
+	return versioner, nil
+}
+
+// parseVersions returns the version numbers of a comma separated list such as '1,2'
+func (cmd *Config) parseVersions(value string) ([]int, error) {
+	var versions []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		version, err := strconv.Atoi(field)
+		if err != nil || version < 1 {
+			err := &errors.CustomError{
+				Message: fmt.Sprintf("'%s' is not a version. Versions are positive numbers such as '1,2'", field),
+				Level:   "ERROR",
+			}
+
+			helpers.Logging(cmd.Cfg, err.Message, err.Level)
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"os"
 	"os/user"
 
 	"github.com/tonedefdev/terracreds/pkg/errors"
@@ -24,7 +23,7 @@ func (cmd *Config) NewCommandForget() *cli.Command {
 
 // newCommandActionForget deletes the requested secret in the vault when called by 'terraform logout'
 func (cmd *Config) newCommandActionForget(c *cli.Context) error {
-	if len(os.Args) == 2 {
+	if c.NArg() == 0 {
 		err := &errors.CustomError{
 			Message: "No secret name or secret was specified. Use 'terracreds forget -h' to print help info",
 			Level:   "ERROR",
@@ -34,16 +33,32 @@ func (cmd *Config) newCommandActionForget(c *cli.Context) error {
 		return err
 	}
 
-	terraVault := cmd.NewTerraVault(os.Args[2])
-	name := GetSecretName(cmd.Cfg, os.Args[2])
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	terraVault, err := cmd.NewTerraVault(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	name, err := GetSecretName(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
 
 	user, err := user.Current()
-	helpers.CheckError(err)
+	if err != nil {
+		return err
+	}
 
-	err = cmd.TerraCreds.Delete(cmd.Cfg, "delete", name, user, terraVault)
+	err = cmd.TerraCreds.Delete(c.Context, cfg, "delete", name, user, terraVault)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
+	logChainAnswer(cfg, terraVault, name)
+
 	return err
 }
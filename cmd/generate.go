@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"github.com/tonedefdev/terracreds/pkg/helpers"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,7 +13,7 @@ func (cmd *Config) NewCommandGenerate() *cli.Command {
 			&cli.BoolFlag{
 				Name:  "create-cli-config",
 				Value: false,
-				Usage: "Creates the Terraform CLI config with a terracreds credential helper block. This will overwrite the existing file if it already exists.",
+				Usage: "Creates the Terraform CLI config with a terracreds credential helper block. This will overwrite the existing file if it already exists. When a profile is selected with '--profile' the helper is configured to use it.",
 			},
 			&cli.BoolFlag{
 				Name:  "force",
@@ -23,10 +22,7 @@ func (cmd *Config) NewCommandGenerate() *cli.Command {
 			},
 		},
 		Action: func(c *cli.Context) error {
-			err := GenerateTerraCreds(c, cmd.Version, cmd.Confirm)
-			if err != nil {
-				helpers.CheckError(err)
-			}
+			err := GenerateTerraCreds(c, cmd.Version, cmd.Confirm, cmd.Profile)
 			return err
 		},
 	}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"os/user"
 
 	"github.com/tonedefdev/terracreds/pkg/errors"
@@ -16,7 +15,7 @@ func (cmd *Config) NewCommandGet() *cli.Command {
 		Name:  "get",
 		Usage: "Get the credential object value by passing the server's hostname (Terraform backend default behavior) or the name of the secret as an argument. The credential is returned as a JSON object and formatted for consumption by Terraform",
 		Action: func(c *cli.Context) error {
-			err := cmd.newCommandActionGet()
+			err := cmd.newCommandActionGet(c)
 			return err
 		},
 	}
@@ -24,22 +23,67 @@ func (cmd *Config) NewCommandGet() *cli.Command {
 	return cmdGet
 }
 
-// newCommandActionGet returns a JSON string representing the secret value stored in the vault
-func (cmd *Config) newCommandActionGet() error {
-	if len(os.Args) > 2 {
+// newCommandActionGet returns a JSON string representing the secret value stored in the vault.
+// When no credential exists for the host an empty JSON object is returned, as the Terraform
+// credentials helper protocol requires, unless strict mode has been enabled
+func (cmd *Config) newCommandActionGet(c *cli.Context) error {
+	if c.NArg() > 0 {
+		cfg, err := cmd.activeConfig()
+		if err != nil {
+			return err
+		}
+
 		user, err := user.Current()
-		helpers.CheckError(err)
+		if err != nil {
+			return err
+		}
 
-		terraVault := cmd.NewTerraVault(os.Args[2])
-		name := GetSecretName(cmd.Cfg, os.Args[2])
+		hostnames := []string{c.Args().First()}
+		hostname, err := helpers.NormalizeHostname(c.Args().First())
+		if err == nil && hostname != c.Args().First() {
+			hostnames = []string{hostname, c.Args().First()}
+		}
 
-		token, err := cmd.TerraCreds.Get(cmd.Cfg, name, user, terraVault)
-		if err != nil {
-			helpers.CheckError(err)
+		for _, hostname := range hostnames {
+			terraVault, err := cmd.NewTerraVault(hostname)
+			if err != nil {
+				return err
+			}
+
+			name, err := GetSecretName(cfg, hostname)
+			if err != nil {
+				return err
+			}
+
+			token, err := cmd.TerraCreds.Get(c.Context, cfg, name, user, terraVault)
+			if errors.IsNotFound(err) {
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			logChainAnswer(cfg, terraVault, name)
+			fmt.Println(string(token))
+			return nil
+		}
+
+		msg := fmt.Sprintf("No credential object was found for '%s'", hostnames[0])
+		helpers.Logging(cfg, msg, "WARNING")
+
+		if cfg.Strict {
+			err := &errors.CustomError{
+				Message: msg,
+				Level:   "ERROR",
+				Err:     errors.ErrNotFound,
+			}
+
+			return err
 		}
 
-		fmt.Println(string(token))
-		return err
+		fmt.Println("{}")
+		return nil
 	}
 
 	err := &errors.CustomError{
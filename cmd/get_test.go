@@ -1,12 +1,40 @@
 package cmd
 
 import (
+	"io"
 	"os"
 	"testing"
 
+	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
 
+// captureStdout returns what's written to stdout while the function runs
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	run()
+	w.Close()
+
+	output, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(output)
+}
+
 func TestNewCommandActionGet(t *testing.T) {
 	terracreds := config()
 	app := app()
@@ -23,3 +51,34 @@ func TestNewCommandActionGet(t *testing.T) {
 	args = append(args, "get", "test")
 	app.Run(args)
 }
+
+func TestNewCommandActionGetMissing(t *testing.T) {
+	terracreds := config()
+	app := app()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandGet(),
+	}
+
+	var err error
+	args := append(os.Args[0:1], "get", "missing.terracreds.test")
+	output := captureStdout(t, func() {
+		err = app.Run(args)
+	})
+
+	if err != nil || output != "{}\n" {
+		t.Errorf("expected an empty JSON object for a host without a credential got '%s', %v", output, err)
+	}
+
+	terracreds.Cfg.Strict = true
+	output = captureStdout(t, func() {
+		err = app.Run(args)
+	})
+
+	if code := errors.ExitCode(err); code != errors.ExitNotFound {
+		t.Errorf("exit code is %d expected %d in strict mode: %v", code, errors.ExitNotFound, err)
+	}
+
+	if output != "" {
+		t.Errorf("expected nothing to be printed in strict mode got '%s'", output)
+	}
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/fatih/color"
+	"github.com/tonedefdev/terracreds/api"
+	"github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/urfave/cli/v2"
+)
+
+// newCommandHosts instantiates the command used to manage the host routing rules
+func (cmd *Config) newCommandHosts() *cli.Command {
+	hostsConfig := &cli.Command{
+		Name:  "hosts",
+		Usage: "Manage the rules that route hostnames matching a pattern to a vault provider",
+		Subcommands: []*cli.Command{
+			cmd.newCommandHostsAdd(),
+			cmd.newCommandHostsRemove(),
+			cmd.newCommandHostsTest(),
+		},
+	}
+
+	return hostsConfig
+}
+
+// newCommandHostsAdd instantiates the command used to add a host rule for each vault provider
+func (cmd *Config) newCommandHostsAdd() *cli.Command {
+	addConfig := &cli.Command{
+		Name:  "add",
+		Usage: "Add or replace a host rule that routes matching hostnames to a vault provider",
+	}
+
+	for _, provider := range vault.Registered() {
+		addConfig.Subcommands = append(addConfig.Subcommands, &cli.Command{
+			Name:  provider.Name,
+			Usage: fmt.Sprintf("Route matching hostnames to %s", provider.Title),
+			Flags: hostFlags(provider),
+			Action: func(c *cli.Context) error {
+				err := cmd.newCommandActionHostsAdd(c, provider)
+				return err
+			},
+		})
+	}
+
+	return addConfig
+}
+
+// hostFlags returns the flags for a host rule followed by the provider's flags. The provider's own
+// secret name flag is omitted since the rule's secret name template is used instead
+func hostFlags(provider *vault.Provider) []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "pattern",
+			Aliases:  []string{"p"},
+			Usage:    "A glob pattern matched against the hostname such as '*.tfe.corp.example' or 'app.terraform.io'",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "secret-name",
+			Usage:    "A template for the name of the secret where '{{.Hostname}}' is replaced by the hostname. If omitted Terracreds will use the hostname value instead",
+			Value:    "",
+			Required: false,
+		},
+	}
+
+	for _, flag := range providerFlags(provider) {
+		if name := flag.Names()[0]; name == "secret-name" || name == secretNameFlag(provider) {
+			continue
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags
+}
+
+// secretNameFlag returns the name of the flag that sets the provider's secret name
+func secretNameFlag(provider *vault.Provider) string {
+	for _, flag := range provider.Flags {
+		if flag.Key == provider.SecretNameKey {
+			return flag.Name
+		}
+	}
+
+	return ""
+}
+
+// newCommandActionHostsAdd validates the host rule and writes it to the config file, replacing any
+// rule that has the same pattern
+func (cmd *Config) newCommandActionHostsAdd(c *cli.Context, provider *vault.Provider) error {
+	host := api.Host{
+		Pattern:    c.String("pattern"),
+		SecretName: c.String("secret-name"),
+	}
+
+	if provider.Key != "" {
+		err := host.Providers.SetBlock(provider.Key, providerConfig(c, provider))
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := path.Match(host.Pattern, ""); err != nil {
+		err := &errors.CustomError{
+			Message: fmt.Sprintf("The pattern '%s' is not a valid glob pattern", host.Pattern),
+			Level:   "ERROR",
+		}
+
+		return err
+	}
+
+	if host.SecretName != "" {
+		if _, err := renderSecretName(host.SecretName, host.Pattern); err != nil {
+			err := &errors.CustomError{
+				Message: fmt.Sprintf("The secret name template '%s' is not valid: %s", host.SecretName, err),
+				Level:   "ERROR",
+			}
+
+			return err
+		}
+	}
+
+	hosts := cmd.hostRules()
+	replaced := false
+	for i := range *hosts {
+		if (*hosts)[i].Pattern == host.Pattern {
+			(*hosts)[i] = host
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		*hosts = append(*hosts, host)
+	}
+
+	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
+	return err
+}
+
+// newCommandHostsRemove instantiates the command used to remove a host rule
+func (cmd *Config) newCommandHostsRemove() *cli.Command {
+	removeConfig := &cli.Command{
+		Name:  "remove",
+		Usage: "Remove the host rule with the given pattern",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "pattern",
+				Aliases:  []string{"p"},
+				Usage:    "The pattern of the host rule to remove",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := cmd.newCommandActionHostsRemove(c)
+			return err
+		},
+	}
+
+	return removeConfig
+}
+
+// newCommandActionHostsRemove removes the host rule from the config file
+func (cmd *Config) newCommandActionHostsRemove(c *cli.Context) error {
+	rules := cmd.hostRules()
+
+	var hosts []api.Host
+	for _, host := range *rules {
+		if host.Pattern != c.String("pattern") {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if len(hosts) == len(*rules) {
+		err := &errors.CustomError{
+			Message: fmt.Sprintf("No host rule with the pattern '%s' was found", c.String("pattern")),
+			Level:   "ERROR",
+		}
+
+		return err
+	}
+
+	*rules = hosts
+
+	err := helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
+	return err
+}
+
+// newCommandHostsTest instantiates the command used to test which host rule matches a hostname
+func (cmd *Config) newCommandHostsTest() *cli.Command {
+	testConfig := &cli.Command{
+		Name:      "test",
+		Usage:     "Print the host rule, vault provider and secret name used for a hostname",
+		ArgsUsage: "<hostname>",
+		Action: func(c *cli.Context) error {
+			err := cmd.newCommandActionHostsTest(c)
+			return err
+		},
+	}
+
+	return testConfig
+}
+
+// newCommandActionHostsTest prints the routing decision for the hostname passed as an argument
+func (cmd *Config) newCommandActionHostsTest(c *cli.Context) error {
+	hostname := c.Args().First()
+	if hostname == "" {
+		err := &errors.CustomError{
+			Message: "A hostname was expected after the 'test' command but no argument was provided",
+			Level:   "ERROR",
+		}
+
+		return err
+	}
+
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	secretName, err := GetSecretName(cfg, hostname)
+	if err != nil {
+		return err
+	}
+
+	host := MatchHost(cfg, hostname)
+	if host == nil {
+		name, err := providerName(&cfg.Providers)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(color.Output, "%s: '%s' does not match any host rule\n", color.CyanString("INFO"), hostname)
+		fmt.Printf("    provider:   %s\n    secretName: %s\n", name, secretName)
+		return nil
+	}
+
+	name, err := providerName(&host.Providers)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(color.Output, "%s: '%s' matches the host rule '%s'\n", color.CyanString("INFO"), hostname, host.Pattern)
+	fmt.Printf("    provider:   %s\n    secretName: %s\n", name, secretName)
+	return nil
+}
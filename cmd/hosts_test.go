@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestNewCommandActionHostsAdd(t *testing.T) {
+	app := app()
+	terracreds := config()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "hosts", "add", "aws", "--pattern=*.tfe.corp.example", "--secret-name=tfe-{{.Hostname}}", "--region=us-east-1")
+	app.Run(args)
+
+	args = os.Args[0:1]
+	args = append(args, "config", "hosts", "add", "keyring", "--pattern=app.terraform.io")
+	app.Run(args)
+
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+
+	if len(terracreds.Cfg.Hosts) != 2 {
+		t.Fatalf("Hosts has %d rules expected 2", len(terracreds.Cfg.Hosts))
+	}
+
+	host := MatchHost(terracreds.Cfg, "Dev.TFE.corp.example")
+	if host == nil || host.Aws.Region != "us-east-1" {
+		t.Fatalf("expected 'dev.tfe.corp.example' to match the AWS host rule")
+	}
+
+	name, err := GetSecretName(terracreds.Cfg, "dev.tfe.corp.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name != "tfe-dev.tfe.corp.example" {
+		t.Errorf("secret name is '%s' expected 'tfe-dev.tfe.corp.example'", name)
+	}
+
+	terraVault, err := terracreds.NewTerraVault("app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if terraVault != nil {
+		t.Errorf("expected 'app.terraform.io' to use the local credential vault")
+	}
+}
+
+func TestNewCommandActionHostsTest(t *testing.T) {
+	app := app()
+	terracreds := config()
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "hosts", "test", "dev.tfe.corp.example")
+	err := app.Run(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewCommandActionHostsRemove(t *testing.T) {
+	app := app()
+	terracreds := config()
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "hosts", "remove", "--pattern=*.tfe.corp.example")
+	app.Run(args)
+
+	args = os.Args[0:1]
+	args = append(args, "config", "hosts", "remove", "--pattern=app.terraform.io")
+	app.Run(args)
+
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+
+	if len(terracreds.Cfg.Hosts) != 0 {
+		t.Fatalf("Hosts has %d rules expected 0", len(terracreds.Cfg.Hosts))
+	}
+}
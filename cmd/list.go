@@ -3,12 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"os/user"
 	"strings"
 
 	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/tonedefdev/terracreds/pkg/vault"
 	"github.com/urfave/cli/v2"
 )
 
@@ -32,6 +32,12 @@ func (cmd *Config) NewCommandList() *cli.Command {
 				Usage:    "Get the secrets from the 'secrets' list in the configuration file",
 				Required: false,
 			},
+			&cli.BoolFlag{
+				Name:     "all",
+				Value:    false,
+				Usage:    "Get every secret in the vault. Only providers that can enumerate their secrets such as 'pass' and 'file' support this flag",
+				Required: false,
+			},
 			&cli.BoolFlag{
 				Name:     "as-tfvars",
 				Value:    false,
@@ -62,7 +68,7 @@ func (cmd *Config) NewCommandList() *cli.Command {
 
 // newCommandActionList returns the secret names from the vault either as a string, TF_VARs, or JSON
 func (cmd *Config) newCommandActionList(c *cli.Context) error {
-	if len(os.Args) == 2 {
+	if c.NumFlags() == 0 {
 		err := &errors.CustomError{
 			Message: "No list command was specified. Use 'terracreds list -h' to print help info",
 			Level:   "ERROR",
@@ -72,20 +78,29 @@ func (cmd *Config) newCommandActionList(c *cli.Context) error {
 		return err
 	}
 
-	if len(os.Args) > 1 {
-		terraVault := cmd.NewTerraVault(os.Args[2])
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
 
-		if len(cmd.Cfg.Secrets) > 0 {
-			cmd.SecretNames = cmd.Cfg.Secrets
-		}
+	terraVault, err := cmd.NewTerraVault("")
+	if err != nil {
+		return err
+	}
 
-		if c.String("secret-names") != "" {
-			cmd.SecretNames = strings.Split(c.String("secret-names"), ",")
-		}
+	if len(cmd.Cfg.Secrets) > 0 {
+		cmd.SecretNames = cmd.Cfg.Secrets
+	}
+
+	if c.String("secret-names") != "" {
+		cmd.SecretNames = strings.Split(c.String("secret-names"), ",")
+	}
 
-		if len(cmd.Cfg.Secrets) < 1 && c.String("secret-names") == "" {
+	if c.Bool("all") {
+		enumerator, ok := terraVault.(vault.Enumerator)
+		if !ok {
 			err := &errors.CustomError{
-				Message: "A list of secrets must be provided. Use '--secret-names' and pass it a comma separated list of secrets, or setup the 'secrets' block in the terracreds config file to use this command",
+				Message: "The configured vault provider can't enumerate its secrets. Use '--secret-names' or the 'secrets' block in the terracreds config file instead of '--all'",
 				Level:   "ERROR",
 			}
 
@@ -93,51 +108,66 @@ func (cmd *Config) newCommandActionList(c *cli.Context) error {
 			return err
 		}
 
-		user, err := user.Current()
+		cmd.SecretNames, err = enumerator.Names(c.Context)
 		if err != nil {
-			helpers.CheckError(err)
+			return err
 		}
+	}
 
-		list, err := cmd.TerraCreds.List(c, cmd.Cfg, cmd.SecretNames, user, terraVault)
-		if err != nil {
-			helpers.CheckError(err)
+	if len(cmd.Cfg.Secrets) < 1 && c.String("secret-names") == "" && !c.Bool("all") {
+		err := &errors.CustomError{
+			Message: "A list of secrets must be provided. Use '--secret-names' and pass it a comma separated list of secrets, or setup the 'secrets' block in the terracreds config file to use this command",
+			Level:   "ERROR",
 		}
 
-		if c.Bool("as-json") {
-			body := make(map[string]string, len(cmd.SecretNames))
-			for i, name := range cmd.SecretNames {
-				body[name] = list[i]
-			}
+		helpers.Logging(cmd.Cfg, err.Message, err.Level)
+		return err
+	}
 
-			json, err := json.Marshal(body)
-			if err != nil {
-				helpers.CheckError(err)
-			}
+	user, err := user.Current()
+	if err != nil {
+		return err
+	}
+
+	list, err := cmd.TerraCreds.List(c.Context, cfg, cmd.SecretNames, user, terraVault)
+	if err != nil {
+		return err
+	}
 
-			fmt.Println(string(json))
-			return nil
+	logChainAnswer(cfg, terraVault, strings.Join(cmd.SecretNames, ","))
+
+	if c.Bool("as-json") {
+		body := make(map[string]string, len(cmd.SecretNames))
+		for i, name := range cmd.SecretNames {
+			body[name] = list[i]
 		}
 
-		if c.Bool("as-tfvars") {
-			for i, name := range cmd.SecretNames {
-				if c.String("override-replace-string") != "" {
-					cmd.DefaultReplaceString = c.String("override-replace-string")
-				}
+		json, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
 
-				formatSecretName := strings.Replace(name, "-", cmd.DefaultReplaceString, -1)
-				fmt.Printf("TF_VAR_%s=%s\n", formatSecretName, list[i])
+		fmt.Println(string(json))
+		return nil
+	}
+
+	if c.Bool("as-tfvars") {
+		for i, name := range cmd.SecretNames {
+			if c.String("override-replace-string") != "" {
+				cmd.DefaultReplaceString = c.String("override-replace-string")
 			}
 
-			return nil
+			formatSecretName := strings.Replace(name, "-", cmd.DefaultReplaceString, -1)
+			fmt.Printf("TF_VAR_%s=%s\n", formatSecretName, list[i])
 		}
 
-		for _, secret := range list {
-			value := fmt.Sprintf("%s\n", secret)
-			fmt.Print(value)
-		}
+		return nil
+	}
 
-		return err
+	for _, secret := range list {
+		value := fmt.Sprintf("%s\n", secret)
+		fmt.Print(value)
 	}
 
-	return nil
+	return err
 }
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/tonedefdev/terracreds/api"
+	"github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+)
+
+// activeConfig returns the configuration with the vault provider settings and host rules of the
+// selected profile, or the configuration as-is when no profile has been selected
+func (cmd *Config) activeConfig() (*api.Config, error) {
+	if cmd.Profile == "" {
+		return cmd.Cfg, nil
+	}
+
+	profile, ok := cmd.Cfg.Profiles[cmd.Profile]
+	if !ok || profile == nil {
+		err := &errors.CustomError{
+			Message: fmt.Sprintf("The profile '%s' was not found in the configuration file", cmd.Profile),
+			Level:   "ERROR",
+		}
+
+		helpers.Logging(cmd.Cfg, err.Message, err.Level)
+		return nil, err
+	}
+
+	cfg := *cmd.Cfg
+	cfg.Providers = profile.Providers
+	cfg.Hosts = profile.Hosts
+	return &cfg, nil
+}
+
+// profile returns the selected profile, adding it to the configuration when it doesn't exist yet
+func (cmd *Config) profile() *api.Profile {
+	if cmd.Cfg.Profiles == nil {
+		cmd.Cfg.Profiles = make(map[string]*api.Profile)
+	}
+
+	profile, ok := cmd.Cfg.Profiles[cmd.Profile]
+	if !ok || profile == nil {
+		profile = &api.Profile{}
+		cmd.Cfg.Profiles[cmd.Profile] = profile
+	}
+
+	return profile
+}
+
+// hostRules returns the host rules of the selected profile, or the top-level host rules when no
+// profile has been selected
+func (cmd *Config) hostRules() *[]api.Host {
+	if cmd.Profile == "" {
+		return &cmd.Cfg.Hosts
+	}
+
+	return &cmd.profile().Hosts
+}
+
+// activeProviders returns the vault provider settings of the selected profile, or the top-level
+// settings when no profile has been selected
+func (cmd *Config) activeProviders() *api.Providers {
+	if cmd.Profile == "" {
+		return &cmd.Cfg.Providers
+	}
+
+	return &cmd.profile().Providers
+}
+
+// writeProviders updates the vault provider settings of the selected profile, or the top-level settings
+// when no profile has been selected, and writes them to the config file. The other provider blocks are
+// removed first unless a fallback chain is configured, since the chain reads from each of them
+func (cmd *Config) writeProviders(update func(providers *api.Providers) error) error {
+	providers := cmd.activeProviders()
+	if len(providers.Chain.Providers) == 0 {
+		*providers = api.Providers{}
+	}
+
+	err := update(providers)
+	if err != nil {
+		return err
+	}
+
+	return helpers.WriteConfig(cmd.ConfigFile.Path, cmd.Cfg)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestNewCommandActionAwsProfile(t *testing.T) {
+	app := app()
+	terracreds := config()
+	terracreds.Profile = "dev"
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandConfig(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "config", "aws", "--region=us-east-1")
+	app.Run(args)
+
+	terracreds.LoadConfig(terracreds.ConfigFile.Path)
+
+	profile, ok := terracreds.Cfg.Profiles["dev"]
+	if !ok {
+		t.Fatal("expected the 'dev' profile to be created")
+	}
+
+	if profile.Aws.Region != "us-east-1" {
+		t.Errorf("Profiles.dev.Aws.Region is '%s' expected 'us-east-1'", profile.Aws.Region)
+	}
+
+	if terracreds.Cfg.Aws.Region != "" {
+		t.Errorf("Aws.Region is '%s' expected the top-level block to be unchanged", terracreds.Cfg.Aws.Region)
+	}
+
+	cfg, err := terracreds.activeConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Aws.Region != "us-east-1" {
+		t.Errorf("active Aws.Region is '%s' expected 'us-east-1'", cfg.Aws.Region)
+	}
+}
+
+func TestActiveConfigMissingProfile(t *testing.T) {
+	terracreds := config()
+	terracreds.Profile = "missing"
+
+	_, err := terracreds.activeConfig()
+	if err == nil {
+		t.Fatal("expected an error for a profile that doesn't exist")
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/urfave/cli/v2"
+)
+
+// NewCommandRestore creates the command to undelete soft deleted versions of a secret
+func (cmd *Config) NewCommandRestore() *cli.Command {
+	cmdRestore := &cli.Command{
+		Name:  "restore",
+		Usage: "Restore soft deleted versions of the secret a credential is stored in",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "name",
+				Aliases:  []string{"n"},
+				Usage:    "The name of the Terraform Automation and Collaboration Software server's hostname or the name of the secret",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "versions",
+				Usage: "A comma separated list of the versions to restore such as '1,2'. If omitted the latest version is restored",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := cmd.newCommandActionRestore(c)
+			return err
+		},
+	}
+
+	return cmdRestore
+}
+
+// newCommandActionRestore undeletes the versions of the secret with a vault provider that keeps
+// the versions of its secrets
+func (cmd *Config) newCommandActionRestore(c *cli.Context) error {
+	if c.String("name") == "" {
+		err := &errors.CustomError{
+			Message: "No secret name was specified. Use 'terracreds restore -h' to print help info",
+			Level:   "ERROR",
+		}
+
+		helpers.Logging(cmd.Cfg, err.Message, err.Level)
+		return err
+	}
+
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	terraVault, err := cmd.NewTerraVault(c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	name, err := GetSecretName(cfg, c.String("name"))
+	if err != nil {
+		return err
+	}
+
+	versions, err := cmd.parseVersions(c.String("versions"))
+	if err != nil {
+		return err
+	}
+
+	versioner, err := cmd.findVersioner(terraVault)
+	if err != nil {
+		return err
+	}
+
+	err = versioner.Undelete(c.Context, versions)
+	if err != nil {
+		helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
+		return err
+	}
+
+	msg := fmt.Sprintf("the latest version of the secret that stores '%s' was restored", name)
+	if len(versions) > 0 {
+		msg = fmt.Sprintf("the versions %s of the secret that stores '%s' were restored", c.String("versions"), name)
+	}
+
+	helpers.Logging(cfg, fmt.Sprintf("- %s", msg), "INFO")
+	fmt.Fprintf(color.Output, "%s: %s%s\n", color.GreenString("SUCCESS"), strings.ToUpper(msg[:1]), msg[1:])
+	return nil
+}
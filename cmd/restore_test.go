@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestNewCommandActionRestore(t *testing.T) {
+	terracreds := config()
+	app := app()
+	app.Commands = []*cli.Command{
+		terracreds.NewCommandDelete(),
+		terracreds.NewCommandRestore(),
+	}
+
+	args := os.Args[0:1]
+	args = append(args, "restore", "--name=test", "--versions=1,2")
+	if err := app.Run(args); err == nil {
+		t.Errorf("expected an error for a vault provider that doesn't keep versions")
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "delete", "--name=test", "--destroy")
+	if err := app.Run(args); err == nil {
+		t.Errorf("expected an error for '--destroy' without '--versions'")
+	}
+
+	args = os.Args[0:1]
+	args = append(args, "delete", "--name=test", "--soft-delete", "--versions=latest")
+	if err := app.Run(args); err == nil {
+		t.Errorf("expected an error for a version that's not a number")
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/mitchellh/go-homedir"
+	"github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/urfave/cli/v2"
+)
+
+// NewCommandRotate creates the command to re-encrypt the file vault with a new passphrase
+func (cmd *Config) NewCommandRotate() *cli.Command {
+	cmdRotate := &cli.Command{
+		Name:  "rotate",
+		Usage: "Re-encrypt the encrypted file vault with a new passphrase",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "new-key-file",
+				Usage: "The path to a file that contains the new passphrase",
+			},
+			&cli.StringFlag{
+				Name:  "new-passphrase-env",
+				Usage: "The name of the environment variable that holds the new passphrase. If omitted and no key file is set the new passphrase is prompted for",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			err := cmd.newCommandActionRotate(c)
+			return err
+		},
+	}
+
+	return cmdRotate
+}
+
+// newCommandActionRotate re-encrypts the file vault that's configured, or used in place of
+// the keyring, with the new passphrase
+func (cmd *Config) newCommandActionRotate(c *cli.Context) error {
+	terraVault, err := cmd.NewTerraVault("")
+	if err != nil {
+		return err
+	}
+
+	fileVault := findFileVault(terraVault)
+	if fileVault == nil {
+		err := &errors.CustomError{
+			Message: "The rotate command requires the encrypted file vault provider. Use 'terracreds config file' to configure it",
+			Level:   "ERROR",
+		}
+
+		return err
+	}
+
+	passphrase, err := newPassphrase(c)
+	if err != nil {
+		return err
+	}
+
+	err = fileVault.Rotate(c.Context, passphrase)
+	if err != nil {
+		return err
+	}
+
+	helpers.Logging(cmd.Cfg, "- the file vault was re-encrypted with a new passphrase", "INFO")
+	fmt.Fprintf(color.Output, "%s: Re-encrypted the file vault with the new passphrase. Update the key file or environment variable that holds the passphrase\n", color.GreenString("SUCCESS"))
+	return nil
+}
+
+// findFileVault returns the file vault itself or the file vault in a fallback chain
+func findFileVault(terraVault vault.TerraVault) *vault.FileVault {
+	switch v := terraVault.(type) {
+	case *vault.FileVault:
+		return v
+	case *vault.Chain:
+		for _, link := range v.Links {
+			if fileVault, ok := link.Vault.(*vault.FileVault); ok {
+				return fileVault
+			}
+		}
+	}
+
+	return nil
+}
+
+// newPassphrase returns the new passphrase from the key file, the environment variable or the terminal
+func newPassphrase(c *cli.Context) (string, error) {
+	if c.String("new-key-file") != "" {
+		path, err := homedir.Expand(c.String("new-key-file"))
+		if err != nil {
+			return "", err
+		}
+
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(bytes), "\r\n"), nil
+	}
+
+	if c.String("new-passphrase-env") != "" {
+		passphrase := os.Getenv(c.String("new-passphrase-env"))
+		if passphrase == "" {
+			err := &errors.CustomError{
+				Message: fmt.Sprintf("The environment variable '%s' is empty", c.String("new-passphrase-env")),
+				Level:   "ERROR",
+			}
+
+			return "", err
+		}
+
+		return passphrase, nil
+	}
+
+	passphrase, err := helpers.ReadPassword("Enter the new passphrase: ")
+	if err != nil {
+		return "", err
+	}
+
+	confirm, err := helpers.ReadPassword("Enter the new passphrase again: ")
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase != confirm {
+		err := &errors.CustomError{
+			Message: "The passphrases don't match",
+			Level:   "ERROR",
+		}
+
+		return "", err
+	}
+
+	return passphrase, nil
+}
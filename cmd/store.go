@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"os"
 	"os/user"
 
 	"github.com/tonedefdev/terracreds/pkg/errors"
@@ -25,7 +24,7 @@ func (cmd *Config) NewCommandStore() *cli.Command {
 
 // newCommandActionStore creates the secret in the vault when 'terraform login' is called
 func (cmd *Config) newCommandActionStore(c *cli.Context) error {
-	if len(os.Args) == 2 {
+	if c.NArg() == 0 {
 		err := &errors.CustomError{
 			Message: "No hostname was specified. Use 'terracreds store -h' to print help info",
 			Level:   "ERROR",
@@ -35,16 +34,32 @@ func (cmd *Config) newCommandActionStore(c *cli.Context) error {
 		return err
 	}
 
-	terraVault := cmd.NewTerraVault(os.Args[2])
-	name := GetSecretName(cmd.Cfg, os.Args[2])
+	cfg, err := cmd.activeConfig()
+	if err != nil {
+		return err
+	}
+
+	terraVault, err := cmd.NewTerraVault(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	name, err := GetSecretName(cfg, c.Args().First())
+	if err != nil {
+		return err
+	}
 
 	user, err := user.Current()
-	helpers.CheckError(err)
+	if err != nil {
+		return err
+	}
 
-	err = cmd.TerraCreds.Create(cmd.Cfg, name, nil, user, terraVault)
+	err = cmd.TerraCreds.Create(c.Context, cfg, name, nil, user, terraVault)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
+	logChainAnswer(cfg, terraVault, name)
+
 	return err
 }
@@ -1,16 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/user"
+	"path"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"text/template"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/fatih/color"
 	"github.com/tonedefdev/terracreds/api"
+	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/tonedefdev/terracreds/pkg/helpers"
 	"github.com/tonedefdev/terracreds/pkg/platform"
 	"github.com/tonedefdev/terracreds/pkg/vault"
@@ -21,13 +26,13 @@ import (
 // TerraCreds interface implements these methods for a credential's lifecycle
 type TerraCreds interface {
 	// Create or store a secret in a vault
-	Create(cfg *api.Config, hostname string, token any, user *user.User, vault vault.TerraVault) error
+	Create(ctx context.Context, cfg *api.Config, hostname string, token any, user *user.User, vault vault.TerraVault) error
 	// Delete or forget a secret in a vault
-	Delete(cfg *api.Config, command string, hostname string, user *user.User, vault vault.TerraVault) error
+	Delete(ctx context.Context, cfg *api.Config, command string, hostname string, user *user.User, vault vault.TerraVault) error
 	// Get or retrieve a secret in a vault
-	Get(cfg *api.Config, hostname string, user *user.User, vault vault.TerraVault) ([]byte, error)
+	Get(ctx context.Context, cfg *api.Config, hostname string, user *user.User, vault vault.TerraVault) ([]byte, error)
 	// List the secrets from within a vault
-	List(c *cli.Context, cfg *api.Config, secretNames []string, user *user.User, vault vault.TerraVault) ([]string, error)
+	List(ctx context.Context, cfg *api.Config, secretNames []string, user *user.User, vault vault.TerraVault) ([]string, error)
 }
 
 // CopyTerraCreds will create a copy of the binary to the destination path.
@@ -63,8 +68,9 @@ func CopyTerraCreds(dest string) error {
 	return err
 }
 
-// GenerateTerracreds creates the binary to use this package as a credential helper and optionally the terraform.rc file
-func GenerateTerraCreds(c *cli.Context, version string, confirm string) error {
+// GenerateTerracreds creates the binary to use this package as a credential helper and optionally the terraform.rc file.
+// When a profile is provided the credentials helper block passes it to terracreds as an argument
+func GenerateTerraCreds(c *cli.Context, version string, confirm string, profile string) error {
 	var cliConfig string
 	var tfPlugins string
 	var binary string
@@ -94,10 +100,15 @@ func GenerateTerraCreds(c *cli.Context, version string, confirm string) error {
 	}
 
 	if c.Bool("create-cli-config") {
-		doc := heredoc.Doc(`
+		args := "[]"
+		if profile != "" {
+			args = fmt.Sprintf("[\"--profile\", %q]", profile)
+		}
+
+		doc := heredoc.Docf(`
 		credentials_helper "terracreds" {
-		  args = []
-		}`)
+		  args = %s
+		}`, args)
 
 		if !c.Bool("force") {
 			const verbiage = "This command will delete any settings in your .terraformrc file\n\n    Enter 'yes' to continue or press 'enter' or 'return' to cancel: "
@@ -118,22 +129,115 @@ func GenerateTerraCreds(c *cli.Context, version string, confirm string) error {
 	return nil
 }
 
-// GetSecretName returns the name of the secret from the config or returns the hostname value from the CLI
-func GetSecretName(cfg *api.Config, hostname string) string {
-	if cfg.Aws.SecretName != "" {
-		return cfg.Aws.SecretName
+// GetSecretName returns the name of the secret from the host rule matching the hostname or from
+// the config, or returns the hostname value from the CLI
+func GetSecretName(cfg *api.Config, hostname string) (string, error) {
+	providers := &cfg.Providers
+
+	host := MatchHost(cfg, hostname)
+	if host != nil {
+		if host.SecretName != "" {
+			return renderSecretName(host.SecretName, hostname)
+		}
+
+		providers = &host.Providers
 	}
-	if cfg.Azure.SecretName != "" {
-		return cfg.Azure.SecretName
+
+	blocks, err := providers.Blocks()
+	if err != nil {
+		return "", err
 	}
-	if cfg.HashiVault.SecretName != "" {
-		return cfg.HashiVault.SecretName
+
+	for _, provider := range vault.Registered() {
+		if provider.Key == "" || provider.SecretNameKey == "" {
+			continue
+		}
+
+		secretName := vault.ProviderConfig(blocks[provider.Key]).String(provider.SecretNameKey)
+		if secretName != "" {
+			return secretName, nil
+		}
 	}
-	return hostname
+
+	return hostname, nil
+}
+
+// logChainAnswer logs which vault provider answered the request when the vault is a fallback chain
+func logChainAnswer(cfg *api.Config, terraVault vault.TerraVault, name string) {
+	if chain, ok := terraVault.(*vault.Chain); ok && chain.Answered != "" {
+		msg := fmt.Sprintf("- the request for '%s' was answered by the vault provider '%s'", name, chain.Answered)
+		helpers.Logging(cfg, msg, "INFO")
+	}
+}
+
+// MatchHost returns the first host rule whose pattern matches the hostname, or nil
+// when no rule matches
+func MatchHost(cfg *api.Config, hostname string) *api.Host {
+	if hostname == "" {
+		return nil
+	}
+
+	normalized, err := helpers.NormalizeHostname(hostname)
+	if err != nil {
+		normalized = hostname
+	}
+
+	for i := range cfg.Hosts {
+		pattern := strings.ToLower(cfg.Hosts[i].Pattern)
+		matched, err := path.Match(pattern, normalized)
+		if err == nil && matched {
+			return &cfg.Hosts[i]
+		}
+	}
+
+	return nil
+}
+
+// renderSecretName executes a host rule's secret name template for the hostname
+func renderSecretName(secretName string, hostname string) (string, error) {
+	tmpl, err := template.New("secretName").Option("missingkey=error").Parse(secretName)
+	if err != nil {
+		return "", err
+	}
+
+	data := struct {
+		Hostname string
+	}{
+		Hostname: hostname,
+	}
+
+	var name strings.Builder
+	err = tmpl.Execute(&name, data)
+	if err != nil {
+		return "", err
+	}
+
+	return name.String(), nil
+}
+
+// providerName returns the name of the vault provider configured in the providers block. When more
+// than one block is set the provider whose name sorts first is used
+func providerName(providers *api.Providers) (string, error) {
+	if len(providers.Chain.Providers) > 0 {
+		return fmt.Sprintf("chain (%s)", strings.Join(providers.Chain.Providers, " -> ")), nil
+	}
+
+	blocks, err := providers.Blocks()
+	if err != nil {
+		return "", err
+	}
+
+	for _, provider := range vault.Registered() {
+		if provider.Key != "" && len(blocks[provider.Key]) > 0 {
+			return provider.Name, nil
+		}
+	}
+
+	return "keyring", nil
 }
 
 // InitTerraCreds initializes the configuration for Terracreds
-func (cmd *Config) InitTerraCreds() {
+func (cmd *Config) InitTerraCreds() error {
 	if cmd.ConfigFile.EnvironmentValue != "" {
 		cmd.ConfigFile.Path = filepath.Join(cmd.ConfigFile.EnvironmentValue, cmd.ConfigFile.Name)
 	} else {
@@ -143,16 +247,23 @@ func (cmd *Config) InitTerraCreds() {
 
 	err := helpers.CreateConfigFile(cmd.ConfigFile.Path)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
 	}
 
 	err = cmd.LoadConfig(cmd.ConfigFile.Path)
 	if err != nil {
-		helpers.CheckError(err)
+		return err
+	}
+
+	vault.Log = func(msg string, level string) {
+		helpers.Logging(cmd.Cfg, msg, level)
 	}
+
+	return nil
 }
 
-// LoadConfig loads the config file if it exists
+// LoadConfig loads the config file if it exists and checks that each vault provider
+// block belongs to a registered vault provider
 func (cmd *Config) LoadConfig(path string) error {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
@@ -160,7 +271,40 @@ func (cmd *Config) LoadConfig(path string) error {
 	}
 
 	err = yaml.Unmarshal(bytes, &cmd.Cfg)
-	return err
+	if err != nil {
+		return err
+	}
+
+	providers := []*api.Providers{&cmd.Cfg.Providers}
+	for i := range cmd.Cfg.Hosts {
+		providers = append(providers, &cmd.Cfg.Hosts[i].Providers)
+	}
+
+	for _, profile := range cmd.Cfg.Profiles {
+		if profile == nil {
+			continue
+		}
+
+		providers = append(providers, &profile.Providers)
+		for i := range profile.Hosts {
+			providers = append(providers, &profile.Hosts[i].Providers)
+		}
+	}
+
+	for _, block := range providers {
+		for key := range block.Extra {
+			if _, ok := vault.LookupKey(key); !ok {
+				err := &errors.CustomError{
+					Message: fmt.Sprintf("The '%s' block in the configuration file '%s' doesn't belong to a registered vault provider", key, path),
+					Level:   "ERROR",
+				}
+
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 // NewTerraCreds is the constructor to create a TerraCreds interface
@@ -168,64 +312,107 @@ func NewTerraCreds() TerraCreds {
 	return &platform.Platform{}
 }
 
-// NewTerrVault is the constructor to create a TerraVault interface for the vault provider defined in the Cfg
-func (cmdCfg *Config) NewTerraVault(hostname string) vault.TerraVault {
-	if cmdCfg.Cfg.Aws.Region != "" {
-		vault := &vault.AwsSecretsManager{
-			Description: cmdCfg.Cfg.Aws.Description,
-			Region:      cmdCfg.Cfg.Aws.Region,
-			SecretName:  hostname,
-		}
+// NewTerraVault is the constructor to create a TerraVault interface for the vault provider defined
+// in the host rule matching the hostname, or in the Cfg when no rule matches. The settings of the
+// selected profile are used in place of the top-level ones
+func (cmdCfg *Config) NewTerraVault(hostname string) (vault.TerraVault, error) {
+	cfg, err := cmdCfg.activeConfig()
+	if err != nil {
+		return nil, err
+	}
 
-		if cmdCfg.Cfg.Aws.SecretName != "" {
-			vault.SecretName = cmdCfg.Cfg.Aws.SecretName
-		}
+	secretName, err := GetSecretName(cfg, hostname)
+	if err != nil {
+		return nil, err
+	}
 
-		return vault
+	providers := &cfg.Providers
+	if host := MatchHost(cfg, hostname); host != nil {
+		providers = &host.Providers
 	}
 
-	if cmdCfg.Cfg.Azure.VaultUri != "" {
-		vault := &vault.AzureKeyVault{
-			SecretName:     hostname,
-			SubscriptionId: cmdCfg.Cfg.Azure.SubscriptionId,
-			VaultUri:       cmdCfg.Cfg.Azure.VaultUri,
-		}
+	return newTerraVault(providers, secretName)
+}
 
-		if cmdCfg.Cfg.Azure.SecretName != "" {
-			vault.SecretName = cmdCfg.Cfg.Azure.SecretName
-		}
+// newTerraVault creates a TerraVault interface for the vault provider configured in the providers block.
+// A nil TerraVault is returned when the local operating system's credential vault is used
+func newTerraVault(providers *api.Providers, secretName string) (vault.TerraVault, error) {
+	if len(providers.Chain.Providers) > 0 {
+		return newChain(providers, secretName)
+	}
 
-		return vault
+	name, err := providerName(providers)
+	if err != nil {
+		return nil, err
 	}
 
-	if cmdCfg.Cfg.GCP.ProjectId != "" {
-		vault := &vault.GCPSecretManager{
-			ProjectId: cmdCfg.Cfg.GCP.ProjectId,
-			SecretId:  hostname,
+	if name == "keyring" {
+		if runtime.GOOS != "linux" {
+			return nil, nil
+		}
+
+		available, err := vault.SecretServiceAvailable()
+		if err != nil {
+			return nil, err
 		}
 
-		if cmdCfg.Cfg.GCP.SecretId != "" {
-			vault.SecretId = cmdCfg.Cfg.GCP.SecretId
+		if available {
+			return nil, nil
 		}
 
-		return vault
+		vault.Log("- the Secret Service is unavailable so the encrypted file vault is used instead of the keyring", "WARNING")
+		name = "file"
+	}
+
+	return newNamedTerraVault(providers, name, secretName)
+}
+
+// newChain creates a TerraVault interface for the fallback chain configured in the providers block
+func newChain(providers *api.Providers, secretName string) (vault.TerraVault, error) {
+	chain := &vault.Chain{
+		Primary: providers.Chain.Primary,
+	}
+
+	if chain.Primary == "" {
+		chain.Primary = providers.Chain.Providers[0]
 	}
 
-	if cmdCfg.Cfg.HashiVault.VaultUri != "" {
-		vault := &vault.HashiVault{
-			EnvTokenName: cmdCfg.Cfg.HashiVault.EnvironmentTokenName,
-			KeyVaultPath: cmdCfg.Cfg.HashiVault.KeyVaultPath,
-			SecretName:   hostname,
-			SecretPath:   cmdCfg.Cfg.HashiVault.SecretPath,
-			VaultUri:     cmdCfg.Cfg.HashiVault.VaultUri,
+	for _, name := range providers.Chain.Providers {
+		terraVault, err := newNamedTerraVault(providers, name, secretName)
+		if err != nil {
+			return nil, err
 		}
 
-		if cmdCfg.Cfg.HashiVault.SecretName != "" {
-			vault.SecretName = cmdCfg.Cfg.HashiVault.SecretName
+		chain.Links = append(chain.Links, vault.ChainLink{
+			Name:  name,
+			Vault: terraVault,
+		})
+	}
+
+	return chain, nil
+}
+
+// newNamedTerraVault creates a TerraVault interface for the named vault provider using its
+// configuration block in the providers block
+func newNamedTerraVault(providers *api.Providers, name string, secretName string) (vault.TerraVault, error) {
+	provider, ok := vault.Lookup(name)
+	if !ok {
+		err := &errors.CustomError{
+			Message: fmt.Sprintf("The vault provider '%s' is not supported. Use one of %s", name, strings.Join(vault.Names(), ", ")),
+			Level:   "ERROR",
 		}
 
-		return vault
+		return nil, err
 	}
 
-	return nil
+	var block map[string]interface{}
+	if provider.Key != "" {
+		var err error
+		block, err = providers.Block(provider.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return provider.New(block, secretName)
 }
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/tonedefdev/terracreds/cmd"
+	"github.com/tonedefdev/terracreds/pkg/errors"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,7 +20,11 @@ func main() {
 		},
 	}
 
-	terracreds.InitTerraCreds()
+	err := terracreds.InitTerraCreds()
+	if err != nil {
+		print(err.Error())
+		os.Exit(errors.ExitCode(err))
+	}
 
 	app := &cli.App{
 		Name:                 "terracreds",
@@ -27,6 +32,17 @@ func main() {
 		Usage:                "a credential helper for Terraform Automation and Collaboration Software (TACOS) that leverages your vault provider of choice for securely storing API tokens or other secrets.\n\n   Visit https://github.com/tonedefdev/terracreds for more information",
 		UsageText:            "Store Terraform Enterprise or Cloud API tokens by running 'terraform login' or manually store any secret you choose with 'terracreds create -n mySuperSecret -v mySuperSafePassword'",
 		Version:              terracreds.Version,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "profile",
+				EnvVars: []string{"TC_PROFILE"},
+				Usage:   "The name of the profile in the configuration file to use in place of the top-level vault provider settings",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			terracreds.Profile = c.String("profile")
+			return nil
+		},
 		Commands: []*cli.Command{
 			terracreds.NewCommandConfig(),
 			terracreds.NewCommandCreate(),
@@ -35,13 +51,15 @@ func main() {
 			terracreds.NewCommandGenerate(),
 			terracreds.NewCommandGet(),
 			terracreds.NewCommandList(),
+			terracreds.NewCommandRestore(),
+			terracreds.NewCommandRotate(),
 			terracreds.NewCommandStore(),
 		},
 	}
 
-	err := app.Run(os.Args)
+	err = app.Run(os.Args)
 	if err != nil {
 		print(err.Error())
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
 	}
 }
@@ -10,9 +10,17 @@ import (
 type CustomError struct {
 	Message string
 	Level   string
+
+	// Err is the underlying error, such as ErrPermissionDenied, used to select the exit code
+	Err error
 }
 
 // Error returns a custom formatted error message
 func (ce *CustomError) Error() string {
 	return fmt.Sprintf("%s: %s\n", color.RedString(ce.Level), ce.Message)
 }
+
+// Unwrap returns the underlying error
+func (ce *CustomError) Unwrap() error {
+	return ce.Err
+}
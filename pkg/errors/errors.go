@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when a secret does not exist in a vault
+var ErrNotFound = errors.New("secret not found")
+
+// ErrPermissionDenied is returned when the caller isn't allowed to access a secret or
+// couldn't be authenticated by the vault
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrUnavailable is returned when a vault can't be reached, is throttling requests, or
+// didn't respond before the operation timed out
+var ErrUnavailable = errors.New("vault unavailable")
+
+// ErrConflict is returned when a secret can't be written because of its current state in
+// the vault, such as a secret that already exists or was changed by another client
+var ErrConflict = errors.New("conflict")
+
+// Exit codes returned by terracreds. They are documented in the README and must not change
+const (
+	ExitOK               = 0
+	ExitError            = 1
+	ExitNotFound         = 3
+	ExitPermissionDenied = 4
+	ExitUnavailable      = 5
+	ExitConflict         = 6
+)
+
+// IsNotFound reports whether the error, or any error it wraps, is ErrNotFound
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// ExitCode returns the exit code for the error. Permission errors take precedence over
+// the other kinds so that a denied request is never reported as a missing secret
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrPermissionDenied):
+		return ExitPermissionDenied
+	case errors.Is(err, ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		return ExitUnavailable
+	case errors.Is(err, ErrConflict):
+		return ExitConflict
+	case errors.Is(err, ErrNotFound):
+		return ExitNotFound
+	default:
+		return ExitError
+	}
+}
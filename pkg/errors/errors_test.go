@@ -0,0 +1,34 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "nil", err: nil, want: ExitOK},
+		{name: "generic", err: errors.New("boom"), want: ExitError},
+		{name: "not found", err: fmt.Errorf("%w: app.terraform.io", ErrNotFound), want: ExitNotFound},
+		{name: "permission denied", err: fmt.Errorf("%w: app.terraform.io", ErrPermissionDenied), want: ExitPermissionDenied},
+		{name: "unavailable", err: fmt.Errorf("%w: app.terraform.io", ErrUnavailable), want: ExitUnavailable},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded), want: ExitUnavailable},
+		{name: "conflict", err: fmt.Errorf("%w: app.terraform.io", ErrConflict), want: ExitConflict},
+		{name: "custom error", err: &CustomError{Message: "denied", Level: "ERROR", Err: ErrPermissionDenied}, want: ExitPermissionDenied},
+		{name: "permission and not found", err: errors.Join(ErrNotFound, ErrPermissionDenied), want: ExitPermissionDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ExitCode(test.err); got != test.want {
+				t.Errorf("ExitCode(%v) is %d expected %d", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -6,20 +6,24 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
 	"github.com/mitchellh/go-homedir"
 	"github.com/tonedefdev/terracreds/api"
+	"golang.org/x/net/idna"
 	"gopkg.in/yaml.v2"
 )
 
-// CheckError processes the error
-func CheckError(e error) {
-	if e != nil {
-		log.Fatal(e)
-	}
-}
+// hostnameProfile is the IDNA profile used to normalize hostnames. Underscores
+// are permitted since the 'get' command also accepts plain secret names
+var hostnameProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+)
 
 // CreateConfigFile creates a default terracreds config file if one does not
 // exist in the specified file path
@@ -99,6 +103,38 @@ func GetBinaryPath(binary string, os string) string {
 	}
 }
 
+// NormalizeHostname returns the hostname in the form Terraform uses when it calls
+// a credentials helper. The hostname is case folded and converted to its IDNA
+// ASCII form, and an optional port is kept unless it is the default port 443
+func NormalizeHostname(hostname string) (string, error) {
+	host := hostname
+	port := ""
+
+	if i := strings.LastIndex(hostname, ":"); i != -1 {
+		host = hostname[:i]
+		port = hostname[i+1:]
+
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", fmt.Errorf("invalid port '%s' in hostname '%s'", port, hostname)
+		}
+	}
+
+	ascii, err := hostnameProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid hostname '%s': %w", hostname, err)
+	}
+
+	if ascii == "" {
+		return "", fmt.Errorf("invalid hostname '%s'", hostname)
+	}
+
+	if port == "" || port == "443" {
+		return ascii, nil
+	}
+
+	return fmt.Sprintf("%s:%s", ascii, port), nil
+}
+
 // NewDirectory checks for the existence of a directory
 // if it doesn't exist it creates it and checks for errors
 func NewDirectory(path string) error {
@@ -120,7 +156,8 @@ func Logging(cfg *api.Config, msg string, level string) {
 	if cfg.Logging.Enabled {
 		absolutePath, err := homedir.Expand(cfg.Logging.Path)
 		if err != nil {
-			log.Fatal(err)
+			fmt.Fprintf(color.Error, "%s: Unable to write to the log: %s\n", color.YellowString("WARNING"), err)
+			return
 		}
 
 		logPath := filepath.Join(absolutePath, "terracreds.log")
@@ -0,0 +1,32 @@
+package helpers
+
+import "testing"
+
+func TestNormalizeHostname(t *testing.T) {
+	cases := map[string]string{
+		"app.terraform.io":     "app.terraform.io",
+		"App.Terraform.IO":     "app.terraform.io",
+		"app.terraform.io:443": "app.terraform.io",
+		"tfe.example.com:8443": "tfe.example.com:8443",
+		"BÜCHER.example":       "xn--bcher-kva.example",
+		"my_secret":            "my_secret",
+	}
+
+	for hostname, expected := range cases {
+		normalized, err := NormalizeHostname(hostname)
+		if err != nil {
+			t.Fatalf("NormalizeHostname(%q) returned error: %s", hostname, err)
+		}
+
+		if normalized != expected {
+			t.Errorf("NormalizeHostname(%q) is '%s' expected '%s'", hostname, normalized, expected)
+		}
+	}
+}
+
+func TestNormalizeHostnameInvalidPort(t *testing.T) {
+	_, err := NormalizeHostname("app.terraform.io:https")
+	if err == nil {
+		t.Fatal("expected an error for a non-numeric port")
+	}
+}
@@ -0,0 +1,29 @@
+package helpers
+
+import (
+	"fmt"
+
+	"golang.org/x/term"
+)
+
+// ReadPassword prints the prompt to the terminal and reads a line from it without echoing the
+// input. The terminal is used instead of stdin since Terraform talks to terracreds over stdin
+func ReadPassword(prompt string) (string, error) {
+	in, out, err := openTerminal()
+	if err != nil {
+		return "", fmt.Errorf("no terminal is available to prompt for a passphrase: %w", err)
+	}
+	defer in.Close()
+	if out != in {
+		defer out.Close()
+	}
+
+	fmt.Fprint(out, prompt)
+	password, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
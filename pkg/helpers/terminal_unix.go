@@ -0,0 +1,15 @@
+//go:build !windows
+
+package helpers
+
+import "os"
+
+// openTerminal opens the controlling terminal, which the input is read from and the prompt is written to
+func openTerminal() (*os.File, *os.File, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tty, tty, nil
+}
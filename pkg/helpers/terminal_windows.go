@@ -0,0 +1,21 @@
+//go:build windows
+
+package helpers
+
+import "os"
+
+// openTerminal opens the console for reading the input and writing the prompt
+func openTerminal() (*os.File, *os.File, error) {
+	in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+
+	return in, out, nil
+}
@@ -1,14 +1,15 @@
 package platform
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/user"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/urfave/cli/v2"
 	"github.com/zalando/go-keyring"
 
 	"github.com/tonedefdev/terracreds/api"
@@ -19,8 +20,59 @@ import (
 
 type Platform struct{}
 
+// withTimeout returns a context that's canceled after the timeout, or after
+// api.DefaultTimeout when no timeout is configured for the operation
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = api.DefaultTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// vaultContext returns the context of an operation on a vault. The timeout of a chain is set
+// on each of its vaults instead, so that a vault that's slow doesn't use up the others' time
+func vaultContext(ctx context.Context, timeout time.Duration, terraVault vault.TerraVault) (context.Context, context.CancelFunc) {
+	chain, ok := terraVault.(*vault.Chain)
+	if !ok {
+		return withTimeout(ctx, timeout)
+	}
+
+	chain.Timeout = timeout
+	if chain.Timeout <= 0 {
+		chain.Timeout = api.DefaultTimeout
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// createMethod returns whether the secret is created or updated in the vault. A chain picks
+// the method from its primary vault when the secret is created, so it isn't read from the
+// other vaults, and an empty method is returned until then
+func createMethod(ctx context.Context, terraVault vault.TerraVault) (string, error) {
+	if _, ok := terraVault.(*vault.Chain); ok {
+		return "", nil
+	}
+
+	_, err := terraVault.Get(ctx)
+	if errors.IsNotFound(err) {
+		return "Created", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return "Updated", nil
+}
+
+// chainMethod returns whether the chain created or updated the secret
+func chainMethod(terraVault vault.TerraVault) string {
+	return terraVault.(*vault.Chain).Method
+}
+
 // Creates stores or updates a secret in in a vault
-func (platform *Platform) Create(cfg *api.Config, hostname string, token any, user *user.User, vault vault.TerraVault) error {
+func (platform *Platform) Create(ctx context.Context, cfg *api.Config, hostname string, token any, user *user.User, vault vault.TerraVault) error {
 	var method string
 	method = "Updated"
 
@@ -28,63 +80,69 @@ func (platform *Platform) Create(cfg *api.Config, hostname string, token any, us
 		var response api.CredentialResponse
 		err := json.NewDecoder(os.Stdin).Decode(&response)
 		if err != nil {
-			helpers.CheckError(err)
+			return err
 		}
 
 		token = response.Token
 	}
 
 	if vault != nil {
-		_, err := vault.Get()
+		ctx, cancel := vaultContext(ctx, cfg.Timeouts.Create, vault)
+		defer cancel()
+
+		var err error
+		method, err = createMethod(ctx, vault)
 		if err != nil {
-			method = "Created"
+			helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
+			return err
 		}
 
 		secretValue := fmt.Sprintf("%v", token)
-		err = vault.Create(secretValue, method)
+		err = vault.Create(ctx, secretValue, method)
 		if err != nil {
 			helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
 			return err
 		}
 
+		if method == "" {
+			method = chainMethod(vault)
+		}
+
 		fmt.Fprintf(color.Output, "%s: %s the credential object '%s'\n", color.GreenString("SUCCESS"), method, hostname)
 		return err
 	}
 
 	_, err := keyring.Get(hostname, string(user.Username))
-	if err != nil {
+	if err == keyring.ErrNotFound {
 		method = "Created"
+	} else if err != nil {
+		helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
+		return keyringError("You do not have permission to modify this credential", err)
 	}
 
 	str := fmt.Sprintf("%v", token)
 	err = keyring.Set(hostname, string(user.Username), str)
-
-	if err != nil && token != nil {
-		fmt.Fprintf(color.Output, "%s: You do not have permission to modify this credential\n", color.RedString("ERROR"))
-		return err
-	}
-
 	if err != nil {
 		helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
-		return nil
+		return keyringError("You do not have permission to modify this credential", err)
 	}
 
 	msg := fmt.Sprintf("- %s the credential object %s", strings.ToLower(method), hostname)
 	helpers.Logging(cfg, msg, "SUCCESS")
 
-	if token != nil {
-		fmt.Fprintf(color.Output, "%s: %s the credential object '%s'\n", color.GreenString("SUCCESS"), method, hostname)
-		return err
-	}
-
+	fmt.Fprintf(color.Output, "%s: %s the credential object '%s'\n", color.GreenString("SUCCESS"), method, hostname)
 	return err
 }
 
 // Deletes removes or forgets a secret in a vault
-func (platform *Platform) Delete(cfg *api.Config, command string, hostname string, user *user.User, vault vault.TerraVault) error {
+func (platform *Platform) Delete(ctx context.Context, cfg *api.Config, command string, hostname string, user *user.User, vault vault.TerraVault) error {
 	if vault != nil {
-		err := vault.Delete()
+		ctx, cancel := vaultContext(ctx, cfg.Timeouts.Delete, vault)
+		defer cancel()
+
+		err := vault.Delete(ctx)
 		if err != nil {
+			helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
 			return err
 		}
 
@@ -112,20 +170,25 @@ func (platform *Platform) Delete(cfg *api.Config, command string, hostname strin
 	}
 
 	helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
-	if command == "delete" {
+	if command != "delete" {
+		return nil
+	}
+
+	if err == keyring.ErrNotFound {
 		err = &errors.CustomError{
-			Message: "You do not have permission to modify this credential",
+			Message: fmt.Sprintf("The credential object '%s' was not found", hostname),
 			Level:   "ERROR",
+			Err:     errors.ErrNotFound,
 		}
 
 		return err
 	}
 
-	return nil
+	return keyringError("You do not have permission to modify this credential", err)
 }
 
 // Get retrieves a secret from a vault
-func (platform *Platform) Get(cfg *api.Config, hostname string, user *user.User, vault vault.TerraVault) ([]byte, error) {
+func (platform *Platform) Get(ctx context.Context, cfg *api.Config, hostname string, user *user.User, vault vault.TerraVault) ([]byte, error) {
 	if cfg.Logging.Enabled {
 		msg := fmt.Sprintf("- terraform server: %s", hostname)
 		helpers.Logging(cfg, msg, "INFO")
@@ -134,9 +197,13 @@ func (platform *Platform) Get(cfg *api.Config, hostname string, user *user.User,
 	}
 
 	if vault != nil {
-		token, err := vault.Get()
+		ctx, cancel := vaultContext(ctx, cfg.Timeouts.Get, vault)
+		defer cancel()
+
+		token, err := vault.Get(ctx)
 		if err != nil {
-			helpers.CheckError(err)
+			helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
+			return nil, err
 		}
 
 		response := &api.CredentialResponse{
@@ -166,16 +233,15 @@ func (platform *Platform) Get(cfg *api.Config, hostname string, user *user.User,
 		helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
 	}
 
-	err = &errors.CustomError{
-		Message: "You do not have permission to view this credential",
-		Level:   "ERROR",
+	if err == keyring.ErrNotFound {
+		return nil, fmt.Errorf("%w: %s", errors.ErrNotFound, hostname)
 	}
 
-	return nil, err
+	return nil, keyringError("You do not have permission to view this credential", err)
 }
 
 // List returns a list of secrets from a vault in a specified format
-func (platform *Platform) List(c *cli.Context, cfg *api.Config, secretNames []string, user *user.User, vault vault.TerraVault) ([]string, error) {
+func (platform *Platform) List(ctx context.Context, cfg *api.Config, secretNames []string, user *user.User, vault vault.TerraVault) ([]string, error) {
 	var secretValues []string
 	if cfg.Logging.Enabled {
 		msg := fmt.Sprintf("- user requesting access: %s", string(user.Username))
@@ -183,8 +249,12 @@ func (platform *Platform) List(c *cli.Context, cfg *api.Config, secretNames []st
 	}
 
 	if vault != nil {
-		secrets, err := vault.List(secretNames)
+		ctx, cancel := vaultContext(ctx, cfg.Timeouts.List, vault)
+		defer cancel()
+
+		secrets, err := vault.List(ctx, secretNames)
 		if err != nil {
+			helpers.Logging(cfg, fmt.Sprintf("- %s", err), "ERROR")
 			return nil, err
 		}
 
@@ -198,8 +268,12 @@ func (platform *Platform) List(c *cli.Context, cfg *api.Config, secretNames []st
 		}
 
 		cred, err := keyring.Get(secret, string(user.Username))
+		if err == keyring.ErrNotFound {
+			return nil, fmt.Errorf("%w: %s", errors.ErrNotFound, secret)
+		}
+
 		if err != nil {
-			return nil, err
+			return nil, keyringError("You do not have permission to view this credential", err)
 		}
 
 		value := string(cred)
@@ -208,3 +282,26 @@ func (platform *Platform) List(c *cli.Context, cfg *api.Config, secretNames []st
 
 	return secretValues, nil
 }
+
+// keyringError returns the message as an error that's reported with the permission denied exit
+// code, unless the keyring can't be reached, which is reported with the unavailable exit code
+func keyringError(message string, err error) error {
+	if vault.KeyringUnavailable(err) {
+		return &errors.CustomError{
+			Message: "The local operating system's credential vault can't be reached",
+			Level:   "ERROR",
+			Err:     fmt.Errorf("%w: %w", errors.ErrUnavailable, err),
+		}
+	}
+
+	return permissionError(message, err)
+}
+
+// permissionError returns the message as an error that's reported with the permission denied exit code
+func permissionError(message string, err error) error {
+	return &errors.CustomError{
+		Message: message,
+		Level:   "ERROR",
+		Err:     fmt.Errorf("%w: %w", errors.ErrPermissionDenied, err),
+	}
+}
@@ -0,0 +1,20 @@
+package platform
+
+import (
+	"context"
+	"os/user"
+	"path/filepath"
+	"testing"
+
+	"github.com/tonedefdev/terracreds/api"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+func TestPlatformKeyringUnavailable(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+filepath.Join(t.TempDir(), "bus"))
+
+	_, err := (&Platform{}).Get(context.Background(), &api.Config{}, "app.terraform.io", &user.User{Username: "terracreds"}, nil)
+	if code := terraerrors.ExitCode(err); code != terraerrors.ExitUnavailable {
+		t.Errorf("exit code is %d expected %d for a session bus that can't be reached: %v", code, terraerrors.ExitUnavailable, err)
+	}
+}
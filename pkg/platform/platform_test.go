@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/user"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"github.com/tonedefdev/terracreds/api"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+// captureOutput collects what's written to color.Output until the test ends
+func captureOutput(t *testing.T) *bytes.Buffer {
+	var output bytes.Buffer
+	previous := color.Output
+	color.Output = &output
+	t.Cleanup(func() {
+		color.Output = previous
+	})
+
+	return &output
+}
+
+func TestPlatformVaultLifecycle(t *testing.T) {
+	ctx := context.Background()
+	cfg := &api.Config{}
+	user := &user.User{Username: "terracreds"}
+	memory := vaulttest.NewMemory()
+	terraVault := memory.Vault("app.terraform.io")
+	output := captureOutput(t)
+	platform := &Platform{}
+
+	err := platform.Create(ctx, cfg, "app.terraform.io", "first-token", user, terraVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = platform.Create(ctx, cfg, "app.terraform.io", "second-token", user, terraVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SUCCESS: Created the credential object 'app.terraform.io'\nSUCCESS: Updated the credential object 'app.terraform.io'\n"
+	if output.String() != expected {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	token, err := platform.Get(ctx, cfg, "app.terraform.io", user, terraVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(token) != `{"token":"second-token"}` {
+		t.Errorf("Get returned '%s'", token)
+	}
+
+	secrets, err := platform.List(ctx, cfg, []string{"app.terraform.io"}, user, terraVault)
+	if err != nil || strings.Join(secrets, ",") != "second-token" {
+		t.Errorf("List returned %v, %v", secrets, err)
+	}
+
+	output.Reset()
+	err = platform.Delete(ctx, cfg, "delete", "app.terraform.io", user, terraVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.String() != "SUCCESS: The credential object 'app.terraform.io' has been removed\n" {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	if memory.Len() != 0 {
+		t.Errorf("expected the secret to be removed from the vault")
+	}
+}
+
+func TestPlatformVaultErrors(t *testing.T) {
+	ctx := context.Background()
+	cfg := &api.Config{}
+	user := &user.User{Username: "terracreds"}
+	memory := vaulttest.NewMemory()
+	terraVault := memory.Vault("app.terraform.io")
+	captureOutput(t)
+
+	_, err := (&Platform{}).Get(ctx, cfg, "app.terraform.io", user, terraVault)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected a not found error: %v", err)
+	}
+
+	memory.SetError(terraerrors.ErrUnavailable)
+	err = (&Platform{}).Create(ctx, cfg, "app.terraform.io", "token", user, terraVault)
+	if terraerrors.ExitCode(err) != terraerrors.ExitUnavailable {
+		t.Errorf("expected the unavailable exit code: %v", err)
+	}
+}
+
+func TestPlatformChainWithUnavailableVault(t *testing.T) {
+	ctx := context.Background()
+	cfg := &api.Config{}
+	user := &user.User{Username: "terracreds"}
+	down := vaulttest.NewMemory()
+	down.SetError(fmt.Errorf("%w: connection refused", terraerrors.ErrUnavailable))
+	primary := vaulttest.NewMemory()
+	output := captureOutput(t)
+	platform := &Platform{}
+
+	chain := func(secretName string) *vault.Chain {
+		return &vault.Chain{
+			Primary: "keyring",
+			Links: []vault.ChainLink{
+				{Name: "hashicorp", Vault: down.Vault(secretName)},
+				{Name: "keyring", Vault: primary.Vault(secretName)},
+			},
+		}
+	}
+
+	_, err := platform.Get(ctx, cfg, "app.terraform.io", user, chain("app.terraform.io"))
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected a not found error when the primary vault doesn't have the secret: %v", err)
+	}
+
+	err = platform.Create(ctx, cfg, "app.terraform.io", "first-token", user, chain("app.terraform.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = platform.Create(ctx, cfg, "app.terraform.io", "second-token", user, chain("app.terraform.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "SUCCESS: Created the credential object 'app.terraform.io'\nSUCCESS: Updated the credential object 'app.terraform.io'\n"
+	if output.String() != expected {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+
+	token, err := platform.Get(ctx, cfg, "app.terraform.io", user, chain("app.terraform.io"))
+	if err != nil || string(token) != `{"token":"second-token"}` {
+		t.Errorf("Get returned '%s', %v", token, err)
+	}
+}
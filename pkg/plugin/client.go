@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// DefaultHandshakeTimeout is how long terracreds waits for a plugin to answer the handshake
+const DefaultHandshakeTimeout = 10 * time.Second
+
+// Client runs a plugin binary for each operation. The plugin is started, answers the handshake and
+// the operation on stdin and stdout, and exits when its stdin is closed
+type Client struct {
+	// Name is the name of the plugin used in error messages
+	Name string
+
+	// Path is the path to the plugin binary
+	Path string
+
+	// Config is the plugin's block from the terracreds configuration file
+	Config map[string]interface{}
+
+	// HandshakeTimeout is how long to wait for the handshake. If zero DefaultHandshakeTimeout is used
+	HandshakeTimeout time.Duration
+
+	// Stderr receives each line the plugin writes to stderr
+	Stderr func(line string)
+}
+
+// Create stores the secret with the plugin
+func (client *Client) Create(ctx context.Context, secretName string, secretValue string, method string) error {
+	_, err := client.call(ctx, &Request{
+		Operation:   OperationCreate,
+		SecretName:  secretName,
+		SecretValue: secretValue,
+		Method:      method,
+	})
+
+	return err
+}
+
+// Delete removes the secret from the plugin
+func (client *Client) Delete(ctx context.Context, secretName string) error {
+	_, err := client.call(ctx, &Request{
+		Operation:  OperationDelete,
+		SecretName: secretName,
+	})
+
+	return err
+}
+
+// Get returns the value of the secret from the plugin
+func (client *Client) Get(ctx context.Context, secretName string) (string, error) {
+	response, err := client.call(ctx, &Request{
+		Operation:  OperationGet,
+		SecretName: secretName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return response.Secret, nil
+}
+
+// List returns the values of the secrets from the plugin in the order of the secret names
+func (client *Client) List(ctx context.Context, secretNames []string) ([]string, error) {
+	response, err := client.call(ctx, &Request{
+		Operation:   OperationList,
+		SecretNames: secretNames,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Secrets) != len(secretNames) {
+		return nil, fmt.Errorf("the plugin '%s' returned %d secrets for the %d secret names of the %s request", client.Name, len(response.Secrets), len(secretNames), OperationList)
+	}
+
+	return response.Secrets, nil
+}
+
+// Capabilities returns the operations the plugin supports
+func (client *Client) Capabilities(ctx context.Context) ([]string, error) {
+	response, err := client.call(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Capabilities, nil
+}
+
+// call starts the plugin, sends the handshake and then the request if it's not nil
+func (client *Client) call(ctx context.Context, request *Request) (*Response, error) {
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(cmdCtx, client.Path)
+	cmd.WaitDelay = time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("%w: the plugin '%s' can't be started: %w", terraerrors.ErrUnavailable, client.Name, err)
+	}
+
+	response, err := client.exchange(cancel, stdin, stdout, request)
+	stdin.Close()
+
+	waitErr := cmd.Wait()
+	client.logStderr(&stderr)
+
+	if err != nil {
+		var pluginErr *Error
+		switch {
+		case errors.As(err, &pluginErr):
+			return nil, err
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%w: the plugin '%s' didn't answer in time: %w", terraerrors.ErrUnavailable, client.Name, ctx.Err())
+		case waitErr != nil && stderr.Len() > 0:
+			return nil, fmt.Errorf("%w: %s", err, lastLine(&stderr))
+		}
+
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// exchange sends the handshake and the request and returns the response to the request, or to
+// the handshake when the request is nil
+func (client *Client) exchange(cancel context.CancelFunc, stdin io.Writer, stdout io.Reader, request *Request) (*Response, error) {
+	encoder := json.NewEncoder(stdin)
+	decoder := json.NewDecoder(stdout)
+
+	timeout := client.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = DefaultHandshakeTimeout
+	}
+
+	handshake, err := client.send(encoder, decoder, &Request{Operation: OperationHandshake, Config: client.Config}, timeout, cancel)
+	if err != nil {
+		return nil, err
+	}
+
+	if handshake.Version != ProtocolVersion {
+		return nil, fmt.Errorf("the plugin '%s' speaks protocol version %d but terracreds speaks version %d", client.Name, handshake.Version, ProtocolVersion)
+	}
+
+	if request == nil {
+		return handshake, nil
+	}
+
+	if !slices.Contains(handshake.Capabilities, request.Operation) {
+		return nil, fmt.Errorf("the plugin '%s' doesn't support the operation '%s'", client.Name, request.Operation)
+	}
+
+	request.Config = client.Config
+
+	// The context cancels the command, so no other timeout is needed for the operation
+	return client.send(encoder, decoder, request, 0, cancel)
+}
+
+// send writes the request and reads the response. When the timeout is set the plugin is cancelled
+// if it doesn't answer in time
+func (client *Client) send(encoder *json.Encoder, decoder *json.Decoder, request *Request, timeout time.Duration, cancel context.CancelFunc) (*Response, error) {
+	request.Version = ProtocolVersion
+
+	var expired atomic.Bool
+	if timeout > 0 {
+		timer := time.AfterFunc(timeout, func() {
+			expired.Store(true)
+			cancel()
+		})
+		defer timer.Stop()
+	}
+
+	err := encoder.Encode(request)
+	if err != nil {
+		return nil, fmt.Errorf("the plugin '%s' didn't accept the %s request: %w", client.Name, request.Operation, err)
+	}
+
+	var response Response
+	err = decoder.Decode(&response)
+	if err != nil {
+		if expired.Load() {
+			return nil, fmt.Errorf("%w: the plugin '%s' didn't answer the %s request within %s", terraerrors.ErrUnavailable, client.Name, request.Operation, timeout)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("%w: the plugin '%s' exited without answering the %s request", terraerrors.ErrUnavailable, client.Name, request.Operation)
+		}
+
+		return nil, fmt.Errorf("the plugin '%s' returned an invalid response to the %s request: %w", client.Name, request.Operation, err)
+	}
+
+	if response.Error != nil {
+		return nil, response.Error
+	}
+
+	return &response, nil
+}
+
+// logStderr passes each line the plugin wrote to stderr to the Stderr function
+func (client *Client) logStderr(stderr *bytes.Buffer) {
+	if client.Stderr == nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stderr.Bytes()))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			client.Stderr(line)
+		}
+	}
+}
+
+// lastLine returns the last line the plugin wrote to stderr
+func lastLine(stderr *bytes.Buffer) string {
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
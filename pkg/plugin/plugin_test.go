@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+type mapHandler map[string]string
+
+func (h mapHandler) Create(ctx context.Context, request *Request) error {
+	h[request.SecretName] = request.SecretValue
+	return nil
+}
+
+func (h mapHandler) Delete(ctx context.Context, request *Request) error {
+	delete(h, request.SecretName)
+	return nil
+}
+
+func (h mapHandler) Get(ctx context.Context, request *Request) (string, error) {
+	secret, ok := h[request.SecretName]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", terraerrors.ErrNotFound, request.SecretName)
+	}
+
+	return secret, nil
+}
+
+func (h mapHandler) List(ctx context.Context, request *Request) ([]string, error) {
+	return nil, nil
+}
+
+func TestServe(t *testing.T) {
+	server := &Server{
+		Capabilities: []string{OperationGet},
+		Handler:      mapHandler{"api.example.com": "token"},
+	}
+
+	var in bytes.Buffer
+	encoder := json.NewEncoder(&in)
+	requests := []Request{
+		{Version: ProtocolVersion, Operation: OperationHandshake},
+		{Version: ProtocolVersion, Operation: OperationGet, SecretName: "api.example.com"},
+		{Version: ProtocolVersion, Operation: OperationGet, SecretName: "missing"},
+		{Version: ProtocolVersion, Operation: OperationCreate, SecretName: "api.example.com"},
+		{Version: ProtocolVersion + 1, Operation: OperationGet},
+	}
+
+	for _, request := range requests {
+		encoder.Encode(request)
+	}
+
+	var out bytes.Buffer
+	err := server.Serve(context.Background(), &in, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []Response
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var response Response
+		err := decoder.Decode(&response)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		responses = append(responses, response)
+	}
+
+	if len(responses) != len(requests) {
+		t.Fatalf("expected %d responses got %d", len(requests), len(responses))
+	}
+
+	if len(responses[0].Capabilities) != 1 || responses[0].Version != ProtocolVersion {
+		t.Errorf("unexpected handshake response: %+v", responses[0])
+	}
+
+	if responses[1].Secret != "token" {
+		t.Errorf("Get returned '%s' expected 'token'", responses[1].Secret)
+	}
+
+	if responses[2].Error == nil || !errors.Is(responses[2].Error, terraerrors.ErrNotFound) {
+		t.Errorf("expected a not found error: %+v", responses[2].Error)
+	}
+
+	if responses[3].Error == nil || !strings.Contains(responses[3].Error.Message, "not supported") {
+		t.Errorf("expected the unsupported operation to fail: %+v", responses[3].Error)
+	}
+
+	if responses[4].Error == nil || !strings.Contains(responses[4].Error.Message, "protocol version") {
+		t.Errorf("expected the protocol version to be refused: %+v", responses[4].Error)
+	}
+}
+
+// script writes a shell script that acts as a plugin
+func script(t *testing.T, body string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts require a POSIX shell")
+	}
+
+	path := filepath.Join(t.TempDir(), "terracreds-provider-test")
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestClientHandshakeTimeout(t *testing.T) {
+	client := &Client{
+		Name:             "test",
+		Path:             script(t, "sleep 10\n"),
+		HandshakeTimeout: 100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err := client.Get(context.Background(), "api.example.com")
+	if !errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Errorf("expected an unavailable error: %v", err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("expected the plugin to be killed after the handshake timeout")
+	}
+}
+
+func TestClientOperationTimeout(t *testing.T) {
+	client := &Client{
+		Name: "test",
+		Path: script(t, `read line
+echo '{"version":1,"capabilities":["get"]}'
+sleep 10
+`),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Get(ctx, "api.example.com")
+	if !errors.Is(err, terraerrors.ErrUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected an unavailable error from the deadline: %v", err)
+	}
+}
+
+func TestClientStderr(t *testing.T) {
+	var lines []string
+	client := &Client{
+		Name: "test",
+		Path: script(t, `read line
+echo 'starting up' >&2
+echo '{"version":1,"capabilities":["get"]}'
+read line
+echo 'unable to reach the store' >&2
+exit 1
+`),
+		Stderr: func(line string) {
+			lines = append(lines, line)
+		},
+	}
+
+	_, err := client.Get(context.Background(), "api.example.com")
+	if err == nil || !strings.Contains(err.Error(), "unable to reach the store") {
+		t.Errorf("expected the error to include the plugin's stderr: %v", err)
+	}
+
+	if len(lines) != 2 || lines[0] != "starting up" {
+		t.Errorf("expected the stderr lines to be captured: %v", lines)
+	}
+}
+
+func TestClientProtocolVersion(t *testing.T) {
+	client := &Client{
+		Name: "test",
+		Path: script(t, `read line
+echo '{"version":2,"capabilities":["get"]}'
+`),
+	}
+
+	_, err := client.Get(context.Background(), "api.example.com")
+	if err == nil || !strings.Contains(err.Error(), "protocol version 2") {
+		t.Errorf("expected the protocol version to be refused: %v", err)
+	}
+}
+
+func TestClientUnsupportedOperation(t *testing.T) {
+	client := &Client{
+		Name: "test",
+		Path: script(t, `read line
+echo '{"version":1,"capabilities":["get"]}'
+`),
+	}
+
+	err := client.Delete(context.Background(), "api.example.com")
+	if err == nil || !strings.Contains(err.Error(), "doesn't support the operation 'delete'") {
+		t.Errorf("expected the operation to be refused: %v", err)
+	}
+}
+
+func TestClientListShortReply(t *testing.T) {
+	client := &Client{
+		Name: "test",
+		Path: script(t, `read line
+echo '{"version":1,"capabilities":["get","list"]}'
+read line
+echo '{"version":1,"secrets":["token"]}'
+`),
+	}
+
+	_, err := client.List(context.Background(), []string{"api.example.com", "app.terraform.io"})
+	if err == nil || !strings.Contains(err.Error(), "returned 1 secrets for the 2 secret names") {
+		t.Errorf("expected a reply with fewer secrets than names to be refused: %v", err)
+	}
+}
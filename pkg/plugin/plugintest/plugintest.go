@@ -0,0 +1,121 @@
+// Package plugintest is a conformance suite for terracreds provider plugins. A plugin's tests build
+// the plugin binary and pass a client for it to Run
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/plugin"
+)
+
+// Run checks that the plugin answers the handshake and each operation in its capabilities the way
+// terracreds expects. The plugin must be writable if it supports the create operation
+func Run(t *testing.T, client *plugin.Client) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	capabilities, err := client.Capabilities(ctx)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+
+	if !slices.Contains(capabilities, plugin.OperationGet) {
+		t.Fatalf("expected the capabilities %v to include '%s'", capabilities, plugin.OperationGet)
+	}
+
+	secretName := fmt.Sprintf("terracreds-conformance-%d", time.Now().UnixNano())
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		_, err := client.Get(ctx, secretName)
+		if !errors.Is(err, terraerrors.ErrNotFound) {
+			t.Errorf("expected a not found error for a missing secret: %v", err)
+		}
+	})
+
+	if !slices.Contains(capabilities, plugin.OperationCreate) {
+		return
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		err := client.Create(ctx, secretName, "first", "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertSecret(ctx, t, client, secretName, "first")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		err := client.Create(ctx, secretName, "second", "Updated")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertSecret(ctx, t, client, secretName, "second")
+	})
+
+	if slices.Contains(capabilities, plugin.OperationList) {
+		t.Run("List", func(t *testing.T) {
+			secrets, err := client.List(ctx, []string{secretName})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(secrets) != 1 || secrets[0] != "second" {
+				t.Errorf("List returned %v expected [second]", secrets)
+			}
+
+			// A value is returned for each name even when a name is repeated, since terracreds
+			// pairs the values with the names by their position
+			secrets, err = client.List(ctx, []string{secretName, secretName})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(secrets) != 2 || secrets[0] != "second" || secrets[1] != "second" {
+				t.Errorf("List returned %v expected [second second]", secrets)
+			}
+		})
+	}
+
+	if slices.Contains(capabilities, plugin.OperationDelete) {
+		t.Run("Delete", func(t *testing.T) {
+			err := client.Delete(ctx, secretName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.Get(ctx, secretName)
+			if !errors.Is(err, terraerrors.ErrNotFound) {
+				t.Errorf("expected a not found error after the secret was deleted: %v", err)
+			}
+
+			err = client.Delete(ctx, secretName)
+			if !errors.Is(err, terraerrors.ErrNotFound) {
+				t.Errorf("expected a not found error when deleting a missing secret: %v", err)
+			}
+		})
+	}
+}
+
+// assertSecret checks the value of the secret returned by the plugin
+func assertSecret(ctx context.Context, t *testing.T, client *plugin.Client, secretName string, expected string) {
+	t.Helper()
+
+	value, err := client.Get(ctx, secretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != expected {
+		t.Errorf("Get returned '%s' expected '%s'", value, expected)
+	}
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"errors"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// ProtocolVersion is the version of the protocol spoken between terracreds and a plugin. A plugin
+// answers the handshake with the version it speaks and terracreds refuses any other version
+const ProtocolVersion = 1
+
+// Operations that terracreds requests from a plugin. The handshake is always sent first and the
+// other operations are listed in the capabilities the plugin returns from the handshake
+const (
+	OperationHandshake = "handshake"
+	OperationCreate    = "create"
+	OperationDelete    = "delete"
+	OperationGet       = "get"
+	OperationList      = "list"
+)
+
+// Kinds of errors a plugin returns so that terracreds can tell a missing secret apart from
+// other failures
+const (
+	KindNotFound         = "not_found"
+	KindPermissionDenied = "permission_denied"
+	KindUnavailable      = "unavailable"
+	KindConflict         = "conflict"
+)
+
+// Request is a single JSON line that terracreds writes to the plugin's stdin
+type Request struct {
+	// Version is the protocol version spoken by terracreds
+	Version int `json:"version"`
+
+	// Operation is one of the Operation constants
+	Operation string `json:"operation"`
+
+	// Config is the plugin's block from the terracreds configuration file
+	Config map[string]interface{} `json:"config,omitempty"`
+
+	// SecretName is the name of the secret for the create, delete and get operations
+	SecretName string `json:"secretName,omitempty"`
+
+	// SecretNames are the names of the secrets for the list operation
+	SecretNames []string `json:"secretNames,omitempty"`
+
+	// SecretValue is the value of the secret for the create operation
+	SecretValue string `json:"secretValue,omitempty"`
+
+	// Method is 'Created' when the secret doesn't exist yet, otherwise 'Updated'
+	Method string `json:"method,omitempty"`
+}
+
+// Response is a single JSON line that the plugin writes to its stdout for each request
+type Response struct {
+	// Version is the protocol version spoken by the plugin
+	Version int `json:"version"`
+
+	// Capabilities are the operations the plugin supports, returned from the handshake
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Secret is the value of the secret returned from the get operation
+	Secret string `json:"secret,omitempty"`
+
+	// Secrets are the values of the secrets returned from the list operation, in the same
+	// order as the names in the request
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Error is set when the operation failed
+	Error *Error `json:"error,omitempty"`
+}
+
+// Error is an error returned by a plugin
+type Error struct {
+	// Kind is one of the Kind constants, or empty for any other error
+	Kind string `json:"kind,omitempty"`
+
+	// Message describes the error
+	Message string `json:"message"`
+}
+
+// Error returns the message of the error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the terracreds error for the kind of the error
+func (e *Error) Unwrap() error {
+	switch e.Kind {
+	case KindNotFound:
+		return terraerrors.ErrNotFound
+	case KindPermissionDenied:
+		return terraerrors.ErrPermissionDenied
+	case KindUnavailable:
+		return terraerrors.ErrUnavailable
+	case KindConflict:
+		return terraerrors.ErrConflict
+	default:
+		return nil
+	}
+}
+
+// newError converts an error into the Error returned to terracreds
+func newError(err error) *Error {
+	pluginErr := &Error{
+		Message: err.Error(),
+	}
+
+	switch {
+	case errors.Is(err, terraerrors.ErrPermissionDenied):
+		pluginErr.Kind = KindPermissionDenied
+	case errors.Is(err, terraerrors.ErrUnavailable):
+		pluginErr.Kind = KindUnavailable
+	case errors.Is(err, terraerrors.ErrConflict):
+		pluginErr.Kind = KindConflict
+	case errors.Is(err, terraerrors.ErrNotFound):
+		pluginErr.Kind = KindNotFound
+	}
+
+	return pluginErr
+}
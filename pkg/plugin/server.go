@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+)
+
+// Handler implements the operations of a plugin. Errors that wrap ErrNotFound, ErrPermissionDenied,
+// ErrUnavailable or ErrConflict from the terracreds errors package are returned to terracreds with
+// their kind
+type Handler interface {
+	Create(ctx context.Context, request *Request) error
+	Delete(ctx context.Context, request *Request) error
+	Get(ctx context.Context, request *Request) (string, error)
+	List(ctx context.Context, request *Request) ([]string, error)
+}
+
+// Server answers the requests that terracreds sends to a plugin
+type Server struct {
+	// Capabilities are the operations the plugin supports. If omitted every operation is supported
+	Capabilities []string
+
+	// Handler implements the operations
+	Handler Handler
+}
+
+// Serve runs the server on stdin and stdout and exits the process when terracreds closes stdin.
+// Diagnostics written to stderr are copied into the terracreds log
+func Serve(server *Server) {
+	err := server.Serve(context.Background(), os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+// Serve reads requests from in and writes a response for each one to out until in is closed
+func (server *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	decoder := json.NewDecoder(in)
+	encoder := json.NewEncoder(out)
+
+	for {
+		var request Request
+		err := decoder.Decode(&request)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+
+		response := server.handle(ctx, &request)
+		response.Version = ProtocolVersion
+
+		err = encoder.Encode(response)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// capabilities returns the operations the plugin supports
+func (server *Server) capabilities() []string {
+	if len(server.Capabilities) > 0 {
+		return server.Capabilities
+	}
+
+	return []string{OperationCreate, OperationDelete, OperationGet, OperationList}
+}
+
+// handle runs the requested operation
+func (server *Server) handle(ctx context.Context, request *Request) *Response {
+	response := &Response{}
+
+	if request.Version != ProtocolVersion {
+		response.Error = &Error{
+			Message: fmt.Sprintf("protocol version %d is not supported, the plugin speaks version %d", request.Version, ProtocolVersion),
+		}
+
+		return response
+	}
+
+	if request.Operation == OperationHandshake {
+		response.Capabilities = server.capabilities()
+		return response
+	}
+
+	if !slices.Contains(server.capabilities(), request.Operation) {
+		response.Error = &Error{
+			Message: fmt.Sprintf("the operation '%s' is not supported by the plugin", request.Operation),
+		}
+
+		return response
+	}
+
+	var err error
+	switch request.Operation {
+	case OperationCreate:
+		err = server.Handler.Create(ctx, request)
+	case OperationDelete:
+		err = server.Handler.Delete(ctx, request)
+	case OperationGet:
+		response.Secret, err = server.Handler.Get(ctx, request)
+	case OperationList:
+		response.Secrets, err = server.Handler.List(ctx, request)
+	default:
+		err = fmt.Errorf("the operation '%s' is not supported by the plugin", request.Operation)
+	}
+
+	if err != nil {
+		response = &Response{
+			Error: newError(err),
+		}
+	}
+
+	return response
+}
@@ -1,30 +1,127 @@
 package vault
 
 import (
+	"context"
+	"errors"
+	"strings"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/aws/smithy-go"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
 )
 
 type AwsSecretsManager struct {
 	Description string
+	Endpoint    string
 	Region      string
 	SecretName  string
 }
 
-func (asm *AwsSecretsManager) getAwsSecetsManager() *secretsmanager.Client {
-	cfg, err := config.LoadDefaultConfig(ctx)
+func init() {
+	Register(&Provider{
+		Name:  "aws",
+		Key:   "aws",
+		Title: "AWS Secrets Manager",
+		Usage: "AWS Secrets Manager provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "description",
+				Key:   "description",
+				Usage: "A description to provide to the secret",
+			},
+			{
+				Name:  "endpoint",
+				Key:   "endpoint",
+				Usage: "The URL of the Secrets Manager endpoint such as a VPC endpoint or a local emulator. If omitted the endpoint of the region is used",
+			},
+			{
+				Name:     "region",
+				Key:      "region",
+				Usage:    "The region where AWS Secrets Manager is hosted",
+				Required: true,
+			},
+			{
+				Name:  "secret-name",
+				Key:   "secretName",
+				Usage: "The friendly name of the secret stored in AWS Secrets Manager. If omitted Terracreds will use the hostname value instead",
+			},
+		},
+		SecretNameKey: "secretName",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &AwsSecretsManager{
+				Description: config.String("description"),
+				Endpoint:    config.String("endpoint"),
+				Region:      config.String("region"),
+				SecretName:  secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+func (asm *AwsSecretsManager) getAwsSecetsManager(ctx context.Context) (*secretsmanager.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(asm.Region))
 	if err != nil {
-		helpers.CheckError(err)
+		return nil, err
+	}
+
+	svc := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		if asm.Endpoint != "" {
+			o.BaseEndpoint = aws.String(asm.Endpoint)
+		}
+	})
+
+	return svc, nil
+}
+
+// scheduledForDeletion returns whether the error is returned for a secret that's scheduled for
+// deletion, which Secrets Manager keeps until its recovery window ends
+func scheduledForDeletion(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidRequestException" &&
+		strings.Contains(apiErr.ErrorMessage(), "for deletion")
+}
+
+// awsError converts a Secrets Manager or Systems Manager error into the kind of failure it represents.
+// A secret that's scheduled for deletion is not found
+func awsError(err error, secretName string) error {
+	if scheduledForDeletion(err) {
+		return wrapError(terraerrors.ErrNotFound, err, secretName)
 	}
 
-	svc := secretsmanager.NewFromConfig(cfg)
-	return svc
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "ResourceNotFoundException", "ParameterNotFound", "ParameterVersionNotFound":
+			return wrapError(terraerrors.ErrNotFound, err, secretName)
+		case "AccessDeniedException", "UnrecognizedClientException", "InvalidSignatureException",
+			"ExpiredTokenException", "DecryptionFailure", "InvalidKeyId":
+			return wrapError(terraerrors.ErrPermissionDenied, err, secretName)
+		case "ResourceExistsException", "ParameterAlreadyExists":
+			return wrapError(terraerrors.ErrConflict, err, secretName)
+		case "ThrottlingException", "InternalServiceError", "ServiceUnavailable", "InternalServerError",
+			"TooManyUpdates":
+			return wrapError(terraerrors.ErrUnavailable, err, secretName)
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return statusError(respErr.HTTPStatusCode(), err, secretName)
+	}
+
+	return transportError(err, secretName)
 }
 
-func (asm *AwsSecretsManager) Create(secretValue string, method string) error {
-	svc := asm.getAwsSecetsManager()
+func (asm *AwsSecretsManager) Create(ctx context.Context, secretValue string, method string) error {
+	svc, err := asm.getAwsSecetsManager(ctx)
+	if err != nil {
+		return err
+	}
 
 	if method == "Updated" {
 		input := &secretsmanager.PutSecretValueInput{
@@ -34,7 +131,7 @@ func (asm *AwsSecretsManager) Create(secretValue string, method string) error {
 
 		_, err := svc.PutSecretValue(ctx, input)
 		if err != nil {
-			return err
+			return awsError(err, asm.SecretName)
 		}
 
 		return err
@@ -46,32 +143,65 @@ func (asm *AwsSecretsManager) Create(secretValue string, method string) error {
 		SecretString: aws.String(secretValue),
 	}
 
-	_, err := svc.CreateSecret(ctx, input)
+	_, err = svc.CreateSecret(ctx, input)
+	if scheduledForDeletion(err) {
+		return asm.restore(ctx, svc, secretValue)
+	}
+
 	if err != nil {
-		return err
+		return awsError(err, asm.SecretName)
 	}
 
 	return err
 }
 
-func (asm *AwsSecretsManager) Delete() error {
-	svc := asm.getAwsSecetsManager()
+// restore cancels the deletion of a secret that was deleted within its recovery window and
+// stores the new value, since its name can't be used by a new secret until then
+func (asm *AwsSecretsManager) restore(ctx context.Context, svc *secretsmanager.Client, secretValue string) error {
+	_, err := svc.RestoreSecret(ctx, &secretsmanager.RestoreSecretInput{
+		SecretId: aws.String(asm.SecretName),
+	})
+
+	if err != nil {
+		return awsError(err, asm.SecretName)
+	}
+
+	_, err = svc.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(asm.SecretName),
+		SecretString: aws.String(secretValue),
+	})
+
+	if err != nil {
+		return awsError(err, asm.SecretName)
+	}
+
+	return err
+}
+
+func (asm *AwsSecretsManager) Delete(ctx context.Context) error {
+	svc, err := asm.getAwsSecetsManager(ctx)
+	if err != nil {
+		return err
+	}
 
 	input := &secretsmanager.DeleteSecretInput{
 		RecoveryWindowInDays: aws.Int64(7),
 		SecretId:             aws.String(asm.SecretName),
 	}
 
-	_, err := svc.DeleteSecret(ctx, input)
+	_, err = svc.DeleteSecret(ctx, input)
 	if err != nil {
-		return err
+		return awsError(err, asm.SecretName)
 	}
 
 	return err
 }
 
-func (asm *AwsSecretsManager) Get() ([]byte, error) {
-	svc := asm.getAwsSecetsManager()
+func (asm *AwsSecretsManager) Get(ctx context.Context) ([]byte, error) {
+	svc, err := asm.getAwsSecetsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	input := &secretsmanager.GetSecretValueInput{
 		SecretId: aws.String(asm.SecretName),
@@ -79,15 +209,18 @@ func (asm *AwsSecretsManager) Get() ([]byte, error) {
 
 	result, err := svc.GetSecretValue(ctx, input)
 	if err != nil {
-		return nil, err
+		return nil, awsError(err, asm.SecretName)
 	}
 
 	return []byte(*result.SecretString), err
 }
 
-func (asm *AwsSecretsManager) List(secretNames []string) ([]string, error) {
+func (asm *AwsSecretsManager) List(ctx context.Context, secretNames []string) ([]string, error) {
 	var secretValues []string
-	svc := asm.getAwsSecetsManager()
+	svc, err := asm.getAwsSecetsManager(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, secret := range secretNames {
 		input := &secretsmanager.GetSecretValueInput{
@@ -96,7 +229,7 @@ func (asm *AwsSecretsManager) List(secretNames []string) ([]string, error) {
 
 		result, err := svc.GetSecretValue(ctx, input)
 		if err != nil {
-			return nil, err
+			return nil, awsError(err, secret)
 		}
 
 		secretValues = append(secretValues, *result.SecretString)
@@ -0,0 +1,53 @@
+package vault_test
+
+import (
+	"context"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+func TestAwsScheduledForDeletion(t *testing.T) {
+	ctx := context.Background()
+	setAwsEnv(t)
+
+	fake := vaulttest.NewSecretsManager(t)
+	asm := newVault(t, "aws", vault.ProviderConfig{"endpoint": fake.URL, "region": "us-east-1"}, "app.terraform.io")
+
+	err := asm.Create(ctx, "first-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// forget schedules the secret for deletion within its recovery window
+	err = asm.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !fake.Deleted("app.terraform.io") {
+		t.Fatal("expected the secret to be scheduled for deletion")
+	}
+
+	_, err = asm.Get(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound for a secret that's scheduled for deletion got %v", err)
+	}
+
+	err = asm.Delete(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound when the secret is deleted again got %v", err)
+	}
+
+	err = asm.Create(ctx, "second-token", "Created")
+	if err != nil {
+		t.Fatalf("expected a secret that's scheduled for deletion to be restored got %v", err)
+	}
+
+	secret, err := asm.Get(ctx)
+	if err != nil || string(secret) != "second-token" {
+		t.Errorf("expected the restored secret to hold the new value got '%s', %v", secret, err)
+	}
+}
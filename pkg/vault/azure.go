@@ -2,24 +2,73 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
 )
 
 type AzureKeyVault struct {
+	// Credential authenticates with Key Vault. If nil the default Azure credential is used
+	Credential     azcore.TokenCredential
 	SecretName     string
 	SubscriptionId string
 	VaultUri       string
 }
 
-// getDefaultAzureClient returns a pointer to an azsecrets.Client using the default
-// authorization scheme for azidentity
+func init() {
+	Register(&Provider{
+		Name:  "azure",
+		Key:   "azure",
+		Title: "Azure Key Vault",
+		Usage: "Azure Key Vault provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "secret-name",
+				Key:   "secretName",
+				Usage: "The name of the secret stored in Azure Key Vault. If omitted Terracreds will use the hostname value instead",
+			},
+			{
+				Name:     "subscription-id",
+				Aliases:  []string{"id"},
+				Key:      "subscriptionId",
+				Usage:    "The subscription ID where the Key Vault instance has been created",
+				Required: true,
+			},
+			{
+				Name:     "vault-uri",
+				Key:      "vaultUri",
+				Usage:    "The FQDN of the Azure Key Vault resource",
+				Required: true,
+			},
+		},
+		SecretNameKey: "secretName",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &AzureKeyVault{
+				SecretName:     secretName,
+				SubscriptionId: config.String("subscriptionId"),
+				VaultUri:       config.String("vaultUri"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// getDefaultAzureClient returns a pointer to an azsecrets.Client using the vault's credential
+// or the default authorization scheme for azidentity
 func getDefaultAzureClient(akv *AzureKeyVault) (*azsecrets.Client, error) {
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
-	if err != nil {
-		return nil, err
+	var cred azcore.TokenCredential = akv.Credential
+	if cred == nil {
+		defaultCred, err := azidentity.NewDefaultAzureCredential(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		cred = defaultCred
 	}
 
 	vaultClient, err := azsecrets.NewClient(akv.VaultUri, cred, nil)
@@ -37,9 +86,23 @@ func formatSecretName(secretName string) string {
 	return hostname
 }
 
+// azureError converts a Key Vault error into the kind of failure it represents
+func azureError(err error, secretName string) error {
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return wrapError(terraerrors.ErrPermissionDenied, err, secretName)
+	}
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return statusError(respErr.StatusCode, err, secretName)
+	}
+
+	return transportError(err, secretName)
+}
+
 // Create stores a secret in an Azure Key Vault
-func (akv *AzureKeyVault) Create(secretValue string, method string) error {
-	ctx := context.Background()
+func (akv *AzureKeyVault) Create(ctx context.Context, secretValue string, method string) error {
 	client, err := getDefaultAzureClient(akv)
 	if err != nil {
 		return err
@@ -52,12 +115,15 @@ func (akv *AzureKeyVault) Create(secretValue string, method string) error {
 
 	secret := formatSecretName(akv.SecretName)
 	_, err = client.SetSecret(ctx, secret, secretValue, &options)
+	if err != nil {
+		return azureError(err, secret)
+	}
+
 	return err
 }
 
 // Delete removes a secret stored in an Azure Key Vault
-func (akv *AzureKeyVault) Delete() error {
-	ctx := context.Background()
+func (akv *AzureKeyVault) Delete(ctx context.Context) error {
 	client, err := getDefaultAzureClient(akv)
 	if err != nil {
 		return err
@@ -67,12 +133,15 @@ func (akv *AzureKeyVault) Delete() error {
 	secret := formatSecretName(akv.SecretName)
 
 	_, err = client.BeginDeleteSecret(ctx, secret, &options)
+	if err != nil {
+		return azureError(err, secret)
+	}
+
 	return err
 }
 
 // Get retrieves a secrete stored in an Azure Key Vault
-func (akv *AzureKeyVault) Get() ([]byte, error) {
-	ctx := context.Background()
+func (akv *AzureKeyVault) Get(ctx context.Context) ([]byte, error) {
 	client, err := getDefaultAzureClient(akv)
 	if err != nil {
 		return nil, err
@@ -83,14 +152,13 @@ func (akv *AzureKeyVault) Get() ([]byte, error) {
 
 	get, err := client.GetSecret(ctx, secret, &options)
 	if err != nil {
-		return nil, err
+		return nil, azureError(err, secret)
 	}
 	return []byte(*get.Value), err
 }
 
-func (akv *AzureKeyVault) List(secretNames []string) ([]string, error) {
+func (akv *AzureKeyVault) List(ctx context.Context, secretNames []string) ([]string, error) {
 	var secretValues []string
-	ctx := context.Background()
 	client, err := getDefaultAzureClient(akv)
 	if err != nil {
 		return nil, err
@@ -102,7 +170,7 @@ func (akv *AzureKeyVault) List(secretNames []string) ([]string, error) {
 
 		get, err := client.GetSecret(ctx, secret, &options)
 		if err != nil {
-			return nil, err
+			return nil, azureError(err, secret)
 		}
 
 		secretValues = append(secretValues, *get.Value)
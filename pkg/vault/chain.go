@@ -0,0 +1,210 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// ChainLink is a named vault that's part of a Chain
+type ChainLink struct {
+	Name  string
+	Vault TerraVault
+}
+
+// Chain is an ordered list of vaults. Reads return the first vault that has the
+// secret and writes go to the primary vault
+type Chain struct {
+	// Answered is the name of the vault that answered the last call
+	Answered string
+
+	// Links are the vaults in the order they are read from
+	Links []ChainLink
+
+	// Method is whether the last call to Create created or updated the secret
+	Method string
+
+	// Primary is the name of the vault that secrets are written to
+	Primary string
+
+	// Timeout is the maximum time each vault may take for a call, so that a vault that's
+	// slow doesn't use up the time of the others. No timeout is set when it's zero
+	Timeout time.Duration
+}
+
+// linkContext returns the context of a call to a single vault in the chain
+func (chain *Chain) linkContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if chain.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, chain.Timeout)
+}
+
+// skip logs the errors of the vaults that couldn't be reached as skipped when a vault that
+// could be reached doesn't have the secret, so that the secret is reported as not found.
+// Otherwise the errors are returned
+func skip(reached bool, errs []error) error {
+	for _, err := range errs {
+		if !reached || !errors.Is(err, terraerrors.ErrUnavailable) {
+			return errors.Join(errs...)
+		}
+	}
+
+	for _, err := range errs {
+		Log(fmt.Sprintf("- the vault was skipped: %s", err), "WARNING")
+	}
+
+	return nil
+}
+
+// primary returns the link that secrets are written to
+func (chain *Chain) primary() (*ChainLink, error) {
+	for i := range chain.Links {
+		if chain.Links[i].Name == chain.Primary {
+			return &chain.Links[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("the primary vault '%s' is not part of the chain", chain.Primary)
+}
+
+// Create stores a secret in the primary vault
+func (chain *Chain) Create(ctx context.Context, secretValue string, method string) error {
+	primary, err := chain.primary()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := chain.linkContext(ctx)
+	defer cancel()
+
+	// the secret may have been found in another vault, so whether it's
+	// created or updated depends on the primary vault alone
+	method = "Updated"
+	_, err = primary.Vault.Get(ctx)
+	if terraerrors.IsNotFound(err) {
+		method = "Created"
+	} else if err != nil {
+		return err
+	}
+
+	chain.Answered = primary.Name
+	chain.Method = method
+	return primary.Vault.Create(ctx, secretValue, method)
+}
+
+// Delete removes a secret from the primary vault
+func (chain *Chain) Delete(ctx context.Context) error {
+	primary, err := chain.primary()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := chain.linkContext(ctx)
+	defer cancel()
+
+	chain.Answered = primary.Name
+	return primary.Vault.Delete(ctx)
+}
+
+// get retrieves a secret from a single vault in the chain
+func (chain *Chain) get(ctx context.Context, link ChainLink) ([]byte, error) {
+	ctx, cancel := chain.linkContext(ctx)
+	defer cancel()
+
+	return link.Vault.Get(ctx)
+}
+
+// list retrieves a single secret from a single vault in the chain
+func (chain *Chain) list(ctx context.Context, link ChainLink, secretName string) (string, error) {
+	ctx, cancel := chain.linkContext(ctx)
+	defer cancel()
+
+	values, err := link.Vault.List(ctx, []string{secretName})
+	if err != nil {
+		return "", err
+	}
+
+	if len(values) != 1 {
+		return "", fmt.Errorf("the vault '%s' returned %d secrets for the secret name '%s'", link.Name, len(values), secretName)
+	}
+
+	return values[0], nil
+}
+
+// Get retrieves a secret from the first vault that has it. Vaults that can't be
+// reached are skipped, and ErrNotFound is returned when none of the others has the secret
+func (chain *Chain) Get(ctx context.Context) ([]byte, error) {
+	var errs []error
+	reached := false
+
+	for _, link := range chain.Links {
+		secret, err := chain.get(ctx, link)
+		if err == nil {
+			chain.Answered = link.Name
+			return secret, nil
+		}
+
+		if terraerrors.IsNotFound(err) {
+			reached = true
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", link.Name, err))
+	}
+
+	err := skip(reached, errs)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, terraerrors.ErrNotFound
+}
+
+// List retrieves each secret from the first vault that has it
+func (chain *Chain) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	var answered []string
+
+	for _, secret := range secretNames {
+		var errs []error
+		found := false
+		reached := false
+
+		for _, link := range chain.Links {
+			value, err := chain.list(ctx, link, secret)
+			if err == nil {
+				secretValues = append(secretValues, value)
+				answered = append(answered, link.Name)
+				found = true
+				break
+			}
+
+			if terraerrors.IsNotFound(err) {
+				reached = true
+				continue
+			}
+
+			errs = append(errs, fmt.Errorf("%s: %w", link.Name, err))
+		}
+
+		if found {
+			continue
+		}
+
+		err := skip(reached, errs)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secret)
+	}
+
+	chain.Answered = strings.Join(answered, ",")
+	return secretValues, nil
+}
@@ -0,0 +1,188 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// unavailableVault is a TerraVault that can't be reached
+type unavailableVault struct{}
+
+func (uv *unavailableVault) Create(ctx context.Context, secretValue string, method string) error {
+	return fmt.Errorf("%w: connection refused", terraerrors.ErrUnavailable)
+}
+
+func (uv *unavailableVault) Delete(ctx context.Context) error {
+	return fmt.Errorf("%w: connection refused", terraerrors.ErrUnavailable)
+}
+
+func (uv *unavailableVault) Get(ctx context.Context) ([]byte, error) {
+	return nil, fmt.Errorf("%w: connection refused", terraerrors.ErrUnavailable)
+}
+
+func (uv *unavailableVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	return nil, fmt.Errorf("%w: connection refused", terraerrors.ErrUnavailable)
+}
+
+// slowVault is a TerraVault that doesn't answer until its context is done
+type slowVault struct {
+	unavailableVault
+}
+
+func (sv *slowVault) Get(ctx context.Context) ([]byte, error) {
+	<-ctx.Done()
+	return nil, fmt.Errorf("%w: %w", terraerrors.ErrUnavailable, ctx.Err())
+}
+
+// emptyVault is a TerraVault that lists no secrets without an error
+type emptyVault struct {
+	unavailableVault
+}
+
+func (ev *emptyVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	return nil, nil
+}
+
+func newTestChain(secretName string) *Chain {
+	keyring.MockInit()
+
+	return &Chain{
+		Primary: "keyring",
+		Links: []ChainLink{
+			{Name: "hashicorp", Vault: &unavailableVault{}},
+			{Name: "keyring", Vault: &Keyring{SecretName: secretName, User: "test"}},
+		},
+	}
+}
+
+func TestChainGetSkipsUnavailableVault(t *testing.T) {
+	chain := newTestChain("chain.example")
+
+	err := chain.Create(context.Background(), "password", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := chain.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret) != "password" {
+		t.Errorf("secret is '%s' expected 'password'", secret)
+	}
+
+	if chain.Answered != "keyring" {
+		t.Errorf("Answered is '%s' expected 'keyring'", chain.Answered)
+	}
+
+	err = chain.Delete(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainGetReturnsUnavailableError(t *testing.T) {
+	chain := &Chain{
+		Primary: "hashicorp",
+		Links: []ChainLink{
+			{Name: "hashicorp", Vault: &unavailableVault{}},
+			{Name: "aws", Vault: &unavailableVault{}},
+		},
+	}
+
+	_, err := chain.Get(context.Background())
+	if err == nil {
+		t.Fatal("expected an error when no vault is available")
+	}
+
+	if terraerrors.IsNotFound(err) {
+		t.Errorf("expected the unavailable error instead of ErrNotFound: %s", err)
+	}
+
+	if code := terraerrors.ExitCode(err); code != terraerrors.ExitUnavailable {
+		t.Errorf("exit code is %d expected %d", code, terraerrors.ExitUnavailable)
+	}
+}
+
+func TestChainGetSkipsUnavailableVaultWhenNotFound(t *testing.T) {
+	chain := newTestChain("missing.example")
+
+	var logged []string
+	Log = func(msg string, level string) {
+		logged = append(logged, level+" "+msg)
+	}
+	t.Cleanup(func() {
+		Log = func(msg string, level string) {}
+	})
+
+	_, err := chain.Get(context.Background())
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound when the available vault doesn't have the secret got: %v", err)
+	}
+
+	_, err = chain.List(context.Background(), []string{"missing.example"})
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound from List got: %v", err)
+	}
+
+	if len(logged) != 2 || !strings.HasPrefix(logged[0], "WARNING") || !strings.Contains(logged[0], "hashicorp") {
+		t.Errorf("expected the unavailable vault to be logged as skipped got %q", logged)
+	}
+}
+
+func TestChainTimeoutPerVault(t *testing.T) {
+	chain := newTestChain("slow.example")
+	chain.Links[0].Vault = &slowVault{}
+	chain.Timeout = 50 * time.Millisecond
+
+	err := chain.Create(context.Background(), "password", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	secret, err := chain.Get(ctx)
+	if err != nil || string(secret) != "password" {
+		t.Errorf("expected the secret from the vault after the slow one got '%s', %v", secret, err)
+	}
+
+	err = chain.Delete(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChainGetNotFound(t *testing.T) {
+	keyring.MockInit()
+	chain := &Chain{
+		Primary: "keyring",
+		Links: []ChainLink{
+			{Name: "keyring", Vault: &Keyring{SecretName: "missing.example", User: "test"}},
+		},
+	}
+
+	_, err := chain.Get(context.Background())
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound got: %v", err)
+	}
+}
+
+func TestChainListWrongNumberOfSecrets(t *testing.T) {
+	chain := newTestChain("empty.example")
+	chain.Links[0].Vault = &emptyVault{}
+
+	_, err := chain.List(context.Background(), []string{"empty.example"})
+	if err == nil || !strings.Contains(err.Error(), "returned 0 secrets") {
+		t.Errorf("expected a vault that lists no secrets to fail got: %v", err)
+	}
+}
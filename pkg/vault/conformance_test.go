@@ -0,0 +1,295 @@
+package vault_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+// newVault creates the registered provider's TerraVault from its configuration block
+func newVault(t *testing.T, name string, config vault.ProviderConfig, secretName string) vault.TerraVault {
+	t.Helper()
+
+	provider, ok := vault.Lookup(name)
+	if !ok {
+		t.Fatalf("the '%s' provider is not registered", name)
+	}
+
+	terraVault, err := provider.New(config, secretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return terraVault
+}
+
+// setAwsEnv gives the AWS SDK static credentials and keeps it from reading the user's
+// configuration or the instance metadata
+func setAwsEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "vaulttest")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "vaulttest")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestAwsConformance(t *testing.T) {
+	setAwsEnv(t)
+
+	fake := vaulttest.NewSecretsManager(t)
+	config := vault.ProviderConfig{
+		"endpoint": fake.URL,
+		"region":   "us-east-1",
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "aws", config, secretName)
+	})
+}
+
+func TestAzureConformance(t *testing.T) {
+	fake := vaulttest.NewKeyVault(t)
+
+	factory := func(t *testing.T, secretName string) vault.TerraVault {
+		return &vault.AzureKeyVault{
+			Credential: &vaulttest.Credential{},
+			SecretName: secretName,
+			VaultUri:   fake.URL,
+		}
+	}
+
+	vaulttest.Run(t, factory)
+
+	err := factory(t, "app.terraform.io").Create(context.Background(), "token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Secret("app-terraform-io"); !ok {
+		t.Errorf("expected the periods in the secret name to be replaced with dashes")
+	}
+}
+
+func TestHashiCorpConformance(t *testing.T) {
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "tfe",
+		"vaultUri":             fake.URL,
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "hashicorp", config, secretName)
+	})
+}
+
+func TestHashiCorpKVv1Conformance(t *testing.T) {
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.Mount("legacy", 1)
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "legacy",
+		"secretPath":           "tfe",
+		"vaultUri":             fake.URL,
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "hashicorp", config, secretName)
+	})
+}
+
+func TestKeyringConformance(t *testing.T) {
+	keyring.MockInit()
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "keyring", nil, secretName)
+	})
+}
+
+func TestFileConformance(t *testing.T) {
+	t.Setenv("TC_TEST_FILE_PASSPHRASE", "correct horse battery staple")
+
+	config := vault.ProviderConfig{
+		"passphraseEnv": "TC_TEST_FILE_PASSPHRASE",
+		"path":          filepath.Join(t.TempDir(), "vault.enc"),
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "file", config, secretName)
+	})
+}
+
+func TestPassConformance(t *testing.T) {
+	fingerprint := vaulttest.NewGPGKey(t)
+
+	store := t.TempDir()
+	err := os.WriteFile(filepath.Join(store, ".gpg-id"), []byte(fingerprint+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := vault.ProviderConfig{
+		"prefix":   "terraform",
+		"storeDir": store,
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "pass", config, secretName)
+	})
+}
+
+func TestKeePassConformance(t *testing.T) {
+	t.Setenv("TC_TEST_KEEPASS_PASSWORD", "correct horse battery staple")
+
+	config := vault.ProviderConfig{
+		"group":       "Terraform",
+		"passwordEnv": "TC_TEST_KEEPASS_PASSWORD",
+		"path":        filepath.Join(t.TempDir(), "team.kdbx"),
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "keepass", config, secretName)
+	})
+}
+
+func TestKernelKeyringConformance(t *testing.T) {
+	config := vault.ProviderConfig{
+		"prefix": fmt.Sprintf("terracreds-conformance-%d:", os.Getpid()),
+	}
+
+	probe := newVault(t, "kernel-keyring", config, "probe")
+	_, err := probe.Get(context.Background())
+	if errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Skip(err)
+	}
+
+	// Keys outlive the test process, so the ones the suite leaves behind are removed
+	t.Cleanup(func() {
+		names, _ := probe.(vault.Enumerator).Names(context.Background())
+		for _, name := range names {
+			newVault(t, "kernel-keyring", config, name).Delete(context.Background())
+		}
+	})
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "kernel-keyring", config, secretName)
+	})
+}
+
+func TestSopsConformance(t *testing.T) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		t.Skip("sops is not installed")
+	}
+
+	fingerprint := vaulttest.NewGPGKey(t)
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	err := os.WriteFile(path, []byte("terraform: {}\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := exec.Command("sops", "encrypt", "--pgp", fingerprint, "--in-place", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sops: %v: %s", err, output)
+	}
+
+	config := vault.ProviderConfig{
+		"path":   path,
+		"prefix": "terraform",
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "sops", config, secretName)
+	})
+}
+
+func TestSsmConformance(t *testing.T) {
+	setAwsEnv(t)
+
+	fake := vaulttest.NewParameterStore(t)
+	config := vault.ProviderConfig{
+		"endpoint": fake.URL,
+		"path":     "/terraform",
+		"region":   "us-east-1",
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "ssm", config, secretName)
+	})
+}
+
+func TestConsulConformance(t *testing.T) {
+	t.Setenv("CONSUL_HTTP_TOKEN_FILE", "")
+	t.Setenv("TC_CONSUL_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewConsulKV(t, "vaulttest")
+	config := vault.ProviderConfig{
+		"address":  fake.URL,
+		"prefix":   "terraform/tokens",
+		"tokenEnv": "TC_CONSUL_TOKEN",
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "consul", config, secretName)
+	})
+}
+
+func TestKubernetesConformance(t *testing.T) {
+	fake := vaulttest.NewKubernetesAPI(t, "vaulttest")
+	kubeconfig := fake.Kubeconfig(t, "terraform")
+
+	for name, config := range map[string]vault.ProviderConfig{
+		"SecretPerName": {"kubeconfig": kubeconfig, "prefix": "terracreds-"},
+		"SingleSecret":  {"kubeconfig": kubeconfig, "secret": "terracreds"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+				return newVault(t, "kubernetes", config, secretName)
+			})
+		})
+	}
+}
+
+func TestConjurConformance(t *testing.T) {
+	t.Setenv("TC_CONJUR_API_KEY", "vaulttest")
+
+	fake := vaulttest.NewConjur(t, "myorg")
+	fake.APIKeys["host/terraform/runner"] = "vaulttest"
+	config := vault.ProviderConfig{
+		"account":      "myorg",
+		"apiKeyEnv":    "TC_CONJUR_API_KEY",
+		"login":        "host/terraform/runner",
+		"policyBranch": "terraform/tokens",
+		"url":          fake.URL,
+	}
+
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "conjur", config, secretName)
+	})
+}
+
+func TestHTTPConformance(t *testing.T) {
+	t.Setenv("TC_HTTP_TOKEN", "vaulttest")
+	t.Setenv("TC_TEAM", "platform")
+
+	config := secretAPIConfig(newSecretAPI(t, "vaulttest").URL)
+	vaulttest.Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return newVault(t, "http", config, secretName)
+	})
+}
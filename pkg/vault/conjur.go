@@ -0,0 +1,500 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+const (
+	// DefaultConjurAPIKeyEnv is the environment variable that holds the API key of the login
+	DefaultConjurAPIKeyEnv = "CONJUR_AUTHN_API_KEY"
+
+	// DefaultConjurJWTEnv is the environment variable that holds the JWT for authn-jwt
+	DefaultConjurJWTEnv = "CONJUR_AUTHN_JWT_TOKEN"
+
+	// conjurTokenLifetime is how long an access token is reused. Conjur's access tokens expire
+	// after 8 minutes, so a token is renewed before a long invocation outlives it
+	conjurTokenLifetime = 6 * time.Minute
+
+	// conjurPageSize is the most variables that a single request for resources returns
+	conjurPageSize = 1000
+)
+
+// ConjurVault stores secrets as variables below a policy branch of CyberArk Conjur. Variables
+// that don't exist are declared in the branch by loading a policy, and are removed by the same
+type ConjurVault struct {
+	Account       string
+	APIKeyEnv     string
+	Authenticator string
+	CACert        string
+	HostID        string
+	JWTEnv        string
+	JWTFile       string
+	Login         string
+	PolicyBranch  string
+	SecretName    string
+	ServiceID     string
+	URL           string
+
+	client *http.Client
+}
+
+// conjurToken is an access token and when it was issued
+type conjurToken struct {
+	issued time.Time
+	token  string
+}
+
+// conjurTokens caches the access tokens of the logins for the length of the invocation
+var conjurTokens = struct {
+	sync.Mutex
+	tokens map[string]conjurToken
+}{tokens: make(map[string]conjurToken)}
+
+func init() {
+	Register(&Provider{
+		Name:  "conjur",
+		Key:   "conjur",
+		Title: "CyberArk Conjur",
+		Usage: "CyberArk Conjur provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:     "account",
+				Key:      "account",
+				Usage:    "The Conjur account",
+				Required: true,
+			},
+			{
+				Name:  "api-key-env",
+				Key:   "apiKeyEnv",
+				Usage: fmt.Sprintf("The name of the environment variable that holds the API key of the login. If omitted '%s' is used", DefaultConjurAPIKeyEnv),
+			},
+			{
+				Name:  "authenticator",
+				Key:   "authenticator",
+				Usage: "The authenticator to use, either 'authn' with an API key or 'authn-jwt'. If omitted 'authn' is used",
+			},
+			{
+				Name:  "ca-cert",
+				Key:   "caCert",
+				Usage: "The path to the PEM encoded CA certificate that verifies the Conjur server's certificate",
+			},
+			{
+				Name:  "host-id",
+				Key:   "hostId",
+				Usage: "The host that authn-jwt authenticates as when the JWT doesn't identify it",
+			},
+			{
+				Name:  "jwt-env",
+				Key:   "jwtEnv",
+				Usage: fmt.Sprintf("The name of the environment variable that holds the JWT for authn-jwt. If omitted '%s' is used", DefaultConjurJWTEnv),
+			},
+			{
+				Name:  "jwt-file",
+				Key:   "jwtFile",
+				Usage: "The path to a file that holds the JWT for authn-jwt such as a service account token. It takes precedence over the environment variable",
+			},
+			{
+				Name:  "login",
+				Key:   "login",
+				Usage: "The user or host that authn authenticates as such as 'host/terraform/runner'",
+			},
+			{
+				Name:  "policy-branch",
+				Key:   "policyBranch",
+				Usage: "The policy branch that holds the variables such as 'terraform/tokens'. If omitted the root policy is used",
+			},
+			{
+				Name:  "service-id",
+				Key:   "serviceId",
+				Usage: "The service ID of the authn-jwt authenticator",
+			},
+			{
+				Name:     "url",
+				Key:      "url",
+				Usage:    "The URL of the Conjur server such as 'https://conjur.example.com'",
+				Required: true,
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &ConjurVault{
+				Account:       config.String("account"),
+				APIKeyEnv:     config.String("apiKeyEnv"),
+				Authenticator: config.String("authenticator"),
+				CACert:        config.String("caCert"),
+				HostID:        config.String("hostId"),
+				JWTEnv:        config.String("jwtEnv"),
+				JWTFile:       config.String("jwtFile"),
+				Login:         config.String("login"),
+				PolicyBranch:  config.String("policyBranch"),
+				SecretName:    secretName,
+				ServiceID:     config.String("serviceId"),
+				URL:           config.String("url"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// httpClient returns the client for the Conjur server that trusts the CA certificate
+func (cv *ConjurVault) httpClient() (*http.Client, error) {
+	if cv.client != nil {
+		return cv.client, nil
+	}
+
+	client, err := newHTTPClient(cv.CACert)
+	if err != nil {
+		return nil, err
+	}
+
+	cv.client = client
+	return client, nil
+}
+
+// endpoint returns the URL of the API path with each segment escaped
+func (cv *ConjurVault) endpoint(segments ...string) string {
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.TrimSuffix(cv.URL, "/") + "/" + strings.Join(segments, "/")
+}
+
+// tokenKey returns the key of an access token in the cache, so that a token is only reused for
+// the same endpoint and credential
+func tokenKey(endpoint string, credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return endpoint + "#" + hex.EncodeToString(sum[:])
+}
+
+// authenticateRequest returns the request that exchanges the credentials for an access token,
+// and the key of the token in the cache
+func (cv *ConjurVault) authenticateRequest(ctx context.Context) (*http.Request, string, error) {
+	switch cv.Authenticator {
+	case "", "authn":
+		if cv.Login == "" {
+			return nil, "", fmt.Errorf("a login must be configured to authenticate to Conjur with an API key")
+		}
+
+		env := cv.APIKeyEnv
+		if env == "" {
+			env = DefaultConjurAPIKeyEnv
+		}
+
+		apiKey := os.Getenv(env)
+		if apiKey == "" {
+			return nil, "", fmt.Errorf("%w: the environment variable '%s' doesn't hold an API key", terraerrors.ErrPermissionDenied, env)
+		}
+
+		endpoint := cv.endpoint("authn", cv.Account, cv.Login, "authenticate")
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(apiKey))
+		return request, tokenKey(endpoint, apiKey), err
+	case "authn-jwt":
+		if cv.ServiceID == "" {
+			return nil, "", fmt.Errorf("a service ID must be configured to authenticate to Conjur with authn-jwt")
+		}
+
+		jwt, err := cv.jwt()
+		if err != nil {
+			return nil, "", err
+		}
+
+		segments := []string{"authn-jwt", cv.ServiceID, cv.Account}
+		if cv.HostID != "" {
+			segments = append(segments, cv.HostID)
+		}
+
+		endpoint := cv.endpoint(append(segments, "authenticate")...)
+		form := url.Values{"jwt": {jwt}}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, "", err
+		}
+
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return request, tokenKey(endpoint, jwt), nil
+	}
+
+	return nil, "", fmt.Errorf("the Conjur authenticator '%s' is not supported, use 'authn' or 'authn-jwt'", cv.Authenticator)
+}
+
+// jwt reads the JWT for authn-jwt from the file or the environment variable
+func (cv *ConjurVault) jwt() (string, error) {
+	if cv.JWTFile != "" {
+		path, err := homedir.Expand(cv.JWTFile)
+		if err != nil {
+			return "", err
+		}
+
+		jwt, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: unable to read the JWT: %w", terraerrors.ErrPermissionDenied, err)
+		}
+
+		return strings.TrimSpace(string(jwt)), nil
+	}
+
+	env := cv.JWTEnv
+	if env == "" {
+		env = DefaultConjurJWTEnv
+	}
+
+	jwt := os.Getenv(env)
+	if jwt == "" {
+		return "", fmt.Errorf("%w: the environment variable '%s' doesn't hold a JWT", terraerrors.ErrPermissionDenied, env)
+	}
+
+	return jwt, nil
+}
+
+// accessToken returns the access token of the login, which is cached until it's close to
+// expiring or renew is set
+func (cv *ConjurVault) accessToken(ctx context.Context, renew bool) (string, error) {
+	request, key, err := cv.authenticateRequest(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	conjurTokens.Lock()
+	defer conjurTokens.Unlock()
+
+	cached, ok := conjurTokens.tokens[key]
+	if ok && !renew && time.Since(cached.issued) < conjurTokenLifetime {
+		return cached.token, nil
+	}
+
+	client, err := cv.httpClient()
+	if err != nil {
+		return "", err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", transportError(err, cv.Login)
+	}
+
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", transportError(err, cv.Login)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := fmt.Errorf("unable to authenticate to Conjur: %s", response.Status)
+		return "", statusError(response.StatusCode, err, request.URL.Path)
+	}
+
+	token := base64.StdEncoding.EncodeToString(body)
+	conjurTokens.tokens[key] = conjurToken{issued: time.Now(), token: token}
+
+	return token, nil
+}
+
+// request sends an authenticated request to the Conjur API. The access token is renewed once
+// when the server rejects it. Responses other than 2xx are returned as errors
+func (cv *ConjurVault) request(ctx context.Context, method string, endpoint string, body []byte, name string) ([]byte, error) {
+	client, err := cv.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	for renew := false; ; renew = true {
+		token, err := cv.accessToken(ctx, renew)
+		if err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set("Authorization", fmt.Sprintf("Token token=\"%s\"", token))
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, transportError(err, name)
+		}
+
+		data, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, transportError(err, name)
+		}
+
+		if response.StatusCode == http.StatusUnauthorized && !renew {
+			continue
+		}
+
+		if response.StatusCode < 200 || response.StatusCode > 299 {
+			err := fmt.Errorf("the Conjur API returned %s: %s", response.Status, strings.TrimSpace(string(data)))
+			return nil, statusError(response.StatusCode, err, name)
+		}
+
+		return data, nil
+	}
+}
+
+// policy returns the ID of the policy branch, which is 'root' when none is configured
+func (cv *ConjurVault) policy() string {
+	branch := strings.Trim(cv.PolicyBranch, "/")
+	if branch == "" {
+		return "root"
+	}
+
+	return branch
+}
+
+// variable returns the ID of the secret's variable
+func (cv *ConjurVault) variable(secretName string) string {
+	if cv.policy() == "root" {
+		return secretName
+	}
+
+	return cv.policy() + "/" + secretName
+}
+
+// loadPolicy declares or deletes records in the policy branch. A POST adds the records and a
+// PATCH can delete them
+func (cv *ConjurVault) loadPolicy(ctx context.Context, method string, policy string) error {
+	endpoint := cv.endpoint("policies", cv.Account, "policy", cv.policy())
+	_, err := cv.request(ctx, method, endpoint, []byte(policy), cv.policy())
+	return err
+}
+
+// Create sets the value of the secret's variable, and declares the variable in the policy
+// branch when it doesn't exist
+func (cv *ConjurVault) Create(ctx context.Context, secretValue string, method string) error {
+	variable := cv.variable(cv.SecretName)
+	endpoint := cv.endpoint("secrets", cv.Account, "variable", variable)
+
+	_, err := cv.request(ctx, http.MethodPost, endpoint, []byte(secretValue), variable)
+	if !terraerrors.IsNotFound(err) {
+		return err
+	}
+
+	policy, err := json.Marshal(cv.SecretName)
+	if err != nil {
+		return err
+	}
+
+	err = cv.loadPolicy(ctx, http.MethodPost, fmt.Sprintf("- !variable %s\n", policy))
+	if err != nil {
+		return err
+	}
+
+	_, err = cv.request(ctx, http.MethodPost, endpoint, []byte(secretValue), variable)
+	return err
+}
+
+// Delete removes the secret's variable from the policy branch
+func (cv *ConjurVault) Delete(ctx context.Context) error {
+	_, err := cv.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	record, err := json.Marshal(cv.SecretName)
+	if err != nil {
+		return err
+	}
+
+	return cv.loadPolicy(ctx, http.MethodPatch, fmt.Sprintf("- !delete\n  record: !variable %s\n", record))
+}
+
+func (cv *ConjurVault) Get(ctx context.Context) ([]byte, error) {
+	variable := cv.variable(cv.SecretName)
+	endpoint := cv.endpoint("secrets", cv.Account, "variable", variable)
+
+	return cv.request(ctx, http.MethodGet, endpoint, nil, variable)
+}
+
+// List reads the secrets' variables with a single batch request
+func (cv *ConjurVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var ids []string
+	for _, secretName := range secretNames {
+		ids = append(ids, fmt.Sprintf("%s:variable:%s", cv.Account, cv.variable(secretName)))
+	}
+
+	endpoint := cv.endpoint("secrets") + "?" + url.Values{"variable_ids": {strings.Join(ids, ",")}}.Encode()
+	data, err := cv.request(ctx, http.MethodGet, endpoint, nil, strings.Join(secretNames, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]string
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for i, id := range ids {
+		value, ok := values[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretNames[i])
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the variables below the policy branch that the login can see
+func (cv *ConjurVault) Names(ctx context.Context) ([]string, error) {
+	prefix := fmt.Sprintf("%s:variable:%s", cv.Account, cv.variable(""))
+
+	var names []string
+	for offset := 0; ; offset += conjurPageSize {
+		query := url.Values{
+			"limit":  {fmt.Sprint(conjurPageSize)},
+			"offset": {fmt.Sprint(offset)},
+		}
+
+		endpoint := cv.endpoint("resources", cv.Account, "variable") + "?" + query.Encode()
+		data, err := cv.request(ctx, http.MethodGet, endpoint, nil, cv.policy())
+		if err != nil {
+			return nil, err
+		}
+
+		var resources []struct {
+			ID string `json:"id"`
+		}
+
+		err = json.Unmarshal(data, &resources)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resource := range resources {
+			if name, ok := strings.CutPrefix(resource.ID, prefix); ok {
+				names = append(names, name)
+			}
+		}
+
+		if len(resources) < conjurPageSize {
+			break
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,106 @@
+package vault_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+func TestConjurAccessToken(t *testing.T) {
+	t.Setenv("CONJUR_AUTHN_API_KEY", "api-key")
+	ctx := context.Background()
+
+	fake := vaulttest.NewConjur(t, "myorg")
+	fake.APIKeys["host/terraform/runner"] = "api-key"
+	config := vault.ProviderConfig{
+		"account":      "myorg",
+		"login":        "host/terraform/runner",
+		"policyBranch": "/terraform/tokens/",
+		"url":          fake.URL,
+	}
+
+	for _, name := range []string{"app.terraform.io", "tfe.example.com"} {
+		err := newVault(t, "conjur", config, name).Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if value, _ := fake.Variable("terraform/tokens/app.terraform.io"); value != "token-app.terraform.io" {
+		t.Errorf("expected the variable below the policy branch got '%s'", value)
+	}
+
+	if authentications := fake.Authentications(); authentications != 1 {
+		t.Errorf("expected the access token to be reused got %d authentications", authentications)
+	}
+
+	fake.Expire()
+	values, err := newVault(t, "conjur", config, "").List(ctx, []string{"tfe.example.com", "app.terraform.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"token-tfe.example.com", "token-app.terraform.io"}) {
+		t.Errorf("expected the values in order got %q", values)
+	}
+
+	if authentications := fake.Authentications(); authentications != 2 {
+		t.Errorf("expected an expired access token to be renewed once got %d authentications", authentications)
+	}
+
+	names, err := newVault(t, "conjur", config, "").(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "tfe.example.com"}) {
+		t.Errorf("expected the variables below the policy branch got %q", names)
+	}
+}
+
+func TestConjurJWT(t *testing.T) {
+	ctx := context.Background()
+	fake := vaulttest.NewConjur(t, "myorg")
+	fake.JWTs["kubernetes"] = "header.payload.signature"
+
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(jwtFile, []byte("header.payload.signature\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := vault.ProviderConfig{
+		"account":       "myorg",
+		"authenticator": "authn-jwt",
+		"hostId":        "host/terraform/atlantis",
+		"jwtFile":       jwtFile,
+		"serviceId":     "kubernetes",
+		"url":           fake.URL,
+	}
+
+	terraVault := newVault(t, "conjur", config, "app.terraform.io")
+	err = terraVault.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Variable("app.terraform.io"); !ok {
+		t.Error("expected the variable in the root policy")
+	}
+
+	delete(config, "jwtFile")
+	t.Setenv("TC_CONJUR_JWT", "expired.jwt.token")
+	config["jwtEnv"] = "TC_CONJUR_JWT"
+
+	_, err = newVault(t, "conjur", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied for a rejected JWT got %v", err)
+	}
+}
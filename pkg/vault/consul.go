@@ -0,0 +1,363 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	consul "github.com/hashicorp/consul/api"
+	"github.com/mitchellh/go-homedir"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// ConsulVault stores secrets as keys in the Consul KV store below a prefix. Writes use
+// check-and-set, so a key that's changed by another process after terracreds read it
+// is not overwritten
+type ConsulVault struct {
+	Address            string
+	CACert             string
+	ClientCert         string
+	ClientKey          string
+	Datacenter         string
+	InsecureSkipVerify bool
+	Namespace          string
+	Prefix             string
+	SecretName         string
+	TLSServerName      string
+	TokenEnv           string
+	TokenFile          string
+
+	// modifyIndex is the index of the key when Get last read it, and read is whether it did
+	modifyIndex uint64
+	read        bool
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "consul",
+		Key:   "consul",
+		Title: "Consul KV",
+		Usage: "Consul KV provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "address",
+				Key:   "address",
+				Usage: "The address of the Consul agent such as 'https://consul.example.com:8501'. If omitted 'CONSUL_HTTP_ADDR' or '127.0.0.1:8500' is used",
+			},
+			{
+				Name:  "ca-cert",
+				Key:   "caCert",
+				Usage: "The path to the PEM encoded CA certificate that verifies the agent's certificate",
+			},
+			{
+				Name:  "client-cert",
+				Key:   "clientCert",
+				Usage: "The path to the PEM encoded client certificate for mutual TLS",
+			},
+			{
+				Name:  "client-key",
+				Key:   "clientKey",
+				Usage: "The path to the PEM encoded private key of the client certificate",
+			},
+			{
+				Name:  "datacenter",
+				Key:   "datacenter",
+				Usage: "The datacenter of the KV store. If omitted the datacenter of the agent is used",
+			},
+			{
+				Name:  "insecure-skip-verify",
+				Key:   "insecureSkipVerify",
+				Usage: "Skip the verification of the agent's certificate",
+				Bool:  true,
+			},
+			{
+				Name:  "namespace",
+				Key:   "namespace",
+				Usage: "The Consul Enterprise namespace of the KV store. If omitted the namespace of the token is used",
+			},
+			{
+				Name:  "prefix",
+				Key:   "prefix",
+				Usage: "The key prefix that holds the secrets such as 'terraform/tokens'. If omitted the keys are stored at the root",
+			},
+			{
+				Name:  "tls-server-name",
+				Key:   "tlsServerName",
+				Usage: "The server name that's verified against the agent's certificate",
+			},
+			{
+				Name:  "token-env",
+				Key:   "tokenEnv",
+				Usage: "The name of the environment variable that holds the ACL token. If omitted 'CONSUL_HTTP_TOKEN' is used",
+			},
+			{
+				Name:  "token-file",
+				Key:   "tokenFile",
+				Usage: "The path to a file that holds the ACL token. It takes precedence over the environment variable",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &ConsulVault{
+				Address:            config.String("address"),
+				CACert:             config.String("caCert"),
+				ClientCert:         config.String("clientCert"),
+				ClientKey:          config.String("clientKey"),
+				Datacenter:         config.String("datacenter"),
+				InsecureSkipVerify: config.Bool("insecureSkipVerify"),
+				Namespace:          config.String("namespace"),
+				Prefix:             config.String("prefix"),
+				SecretName:         secretName,
+				TLSServerName:      config.String("tlsServerName"),
+				TokenEnv:           config.String("tokenEnv"),
+				TokenFile:          config.String("tokenFile"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// newConsulClient returns a client for the KV store. Settings that aren't configured fall back
+// to the CONSUL_* environment variables that the consul CLI reads
+func (cv *ConsulVault) newConsulClient() (*consul.KV, error) {
+	config := consul.DefaultConfig()
+	if cv.Address != "" {
+		config.Address = cv.Address
+	}
+
+	config.Datacenter = cv.Datacenter
+	config.Namespace = cv.Namespace
+
+	if cv.TokenEnv != "" {
+		config.Token = os.Getenv(cv.TokenEnv)
+	}
+
+	tokenFile, err := homedir.Expand(cv.TokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenFile != "" {
+		config.TokenFile = tokenFile
+	}
+
+	for _, setting := range []struct {
+		path  string
+		field *string
+	}{
+		{cv.CACert, &config.TLSConfig.CAFile},
+		{cv.ClientCert, &config.TLSConfig.CertFile},
+		{cv.ClientKey, &config.TLSConfig.KeyFile},
+	} {
+		path, err := homedir.Expand(setting.path)
+		if err != nil {
+			return nil, err
+		}
+
+		if path != "" {
+			*setting.field = path
+		}
+	}
+
+	if cv.TLSServerName != "" {
+		config.TLSConfig.Address = cv.TLSServerName
+	}
+
+	if cv.InsecureSkipVerify {
+		config.TLSConfig.InsecureSkipVerify = true
+	}
+
+	client, err := consul.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.KV(), nil
+}
+
+// consulError converts a Consul error into the kind of failure it represents
+func consulError(err error, key string) error {
+	var statusErr consul.StatusError
+	if errors.As(err, &statusErr) {
+		return statusError(statusErr.Code, err, key)
+	}
+
+	return transportError(err, key)
+}
+
+// prefix returns the key prefix without leading and trailing slashes
+func (cv *ConsulVault) prefix() string {
+	return strings.Trim(cv.Prefix, "/")
+}
+
+// key returns the key of the secret
+func (cv *ConsulVault) key(secretName string) string {
+	if cv.prefix() == "" {
+		return secretName
+	}
+
+	return cv.prefix() + "/" + secretName
+}
+
+// pair reads the secret's key, which is nil when it doesn't exist
+func (cv *ConsulVault) pair(ctx context.Context, kv *consul.KV, secretName string) (*consul.KVPair, error) {
+	key := cv.key(secretName)
+	pair, _, err := kv.Get(key, (&consul.QueryOptions{RequireConsistent: true}).WithContext(ctx))
+	if err != nil {
+		return nil, consulError(err, key)
+	}
+
+	return pair, nil
+}
+
+// Create writes the secret with check-and-set. The key must be unchanged since Get read it,
+// or since it was read here when Get wasn't called, otherwise ErrConflict is returned
+func (cv *ConsulVault) Create(ctx context.Context, secretValue string, method string) error {
+	kv, err := cv.newConsulClient()
+	if err != nil {
+		return err
+	}
+
+	key := cv.key(cv.SecretName)
+	var index uint64
+	if cv.read {
+		index = cv.modifyIndex
+	} else if method != "Created" {
+		pair, err := cv.pair(ctx, kv, cv.SecretName)
+		if err != nil {
+			return err
+		}
+
+		if pair != nil {
+			index = pair.ModifyIndex
+		}
+	}
+
+	pair := &consul.KVPair{
+		Key:         key,
+		ModifyIndex: index,
+		Value:       []byte(secretValue),
+	}
+
+	ok, _, err := kv.CAS(pair, (&consul.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return consulError(err, key)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: the key '%s' was changed by another process", terraerrors.ErrConflict, key)
+	}
+
+	cv.read = false
+	return nil
+}
+
+// Delete removes the secret's key with check-and-set
+func (cv *ConsulVault) Delete(ctx context.Context) error {
+	kv, err := cv.newConsulClient()
+	if err != nil {
+		return err
+	}
+
+	key := cv.key(cv.SecretName)
+	pair, err := cv.pair(ctx, kv, cv.SecretName)
+	if err != nil {
+		return err
+	}
+
+	if pair == nil {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, key)
+	}
+
+	ok, _, err := kv.DeleteCAS(pair, (&consul.WriteOptions{}).WithContext(ctx))
+	if err != nil {
+		return consulError(err, key)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: the key '%s' was changed by another process", terraerrors.ErrConflict, key)
+	}
+
+	cv.read = false
+	return nil
+}
+
+// Get reads the secret's key and remembers its index for the check-and-set of Create
+func (cv *ConsulVault) Get(ctx context.Context) ([]byte, error) {
+	kv, err := cv.newConsulClient()
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := cv.pair(ctx, kv, cv.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	cv.read = true
+	if pair == nil {
+		cv.modifyIndex = 0
+		return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, cv.key(cv.SecretName))
+	}
+
+	cv.modifyIndex = pair.ModifyIndex
+	return pair.Value, nil
+}
+
+func (cv *ConsulVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	kv, err := cv.newConsulClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		pair, err := cv.pair(ctx, kv, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		if pair == nil {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, cv.key(secretName))
+		}
+
+		secretValues = append(secretValues, string(pair.Value))
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the keys below the prefix
+func (cv *ConsulVault) Names(ctx context.Context) ([]string, error) {
+	kv, err := cv.newConsulClient()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := cv.prefix()
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	keys, _, err := kv.Keys(prefix, "", (&consul.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, consulError(err, prefix)
+	}
+
+	var names []string
+	for _, key := range keys {
+		// Keys that end with a slash are folders rather than secrets
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+
+		names = append(names, strings.TrimPrefix(key, prefix))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,165 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+// setConsulEnv keeps the Consul client from reading the user's CONSUL_* settings
+func setConsulEnv(t *testing.T) {
+	for _, env := range []string{"CONSUL_HTTP_ADDR", "CONSUL_HTTP_TOKEN", "CONSUL_HTTP_TOKEN_FILE", "CONSUL_CACERT", "CONSUL_HTTP_SSL_VERIFY"} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestConsulCheckAndSet(t *testing.T) {
+	setConsulEnv(t)
+	t.Setenv("CONSUL_HTTP_TOKEN", "vaulttest")
+	ctx := context.Background()
+
+	fake := vaulttest.NewConsulKV(t, "vaulttest")
+	config := vault.ProviderConfig{
+		"address": fake.URL,
+		"prefix":  "/terraform/",
+	}
+
+	first := newVault(t, "consul", config, "app.terraform.io")
+	second := newVault(t, "consul", config, "app.terraform.io")
+
+	_, err := first.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound got %v", err)
+	}
+
+	err = second.Create(ctx, "second-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = first.Create(ctx, "first-token", "Created")
+	if !errors.Is(err, terraerrors.ErrConflict) {
+		t.Errorf("expected ErrConflict creating a key that was created after the read got %v", err)
+	}
+
+	_, err = first.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Put("", "terraform/app.terraform.io", "concurrent-token")
+	err = first.Create(ctx, "first-token", "Updated")
+	if !errors.Is(err, terraerrors.ErrConflict) {
+		t.Errorf("expected ErrConflict updating a key that changed after the read got %v", err)
+	}
+
+	if value, _ := fake.Value("", "terraform/app.terraform.io"); value != "concurrent-token" {
+		t.Errorf("expected the concurrent write to be kept got '%s'", value)
+	}
+
+	err = second.Create(ctx, "third-token", "Updated")
+	if err != nil {
+		t.Errorf("expected an update without a read to use the current index got %v", err)
+	}
+}
+
+func TestConsulSettings(t *testing.T) {
+	setConsulEnv(t)
+	ctx := context.Background()
+
+	fake := vaulttest.NewConsulKV(t, "file-token")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	err := os.WriteFile(tokenFile, []byte("file-token\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TC_CONSUL_TOKEN", "env-token")
+	config := vault.ProviderConfig{
+		"address":    fake.URL,
+		"datacenter": "dc1",
+		"namespace":  "team-a",
+		"prefix":     "terraform",
+		"tokenEnv":   "TC_CONSUL_TOKEN",
+		"tokenFile":  tokenFile,
+	}
+
+	for _, name := range []string{"app.terraform.io", "tfe/prod.example.com"} {
+		err := newVault(t, "consul", config, name).Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := fake.Value("team-a", "terraform/app.terraform.io"); !ok {
+		t.Error("expected the key in the namespace")
+	}
+
+	fake.Put("team-a", "terraform/", "")
+	fake.Put("team-a", "other/app.terraform.io", "other-token")
+	names, err := newVault(t, "consul", config, "").(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "tfe/prod.example.com"}) {
+		t.Errorf("expected the keys below the prefix got %q", names)
+	}
+
+	delete(config, "tokenFile")
+	_, err = newVault(t, "consul", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied with the environment token got %v", err)
+	}
+
+	config["tokenFile"] = tokenFile
+	config["datacenter"] = "dc2"
+	_, err = newVault(t, "consul", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable for an unknown datacenter got %v", err)
+	}
+}
+
+func TestConsulTLS(t *testing.T) {
+	setConsulEnv(t)
+	t.Setenv("CONSUL_HTTP_TOKEN", "vaulttest")
+	ctx := context.Background()
+
+	fake := vaulttest.NewConsulKVTLS(t, "vaulttest")
+	fake.Put("", "app.terraform.io", "my-secret-token")
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: fake.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := vault.ProviderConfig{"address": fake.URL}
+	_, err = newVault(t, "consul", config, "app.terraform.io").Get(ctx)
+	if err == nil {
+		t.Error("expected an unknown certificate to be rejected")
+	}
+
+	for _, settings := range []vault.ProviderConfig{
+		{"caCert": caCert, "tlsServerName": "example.com"},
+		{"insecureSkipVerify": true},
+	} {
+		settings["address"] = fake.URL
+		value, err := newVault(t, "consul", settings, "app.terraform.io").Get(ctx)
+		if err != nil {
+			t.Fatalf("%v: %v", settings, err)
+		}
+
+		if string(value) != "my-secret-token" {
+			t.Errorf("expected the value got '%s'", value)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+const (
+	// DefaultEnvTemplate is the template of the variable names when none is configured
+	DefaultEnvTemplate = `TC_SECRET_{{ upper (replace .Name "." "_") }}`
+
+	// envPlaceholder stands in for the secret name when the variable names are matched
+	envPlaceholder = "\x00"
+)
+
+// envFuncs are the functions available to the template of the variable names
+var envFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"replace": func(s string, old string, new string) string { return strings.ReplaceAll(s, old, new) },
+	"upper":   strings.ToUpper,
+}
+
+// EnvVault reads secrets from the environment variables that a CI platform injects. The names
+// of the variables are built from a template, and secrets can't be written or deleted
+type EnvVault struct {
+	SecretName string
+	Template   string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "env",
+		Key:   "env",
+		Title: "Environment Variable Provider",
+		Usage: "Read-only environment variable provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "template",
+				Key:   "template",
+				Usage: fmt.Sprintf("The template of the variable names where '.Name' is the secret name. If omitted '%s' is used", DefaultEnvTemplate),
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &EnvVault{
+				SecretName: secretName,
+				Template:   config.String("template"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// variable returns the name of the environment variable that holds the secret
+func (ev *EnvVault) variable(secretName string) (string, error) {
+	text := ev.Template
+	if text == "" {
+		text = DefaultEnvTemplate
+	}
+
+	tmpl, err := template.New("env").Funcs(envFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("the env template is not valid: %w", err)
+	}
+
+	var variable strings.Builder
+	err = tmpl.Execute(&variable, struct{ Name string }{Name: secretName})
+	if err != nil {
+		return "", err
+	}
+
+	return variable.String(), nil
+}
+
+// value reads the secret from its environment variable. A variable that's empty is treated
+// as missing since CI platforms set secrets that aren't available to an empty string
+func (ev *EnvVault) value(secretName string) (string, error) {
+	variable, err := ev.variable(secretName)
+	if err != nil {
+		return "", err
+	}
+
+	value := os.Getenv(variable)
+	if value == "" {
+		return "", fmt.Errorf("%w: %s", terraerrors.ErrNotFound, variable)
+	}
+
+	return value, nil
+}
+
+// readOnly returns the error for a write to the environment
+func (ev *EnvVault) readOnly() error {
+	variable, err := ev.variable(ev.SecretName)
+	if err != nil {
+		return err
+	}
+
+	err = errors.New("the env provider is read-only. Set the variable in the environment of the process instead")
+	return wrapError(terraerrors.ErrPermissionDenied, err, variable)
+}
+
+// Create returns an error since the environment of the process can't be written
+func (ev *EnvVault) Create(ctx context.Context, secretValue string, method string) error {
+	return ev.readOnly()
+}
+
+// Delete returns an error since the environment of the process can't be written
+func (ev *EnvVault) Delete(ctx context.Context) error {
+	return ev.readOnly()
+}
+
+func (ev *EnvVault) Get(ctx context.Context) ([]byte, error) {
+	value, err := ev.value(ev.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+func (ev *EnvVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, err := ev.value(secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the secrets in the environment. The template can't always be
+// reversed, so a name is the part of the variable name that the template fills in, such as
+// 'APP_TERRAFORM_IO', and it's only returned when it builds the same variable name again
+func (ev *EnvVault) Names(ctx context.Context) ([]string, error) {
+	pattern, err := ev.variable(envPlaceholder)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, suffix, ok := strings.Cut(pattern, envPlaceholder)
+	if !ok || strings.Contains(suffix, envPlaceholder) {
+		return nil, fmt.Errorf("the env template must use the secret name exactly once to list the secrets")
+	}
+
+	var names []string
+	for _, env := range os.Environ() {
+		variable, value, _ := strings.Cut(env, "=")
+		if value == "" || len(variable) <= len(prefix)+len(suffix) || !strings.HasPrefix(variable, prefix) || !strings.HasSuffix(variable, suffix) {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(variable, prefix), suffix)
+		if rendered, err := ev.variable(name); err != nil || rendered != variable {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+func TestEnvVault(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_SECRET_APP_TERRAFORM_IO", "app-token")
+	t.Setenv("TC_SECRET_TFE_EXAMPLE_COM", "tfe-token")
+	t.Setenv("TC_SECRET_EMPTY", "")
+	t.Setenv("TC_SECRET_lower", "not-a-secret")
+
+	ev := &EnvVault{SecretName: "app.terraform.io"}
+	secret, err := ev.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret) != "app-token" {
+		t.Errorf("expected 'app-token' got '%s'", secret)
+	}
+
+	values, err := ev.List(ctx, []string{"tfe.example.com", "app.terraform.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"tfe-token", "app-token"}) {
+		t.Errorf("expected the values in the order of the names got %v", values)
+	}
+
+	for _, secretName := range []string{"missing.example.com", "empty"} {
+		_, err = (&EnvVault{SecretName: secretName}).Get(ctx)
+		if !terraerrors.IsNotFound(err) {
+			t.Errorf("expected ErrNotFound for '%s' got %v", secretName, err)
+		}
+	}
+
+	names, err := ev.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"APP_TERRAFORM_IO", "TFE_EXAMPLE_COM"}) {
+		t.Errorf("expected the names of the variables that match the template got %v", names)
+	}
+
+	values, err = ev.List(ctx, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"app-token", "tfe-token"}) {
+		t.Errorf("expected the listed names to be readable got %v", values)
+	}
+}
+
+func TestEnvVaultTemplate(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("ci_app-terraform-io_token", "token")
+
+	ev := &EnvVault{SecretName: "APP.terraform.io", Template: `ci_{{ lower (replace .Name "." "-") }}_token`}
+	secret, err := ev.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret) != "token" {
+		t.Errorf("expected 'token' got '%s'", secret)
+	}
+
+	names, err := ev.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app-terraform-io"}) {
+		t.Errorf("expected [app-terraform-io] got %v", names)
+	}
+
+	_, err = (&EnvVault{Template: "TC_{{ .Name }}_{{ .Name }}"}).Names(ctx)
+	if err == nil {
+		t.Errorf("expected an error for a template that uses the name twice")
+	}
+
+	_, err = (&EnvVault{SecretName: "app.terraform.io", Template: "TC_{{ .Host }}"}).Get(ctx)
+	if err == nil {
+		t.Errorf("expected an error for a template with an unknown field")
+	}
+}
+
+func TestEnvVaultReadOnly(t *testing.T) {
+	ctx := context.Background()
+	ev := &EnvVault{SecretName: "app.terraform.io"}
+
+	for name, err := range map[string]error{
+		"create": ev.Create(ctx, "token", "Created"),
+		"delete": ev.Delete(ctx),
+	} {
+		if !errors.Is(err, terraerrors.ErrPermissionDenied) || !strings.Contains(err.Error(), "read-only") || !strings.Contains(err.Error(), "TC_SECRET_APP_TERRAFORM_IO") {
+			t.Errorf("expected %s to return a read-only error for the variable got %v", name, err)
+		}
+	}
+}
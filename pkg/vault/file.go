@@ -0,0 +1,579 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+)
+
+const (
+	// DefaultFilePath is the path of the file vault when none is configured
+	DefaultFilePath = "~/.terracreds/vault.enc"
+
+	// DefaultPassphraseEnv is the environment variable that holds the passphrase of the file vault
+	// when none is configured
+	DefaultPassphraseEnv = "TC_FILE_PASSPHRASE"
+
+	fileVersion = 1
+	fileLockAge = time.Minute
+)
+
+// FileVault stores secrets in a single file encrypted with XChaCha20-Poly1305 under a key derived
+// from a passphrase with argon2id. It's used on machines without a credential vault such as
+// headless Linux hosts and containers
+type FileVault struct {
+	KeyFile       string
+	PassphraseEnv string
+	Path          string
+	SecretName    string
+
+	// Prompt reads the passphrase when no key file or environment variable holds it. If nil
+	// the passphrase is read from the terminal
+	Prompt func(prompt string) (string, error)
+
+	passphrase string
+}
+
+// fileKDF holds the argon2id parameters used to derive the key from the passphrase
+type fileKDF struct {
+	Name    string `json:"name"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// fileHeader is authenticated along with the secrets so its parameters can't be altered
+type fileHeader struct {
+	Version int     `json:"version"`
+	KDF     fileKDF `json:"kdf"`
+}
+
+// fileEnvelope is the content of the file vault
+type fileEnvelope struct {
+	fileHeader
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "file",
+		Key:   "file",
+		Title: "an encrypted local file",
+		Usage: "Encrypted local file provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "key-file",
+				Key:   "keyFile",
+				Usage: "The path to a file that contains the passphrase",
+			},
+			{
+				Name:  "passphrase-env",
+				Key:   "passphraseEnv",
+				Usage: fmt.Sprintf("The name of the environment variable that holds the passphrase. If omitted '%s' is used", DefaultPassphraseEnv),
+			},
+			{
+				Name:  "path",
+				Key:   "path",
+				Usage: fmt.Sprintf("The path to the encrypted file. If omitted '%s' is used", DefaultFilePath),
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &FileVault{
+				KeyFile:       config.String("keyFile"),
+				PassphraseEnv: config.String("passphraseEnv"),
+				Path:          config.String("path"),
+				SecretName:    secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// path returns the expanded path of the file
+func (fv *FileVault) path() (string, error) {
+	path := fv.Path
+	if path == "" {
+		path = DefaultFilePath
+	}
+
+	return homedir.Expand(path)
+}
+
+// Passphrase returns the passphrase from the key file, the environment variable or the terminal
+// in that order. When the file doesn't exist yet a prompted passphrase is asked for twice
+func (fv *FileVault) Passphrase() (string, error) {
+	if fv.passphrase != "" {
+		return fv.passphrase, nil
+	}
+
+	passphrase, err := fv.readPassphrase()
+	if err != nil {
+		return "", err
+	}
+
+	fv.passphrase = passphrase
+	return passphrase, nil
+}
+
+func (fv *FileVault) readPassphrase() (string, error) {
+	if fv.KeyFile != "" {
+		keyFile, err := homedir.Expand(fv.KeyFile)
+		if err != nil {
+			return "", err
+		}
+
+		bytes, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("%w: unable to read the key file: %w", terraerrors.ErrPermissionDenied, err)
+		}
+
+		passphrase := strings.TrimRight(string(bytes), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("the key file '%s' is empty", keyFile)
+		}
+
+		return passphrase, nil
+	}
+
+	env := fv.PassphraseEnv
+	if env == "" {
+		env = DefaultPassphraseEnv
+	}
+
+	if passphrase := os.Getenv(env); passphrase != "" {
+		return passphrase, nil
+	}
+
+	path, err := fv.path()
+	if err != nil {
+		return "", err
+	}
+
+	prompt := fv.Prompt
+	if prompt == nil {
+		prompt = helpers.ReadPassword
+	}
+
+	passphrase, err := prompt(fmt.Sprintf("Enter the passphrase for the file vault '%s': ", path))
+	if err != nil {
+		return "", fmt.Errorf("%w: the passphrase isn't set in '%s' or a key file and can't be prompted for: %w", terraerrors.ErrPermissionDenied, env, err)
+	}
+
+	if passphrase == "" {
+		return "", fmt.Errorf("%w: the passphrase is empty", terraerrors.ErrPermissionDenied)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		confirm, err := prompt("Enter the passphrase again to create the file vault: ")
+		if err != nil {
+			return "", err
+		}
+
+		if confirm != passphrase {
+			return "", fmt.Errorf("%w: the passphrases don't match", terraerrors.ErrPermissionDenied)
+		}
+	}
+
+	return passphrase, nil
+}
+
+// newKDF returns argon2id parameters with a new random salt
+func newKDF() (fileKDF, error) {
+	kdf := fileKDF{
+		Name:    "argon2id",
+		Salt:    make([]byte, 16),
+		Time:    1,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+
+	_, err := rand.Read(kdf.Salt)
+	return kdf, err
+}
+
+// key derives the encryption key from the passphrase
+func (kdf fileKDF) key(passphrase string) ([]byte, error) {
+	if kdf.Name != "argon2id" {
+		return nil, fmt.Errorf("the key derivation function '%s' is not supported", kdf.Name)
+	}
+
+	return argon2.IDKey([]byte(passphrase), kdf.Salt, kdf.Time, kdf.Memory, kdf.Threads, chacha20poly1305.KeySize), nil
+}
+
+// read decrypts the secrets in the file. A missing file has no secrets, and the header of the
+// file is returned so its parameters can be reused when the secrets are written back
+func (fv *FileVault) read(path string, passphrase string) (map[string]string, *fileHeader, error) {
+	secrets := make(map[string]string)
+
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return secrets, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
+	}
+
+	var envelope fileEnvelope
+	err = json.Unmarshal(bytes, &envelope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the file vault '%s' is not valid: %w", path, err)
+	}
+
+	if envelope.Version != fileVersion {
+		return nil, nil, fmt.Errorf("the file vault '%s' has version %d but only version %d is supported", path, envelope.Version, fileVersion)
+	}
+
+	key, err := envelope.KDF.key(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header, err := json.Marshal(envelope.fileHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: the file vault '%s' can't be decrypted, the passphrase is wrong or the file was modified", terraerrors.ErrPermissionDenied, path)
+	}
+
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secrets, &envelope.fileHeader, nil
+}
+
+// write encrypts the secrets and atomically replaces the file. The header's parameters are
+// reused when it's not nil, otherwise a new salt is generated
+func (fv *FileVault) write(path string, passphrase string, secrets map[string]string, header *fileHeader) error {
+	if header == nil {
+		kdf, err := newKDF()
+		if err != nil {
+			return err
+		}
+
+		header = &fileHeader{
+			Version: fileVersion,
+			KDF:     kdf,
+		}
+	}
+
+	key, err := header.KDF.key(passphrase)
+	if err != nil {
+		return err
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	additionalData, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	envelope := fileEnvelope{
+		fileHeader: *header,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, additionalData),
+	}
+
+	bytes, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, bytes)
+}
+
+// writeFileAtomic writes the data to a temporary file next to the path and renames it over the
+// path, so a reader sees either the old or the new content
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	closeErr := tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	if closeErr != nil {
+		return closeErr
+	}
+
+	err = os.Chmod(tmp.Name(), 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// lock takes the lock file next to the path so that concurrent writers don't lose each other's
+// secrets. A lock older than a minute is left over from a process that died and is removed
+func lock(ctx context.Context, path string) (func(), error) {
+	lockPath := path + ".lock"
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fileLockAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: the file '%s' is locked by another process: %w", terraerrors.ErrUnavailable, path, ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// update applies the change to the secrets while holding the lock
+func (fv *FileVault) update(ctx context.Context, change func(secrets map[string]string) error) error {
+	path, err := fv.path()
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := fv.Passphrase()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, header, err := fv.read(path, passphrase)
+	if err != nil {
+		return err
+	}
+
+	err = change(secrets)
+	if err != nil {
+		return err
+	}
+
+	return fv.write(path, passphrase, secrets, header)
+}
+
+// missing returns ErrNotFound when the file doesn't exist yet, so that reading a new file vault
+// doesn't ask for a passphrase
+func missing(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: the file vault '%s' doesn't exist", terraerrors.ErrNotFound, path)
+	}
+
+	return nil
+}
+
+// secrets returns the decrypted secrets
+func (fv *FileVault) secrets() (map[string]string, error) {
+	path, err := fv.path()
+	if err != nil {
+		return nil, err
+	}
+
+	err = missing(path)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase, err := fv.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, _, err := fv.read(path, passphrase)
+	return secrets, err
+}
+
+// Rotate re-encrypts the file with a key derived from the new passphrase and a new salt
+func (fv *FileVault) Rotate(ctx context.Context, newPassphrase string) error {
+	if newPassphrase == "" {
+		return errors.New("the new passphrase is empty")
+	}
+
+	path, err := fv.path()
+	if err != nil {
+		return err
+	}
+
+	err = missing(path)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := fv.Passphrase()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, _, err := fv.read(path, passphrase)
+	if err != nil {
+		return err
+	}
+
+	err = fv.write(path, newPassphrase, secrets, nil)
+	if err != nil {
+		return err
+	}
+
+	fv.passphrase = newPassphrase
+	return nil
+}
+
+// Create stores a secret in the file
+func (fv *FileVault) Create(ctx context.Context, secretValue string, method string) error {
+	return fv.update(ctx, func(secrets map[string]string) error {
+		secrets[fv.SecretName] = secretValue
+		return nil
+	})
+}
+
+// Delete removes a secret from the file
+func (fv *FileVault) Delete(ctx context.Context) error {
+	path, err := fv.path()
+	if err != nil {
+		return err
+	}
+
+	err = missing(path)
+	if err != nil {
+		return err
+	}
+
+	return fv.update(ctx, func(secrets map[string]string) error {
+		if _, ok := secrets[fv.SecretName]; !ok {
+			return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, fv.SecretName)
+		}
+
+		delete(secrets, fv.SecretName)
+		return nil
+	})
+}
+
+// Get retrieves a secret from the file
+func (fv *FileVault) Get(ctx context.Context) ([]byte, error) {
+	secrets, err := fv.secrets()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := secrets[fv.SecretName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, fv.SecretName)
+	}
+
+	return []byte(value), nil
+}
+
+// List retrieves the secrets from the file
+func (fv *FileVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	secrets, err := fv.secrets()
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, ok := secrets[secretName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the secrets in the file
+func (fv *FileVault) Names(ctx context.Context) ([]string, error) {
+	secrets, err := fv.secrets()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
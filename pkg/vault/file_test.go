@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// newFileVault returns a file vault in a temporary directory that prompts with the passphrases in order
+func newFileVault(t *testing.T, passphrases ...string) *FileVault {
+	return &FileVault{
+		Path:       filepath.Join(t.TempDir(), "vault.enc"),
+		SecretName: "app.terraform.io",
+		Prompt: func(prompt string) (string, error) {
+			if len(passphrases) == 0 {
+				return "", errors.New("no terminal")
+			}
+
+			passphrase := passphrases[0]
+			passphrases = passphrases[1:]
+			return passphrase, nil
+		},
+	}
+}
+
+func TestFileVaultEncrypted(t *testing.T) {
+	ctx := context.Background()
+	fv := newFileVault(t, "first", "first")
+
+	err := fv.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(fv.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(contents, []byte("my-secret-token")) || bytes.Contains(contents, []byte("app.terraform.io")) {
+		t.Errorf("expected the file to be encrypted:\n%s", contents)
+	}
+
+	info, err := os.Stat(fv.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 && os.PathSeparator == '/' {
+		t.Errorf("expected the file mode to be 0600 got %v", info.Mode().Perm())
+	}
+
+	wrong := &FileVault{
+		Path:       fv.Path,
+		SecretName: fv.SecretName,
+		Prompt: func(prompt string) (string, error) {
+			return "wrong", nil
+		},
+	}
+
+	_, err = wrong.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected a permission error for the wrong passphrase: %v", err)
+	}
+
+	tampered := bytes.Replace(contents, []byte(`"time": 1`), []byte(`"time": 2`), 1)
+	err = os.WriteFile(fv.Path, tampered, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fv.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected the modified header to fail authentication: %v", err)
+	}
+}
+
+func TestFileVaultPromptConfirm(t *testing.T) {
+	fv := newFileVault(t, "first", "second")
+
+	err := fv.Create(context.Background(), "token", "Created")
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected the mismatched passphrases to be refused: %v", err)
+	}
+
+	err = newFileVault(t).Create(context.Background(), "token", "Created")
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected an error when no passphrase can be prompted for: %v", err)
+	}
+}
+
+func TestFileVaultMissingFile(t *testing.T) {
+	ctx := context.Background()
+	fv := &FileVault{
+		Path:       filepath.Join(t.TempDir(), "vault.enc"),
+		SecretName: "app.terraform.io",
+		Prompt: func(prompt string) (string, error) {
+			t.Errorf("expected no passphrase prompt for a file vault that doesn't exist: %s", prompt)
+			return "", errors.New("no terminal")
+		},
+	}
+
+	_, err := fv.Get(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound from Get got %v", err)
+	}
+
+	_, err = fv.List(ctx, []string{"app.terraform.io"})
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound from List got %v", err)
+	}
+
+	_, err = fv.Names(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound from Names got %v", err)
+	}
+
+	err = fv.Delete(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound from Delete got %v", err)
+	}
+}
+
+func TestFileVaultKeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	err := os.WriteFile(keyFile, []byte("from-the-key-file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fv := newFileVault(t)
+	fv.KeyFile = keyFile
+
+	passphrase, err := fv.Passphrase()
+	if err != nil || passphrase != "from-the-key-file" {
+		t.Errorf("Passphrase returned '%s', %v", passphrase, err)
+	}
+}
+
+func TestFileVaultRotate(t *testing.T) {
+	ctx := context.Background()
+	fv := newFileVault(t, "old", "old")
+
+	err := fv.Create(ctx, "token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fv.Rotate(ctx, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newFileVault(t, "new")
+	rotated.Path = fv.Path
+
+	value, err := rotated.Get(ctx)
+	if err != nil || string(value) != "token" {
+		t.Errorf("Get with the new passphrase returned '%s', %v", value, err)
+	}
+
+	old := newFileVault(t, "old")
+	old.Path = fv.Path
+
+	_, err = old.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected the old passphrase to be refused: %v", err)
+	}
+}
+
+func TestFileVaultLocked(t *testing.T) {
+	fv := newFileVault(t, "first", "first")
+
+	err := os.WriteFile(fv.Path+".lock", nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = fv.Create(ctx, "token", "Created")
+	if !errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Errorf("expected an unavailable error while the vault is locked: %v", err)
+	}
+
+	stale := time.Now().Add(-2 * fileLockAge)
+	os.Chtimes(fv.Path+".lock", stale, stale)
+
+	err = fv.Create(context.Background(), "token", "Created")
+	if err != nil {
+		t.Errorf("expected a stale lock to be removed: %v", err)
+	}
+}
@@ -7,23 +7,54 @@ import (
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
-	"github.com/tonedefdev/terracreds/pkg/helpers"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
 )
 
-var ctx = context.Background()
-
 type GCPSecretManager struct {
 	ProjectId string
 	SecretId  string
 }
 
-func (gcp *GCPSecretManager) getClient() *secretmanager.Client {
+func init() {
+	Register(&Provider{
+		Name:  "gcp",
+		Key:   "gcp",
+		Title: "Google Cloud Secret Manager",
+		Usage: "Google Cloud Provider Secrets Manager configuration settings",
+		Flags: []Flag{
+			{
+				Name:     "project-id",
+				Key:      "projectId",
+				Usage:    "The name of the GCP project where the Secrets Manager has been created",
+				Required: true,
+			},
+			{
+				Name:  "secret-id",
+				Key:   "secretId",
+				Usage: "The name of the secret identifier in GCP Secrets Manager. If omitted Terracreds will use the hostname value instead",
+			},
+		},
+		SecretNameKey: "secretId",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &GCPSecretManager{
+				ProjectId: config.String("projectId"),
+				SecretId:  secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+func (gcp *GCPSecretManager) getClient(ctx context.Context) (*secretmanager.Client, error) {
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
-		helpers.CheckError(err)
+		return nil, err
 	}
-	return client
+	return client, nil
 }
 
 // formatSecretName replaces the periods from the hostname with dashes
@@ -33,8 +64,27 @@ func formatGcpSecretName(secretName string) string {
 	return hostname
 }
 
-func (gcp *GCPSecretManager) Create(secretValue string, method string) error {
-	client := gcp.getClient()
+// gcpError converts a Secret Manager status into the kind of failure it represents
+func gcpError(err error, secretId string) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return wrapError(terraerrors.ErrNotFound, err, secretId)
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return wrapError(terraerrors.ErrPermissionDenied, err, secretId)
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return wrapError(terraerrors.ErrConflict, err, secretId)
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return wrapError(terraerrors.ErrUnavailable, err, secretId)
+	}
+
+	return transportError(err, secretId)
+}
+
+func (gcp *GCPSecretManager) Create(ctx context.Context, secretValue string, method string) error {
+	client, err := gcp.getClient(ctx)
+	if err != nil {
+		return err
+	}
 	defer client.Close()
 
 	secretId := formatGcpSecretName(gcp.SecretId)
@@ -43,6 +93,10 @@ func (gcp *GCPSecretManager) Create(secretValue string, method string) error {
 	}
 
 	get, err := client.GetSecret(ctx, accessRequest)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return gcpError(err, secretId)
+	}
+
 	if err == nil {
 		payload := []byte(secretValue)
 		addSecretVersionReq := &secretmanagerpb.AddSecretVersionRequest{
@@ -54,7 +108,7 @@ func (gcp *GCPSecretManager) Create(secretValue string, method string) error {
 
 		_, err = client.AddSecretVersion(ctx, addSecretVersionReq)
 		if err != nil {
-			return err
+			return gcpError(err, secretId)
 		}
 
 		return err
@@ -74,7 +128,7 @@ func (gcp *GCPSecretManager) Create(secretValue string, method string) error {
 
 	secret, err := client.CreateSecret(ctx, createSecretReq)
 	if err != nil {
-		return err
+		return gcpError(err, secretId)
 	}
 
 	payload := []byte(secretValue)
@@ -87,14 +141,17 @@ func (gcp *GCPSecretManager) Create(secretValue string, method string) error {
 
 	_, err = client.AddSecretVersion(ctx, addSecretVersionReq)
 	if err != nil {
-		return err
+		return gcpError(err, secretId)
 	}
 
 	return err
 }
 
-func (gcp *GCPSecretManager) Delete() error {
-	client := gcp.getClient()
+func (gcp *GCPSecretManager) Delete(ctx context.Context) error {
+	client, err := gcp.getClient(ctx)
+	if err != nil {
+		return err
+	}
 	defer client.Close()
 
 	secretId := formatGcpSecretName(gcp.SecretId)
@@ -105,7 +162,7 @@ func (gcp *GCPSecretManager) Delete() error {
 
 	result, err := client.AccessSecretVersion(ctx, accessRequest)
 	if err != nil {
-		return err
+		return gcpError(err, secretId)
 	}
 
 	destroySecretVersionReq := secretmanagerpb.DestroySecretVersionRequest{
@@ -114,14 +171,17 @@ func (gcp *GCPSecretManager) Delete() error {
 
 	_, err = client.DestroySecretVersion(ctx, &destroySecretVersionReq)
 	if err != nil {
-		return err
+		return gcpError(err, secretId)
 	}
 
 	return err
 }
 
-func (gcp *GCPSecretManager) Get() ([]byte, error) {
-	client := gcp.getClient()
+func (gcp *GCPSecretManager) Get(ctx context.Context) ([]byte, error) {
+	client, err := gcp.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer client.Close()
 
 	secretId := formatGcpSecretName(gcp.SecretId)
@@ -131,15 +191,18 @@ func (gcp *GCPSecretManager) Get() ([]byte, error) {
 
 	result, err := client.AccessSecretVersion(ctx, accessRequest)
 	if err != nil {
-		return nil, err
+		return nil, gcpError(err, secretId)
 	}
 
 	return result.Payload.Data, err
 }
 
-func (gcp *GCPSecretManager) List(secretNames []string) ([]string, error) {
+func (gcp *GCPSecretManager) List(ctx context.Context, secretNames []string) ([]string, error) {
 	var secretValues []string
-	client := gcp.getClient()
+	client, err := gcp.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer client.Close()
 
 	for _, secret := range secretNames {
@@ -150,7 +213,7 @@ func (gcp *GCPSecretManager) List(secretNames []string) ([]string, error) {
 
 		result, err := client.AccessSecretVersion(ctx, accessRequest)
 		if err != nil {
-			return nil, err
+			return nil, gcpError(err, secretId)
 		}
 
 		secretValues = append(secretValues, string(result.Payload.Data))
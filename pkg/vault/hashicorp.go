@@ -1,109 +1,742 @@
 package vault
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"sort"
+	"strings"
 
 	hcvault "github.com/hashicorp/vault/api"
-	"github.com/tonedefdev/terracreds/pkg/helpers"
+	"github.com/mitchellh/go-homedir"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
 )
 
+const (
+	// DefaultKubernetesTokenFile is the service account token that the Kubernetes auth method
+	// logs in with when no JWT is configured
+	DefaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// hashiWriteAttempts is the most times a write is tried when other clients change the secret
+	hashiWriteAttempts = 5
+)
+
+// HashiVault stores secrets as the keys of a KV secret in HashiCorp Vault. The client
+// logs in with the configured auth method once, and its token is reused by every request
 type HashiVault struct {
-	EnvTokenName string
-	KeyVaultPath string
-	SecretName   string
-	SecretPath   string
-	VaultUri     string
+	AuthMethod         string
+	AuthMount          string
+	AuthRole           string
+	CACert             string
+	ClientCert         string
+	ClientKey          string
+	EnvTokenName       string
+	InsecureSkipVerify bool
+	JWTEnv             string
+	JWTFile            string
+	KeyVaultPath       string
+	KVVersion          string
+	Namespace          string
+	PasswordEnv        string
+	RoleID             string
+	RoleIDFile         string
+	SecretIDEnv        string
+	SecretIDFile       string
+	SecretName         string
+	SecretPath         string
+	TLSServerName      string
+	TokenFile          string
+	Username           string
+	VaultUri           string
+
+	client  *hcvault.Client
+	mount   string
+	version string
+}
+
+// hashiClientFlags are the settings of the connection to Vault and the auth method that the
+// providers of HashiCorp Vault share
+var hashiClientFlags = []Flag{
+	{
+		Name:  "auth-method",
+		Key:   "authMethod",
+		Usage: "The auth method to log in with: 'token', 'approle', 'jwt', 'kubernetes', 'cert' or 'userpass'. If omitted 'token' is used",
+	},
+	{
+		Name:  "auth-mount",
+		Key:   "authMount",
+		Usage: "The path the auth method is mounted at. If omitted the name of the auth method is used",
+	},
+	{
+		Name:  "auth-role",
+		Key:   "authRole",
+		Usage: "The role to log in as with the 'jwt' and 'kubernetes' auth methods, or the name of the certificate role with 'cert'",
+	},
+	{
+		Name:  "ca-cert",
+		Key:   "caCert",
+		Usage: "The path to the PEM encoded CA certificate that verifies the certificate of Vault. If omitted 'VAULT_CACERT' is used",
+	},
+	{
+		Name:  "client-cert",
+		Key:   "clientCert",
+		Usage: "The path to the PEM encoded client certificate that's presented to Vault such as for the 'cert' auth method. If omitted 'VAULT_CLIENT_CERT' is used",
+	},
+	{
+		Name:  "client-key",
+		Key:   "clientKey",
+		Usage: "The path to the PEM encoded private key of the client certificate. If omitted 'VAULT_CLIENT_KEY' is used",
+	},
+	{
+		Name:  "environment-token-name",
+		Key:   "environmentTokenName",
+		Usage: "The name of the environment variable that currently holds the Vault token for the 'token' auth method. If omitted or empty 'VAULT_TOKEN' is used",
+	},
+	{
+		Name:  "insecure-skip-verify",
+		Key:   "insecureSkipVerify",
+		Usage: "Skip the verification of the certificate of Vault",
+		Bool:  true,
+	},
+	{
+		Name:  "jwt-env",
+		Key:   "jwtEnv",
+		Usage: "The name of the environment variable that holds the JWT for the 'jwt' or 'kubernetes' auth method such as a CI ID token",
+	},
+	{
+		Name:  "jwt-file",
+		Key:   "jwtFile",
+		Usage: fmt.Sprintf("The path to the file that holds the JWT for the 'jwt' or 'kubernetes' auth method. The 'kubernetes' auth method uses '%s' if neither is set", DefaultKubernetesTokenFile),
+	},
+	{
+		Name:  "namespace",
+		Key:   "namespace",
+		Usage: "The Vault Enterprise namespace of the mount and the auth method. If omitted 'VAULT_NAMESPACE' is used",
+	},
+	{
+		Name:  "password-env",
+		Key:   "passwordEnv",
+		Usage: "The name of the environment variable that holds the password for the 'userpass' auth method",
+	},
+	{
+		Name:  "role-id",
+		Key:   "roleId",
+		Usage: "The role ID for the 'approle' auth method",
+	},
+	{
+		Name:  "role-id-file",
+		Key:   "roleIdFile",
+		Usage: "The path to the file that holds the role ID for the 'approle' auth method",
+	},
+	{
+		Name:  "secret-id-env",
+		Key:   "secretIdEnv",
+		Usage: "The name of the environment variable that holds the secret ID for the 'approle' auth method",
+	},
+	{
+		Name:  "secret-id-file",
+		Key:   "secretIdFile",
+		Usage: "The path to the file that holds the secret ID for the 'approle' auth method",
+	},
+	{
+		Name:  "tls-server-name",
+		Key:   "tlsServerName",
+		Usage: "The name that's used to verify the certificate of Vault. If omitted 'VAULT_TLS_SERVER_NAME' or the host of the URL is used",
+	},
+	{
+		Name:  "token-file",
+		Key:   "tokenFile",
+		Usage: "The path to the file that holds the Vault token for the 'token' auth method such as the sink of a Vault Agent. It takes precedence over the environment variable",
+	},
+	{
+		Name:  "username",
+		Key:   "username",
+		Usage: "The username for the 'userpass' auth method",
+	},
+	{
+		Name:  "vault-uri",
+		Key:   "vaultUri",
+		Usage: "The URL of the Vault instance including its port. If omitted 'VAULT_ADDR' is used",
+	},
+}
+
+// hashiFlags returns the flags of a provider of HashiCorp Vault sorted by name, including the
+// settings of the connection to Vault
+func hashiFlags(flags ...Flag) []Flag {
+	flags = append(flags, hashiClientFlags...)
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+
+	return flags
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "hashicorp",
+		Key:   "hcvault",
+		Title: "HashiCorp Vault",
+		Usage: "HashiCorp Vault provider configuration settings",
+		Flags: hashiFlags(
+			Flag{
+				Name:     "key-vault-path",
+				Key:      "keyVaultPath",
+				Usage:    "The name of the Key Vault store inside of Vault",
+				Required: true,
+			},
+			Flag{
+				Name:  "kv-version",
+				Key:   "kvVersion",
+				Usage: "The version of the KV secrets engine of the mount, '1' or '2'. If omitted the version is detected",
+			},
+			Flag{
+				Name:  "secret-name",
+				Key:   "secretName",
+				Usage: "The name of the secret stored inside of Vault. If omitted Terracreds will use the hostname value instead",
+			},
+			Flag{
+				Name:     "secret-path",
+				Key:      "secretPath",
+				Usage:    "The path of the secret itself inside of the vault",
+				Required: true,
+			},
+		),
+		SecretNameKey: "secretName",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := newHashiVault(config)
+			vault.KeyVaultPath = config.String("keyVaultPath")
+			vault.KVVersion = config.String("kvVersion")
+			vault.SecretName = secretName
+			vault.SecretPath = config.String("secretPath")
+
+			return vault, nil
+		},
+	})
 }
 
-func (hc *HashiVault) newHashiVaultClient() *hcvault.Client {
+// newHashiVault returns a HashiVault with the settings of the connection to Vault and the auth
+// method from the configuration block
+func newHashiVault(config ProviderConfig) *HashiVault {
+	return &HashiVault{
+		AuthMethod:         config.String("authMethod"),
+		AuthMount:          config.String("authMount"),
+		AuthRole:           config.String("authRole"),
+		CACert:             config.String("caCert"),
+		ClientCert:         config.String("clientCert"),
+		ClientKey:          config.String("clientKey"),
+		EnvTokenName:       config.String("environmentTokenName"),
+		InsecureSkipVerify: config.Bool("insecureSkipVerify"),
+		JWTEnv:             config.String("jwtEnv"),
+		JWTFile:            config.String("jwtFile"),
+		Namespace:          config.String("namespace"),
+		PasswordEnv:        config.String("passwordEnv"),
+		RoleID:             config.String("roleId"),
+		RoleIDFile:         config.String("roleIdFile"),
+		SecretIDEnv:        config.String("secretIdEnv"),
+		SecretIDFile:       config.String("secretIdFile"),
+		TLSServerName:      config.String("tlsServerName"),
+		TokenFile:          config.String("tokenFile"),
+		Username:           config.String("username"),
+		VaultUri:           config.String("vaultUri"),
+	}
+}
+
+// newHashiVaultClient returns a client that's logged in with the auth method. The client is
+// created once, so every request of the invocation reuses the token of the first login. The
+// settings of the configuration take precedence over the VAULT_* environment variables
+func (hc *HashiVault) newHashiVaultClient(ctx context.Context) (*hcvault.Client, error) {
+	if hc.client != nil {
+		return hc.client, nil
+	}
+
 	config := hcvault.DefaultConfig()
-	config.Address = hc.VaultUri
+	if config.Error != nil {
+		return nil, fmt.Errorf("the VAULT_* environment variables are not valid: %w", config.Error)
+	}
+
+	if hc.VaultUri != "" {
+		config.Address = hc.VaultUri
+	} else if os.Getenv(hcvault.EnvVaultAddress) == "" {
+		return nil, fmt.Errorf("the address of Vault must be set with 'vaultUri' or the '%s' environment variable", hcvault.EnvVaultAddress)
+	}
+
+	var paths []string
+	for _, path := range []string{hc.CACert, hc.ClientCert, hc.ClientKey} {
+		path, err := homedir.Expand(path)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	err := config.ConfigureTLS(&hcvault.TLSConfig{
+		CACert:        paths[0],
+		ClientCert:    paths[1],
+		ClientKey:     paths[2],
+		TLSServerName: hc.TLSServerName,
+		Insecure:      hc.InsecureSkipVerify,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure TLS for Vault: %w", err)
+	}
 
 	client, err := hcvault.NewClient(config)
 	if err != nil {
-		helpers.CheckError(err)
+		return nil, err
 	}
 
-	client.SetToken(os.Getenv(hc.EnvTokenName))
+	if hc.Namespace != "" {
+		client.SetNamespace(hc.Namespace)
+	}
 
-	return client
+	token, err := hc.login(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	client.SetToken(token)
+	hc.client = client
+
+	return client, nil
+}
+
+// credential reads a credential from the file, or from the environment variable when no file
+// is set, and returns an error that names the setting when neither holds a value
+func credential(description string, file string, env string) (string, error) {
+	if file != "" {
+		path, err := homedir.Expand(file)
+		if err != nil {
+			return "", err
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%w: unable to read the %s: %w", terraerrors.ErrPermissionDenied, description, err)
+		}
+
+		return strings.TrimSpace(string(value)), nil
+	}
+
+	if env == "" {
+		return "", fmt.Errorf("%w: no file or environment variable holds the %s", terraerrors.ErrPermissionDenied, description)
+	}
+
+	value := os.Getenv(env)
+	if value == "" {
+		return "", fmt.Errorf("%w: the environment variable '%s' doesn't hold the %s", terraerrors.ErrPermissionDenied, env, description)
+	}
+
+	return value, nil
 }
 
-func (hc *HashiVault) Create(secretValue string, method string) error {
-	client := hc.newHashiVaultClient()
-	secret := make(map[string]interface{})
+// login returns the token of the auth method. The 'token' auth method reads the token as-is,
+// and the other methods exchange their credentials for a token
+func (hc *HashiVault) login(ctx context.Context, client *hcvault.Client) (string, error) {
+	method := hc.AuthMethod
+	if method == "" {
+		method = "token"
+	}
 
-	key := hc.SecretName
+	mount := hc.AuthMount
+	if mount == "" {
+		mount = method
+	}
+
+	path := fmt.Sprintf("auth/%s/login", strings.Trim(mount, "/"))
 	data := make(map[string]interface{})
-	data[key] = secretValue
-	secret["data"] = data
 
-	kvPath := fmt.Sprintf("%s/data/%s", hc.KeyVaultPath, hc.SecretPath)
-	_, err := client.Logical().Write(kvPath, secret)
+	switch method {
+	case "token":
+		if hc.TokenFile != "" {
+			return credential("Vault token", hc.TokenFile, "")
+		}
+
+		if token := os.Getenv(hc.EnvTokenName); hc.EnvTokenName != "" && token != "" {
+			return token, nil
+		}
+
+		return os.Getenv(hcvault.EnvVaultToken), nil
+	case "approle":
+		roleID := hc.RoleID
+		if roleID == "" {
+			var err error
+			roleID, err = credential("role ID", hc.RoleIDFile, "")
+			if err != nil {
+				return "", err
+			}
+		}
+
+		data["role_id"] = roleID
+		if hc.SecretIDFile != "" || hc.SecretIDEnv != "" {
+			secretID, err := credential("secret ID", hc.SecretIDFile, hc.SecretIDEnv)
+			if err != nil {
+				return "", err
+			}
+
+			data["secret_id"] = secretID
+		}
+	case "jwt", "kubernetes":
+		file := hc.JWTFile
+		if method == "kubernetes" && file == "" && hc.JWTEnv == "" {
+			file = DefaultKubernetesTokenFile
+		}
+
+		jwt, err := credential("JWT", file, hc.JWTEnv)
+		if err != nil {
+			return "", err
+		}
+
+		data["jwt"] = jwt
+		if hc.AuthRole != "" {
+			data["role"] = hc.AuthRole
+		}
+	case "cert":
+		if hc.ClientCert == "" && os.Getenv(hcvault.EnvVaultClientCert) == "" {
+			return "", fmt.Errorf("the 'cert' auth method needs a client certificate and key")
+		}
+
+		if hc.AuthRole != "" {
+			data["name"] = hc.AuthRole
+		}
+	case "userpass":
+		if hc.Username == "" {
+			return "", fmt.Errorf("the 'userpass' auth method needs a username")
+		}
+
+		password, err := credential("password", "", hc.PasswordEnv)
+		if err != nil {
+			return "", err
+		}
+
+		path = fmt.Sprintf("%s/%s", path, hc.Username)
+		data["password"] = password
+	default:
+		return "", fmt.Errorf("the Vault auth method '%s' is not supported, use 'token', 'approle', 'jwt', 'kubernetes', 'cert' or 'userpass'", method)
+	}
+
+	secret, err := hc.request(ctx, client, http.MethodPut, path, data)
 	if err != nil {
-		return err
+		return "", loginError(err)
+	}
+
+	if secret == nil || secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return "", fmt.Errorf("%w: the Vault login at '%s' didn't return a token", terraerrors.ErrPermissionDenied, path)
+	}
+
+	return secret.Auth.ClientToken, nil
+}
+
+// loginError reports a login that Vault rejected as a permission error, since Vault answers
+// invalid credentials with a bad request
+func loginError(err error) error {
+	var respErr *hcvault.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusBadRequest {
+		return fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
 	}
 
 	return err
 }
 
-func (hc *HashiVault) Delete() error {
-	client := hc.newHashiVaultClient()
+// hashiError converts a Vault error into the kind of failure it represents
+func hashiError(err error, kvPath string) error {
+	var respErr *hcvault.ResponseError
+	if errors.As(err, &respErr) {
+		return statusError(respErr.StatusCode, err, kvPath)
+	}
+
+	return transportError(err, kvPath)
+}
+
+// request sends a request to the Vault API that is canceled with the context. A nil secret
+// is returned when the path doesn't exist
+func (hc *HashiVault) request(ctx context.Context, client *hcvault.Client, method string, kvPath string, data map[string]interface{}) (*hcvault.Secret, error) {
+	r := client.NewRequest(method, "/v1/"+kvPath)
+	if data != nil {
+		if err := r.SetJSONBody(data); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := client.RawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
 
-	kvPath := fmt.Sprintf("%s/data/%s", hc.KeyVaultPath, hc.SecretPath)
-	_, err := client.Logical().Delete(kvPath)
 	if err != nil {
-		return err
+		return nil, hashiError(err, kvPath)
 	}
 
-	return err
+	secret, err := hcvault.ParseSecret(resp.Body)
+	if err == io.EOF {
+		return nil, nil
+	}
+
+	return secret, err
 }
 
-func (hc *HashiVault) Get() ([]byte, error) {
-	client := hc.newHashiVaultClient()
+// kvPath returns the path of the secret in the API of the KV secrets engine. Unless the
+// version is configured, the mount and its version are read from the preflight endpoint that
+// the Vault CLI uses, and a Vault that doesn't answer it is assumed to use KV version 2
+func (hc *HashiVault) kvPath(ctx context.Context, client *hcvault.Client) (string, error) {
+	if hc.mount == "" {
+		mount := strings.Trim(hc.KeyVaultPath, "/")
+		version := hc.KVVersion
+		switch version {
+		case "1", "2":
+		case "":
+			version = "2"
+			preflight, err := hc.request(ctx, client, http.MethodGet, fmt.Sprintf("sys/internal/ui/mounts/%s/%s", mount, hc.SecretPath), nil)
+			if errors.Is(err, terraerrors.ErrUnavailable) {
+				return "", err
+			}
 
-	kvPath := fmt.Sprintf("%s/data/%s", hc.KeyVaultPath, hc.SecretPath)
-	secret, err := client.Logical().Read(kvPath)
-	if secret == nil {
-		return nil, errors.New("no secret. need to create")
+			if err == nil && preflight != nil {
+				if path, ok := preflight.Data["path"].(string); ok && path != "" {
+					mount = strings.Trim(path, "/")
+				}
+
+				version = "1"
+				if options, ok := preflight.Data["options"].(map[string]interface{}); ok && options["version"] == "2" {
+					version = "2"
+				}
+			}
+		default:
+			return "", fmt.Errorf("the KV version '%s' is not supported, use '1' or '2'", hc.KVVersion)
+		}
+
+		hc.mount = mount
+		hc.version = version
+	}
+
+	if hc.version == "1" {
+		return fmt.Sprintf("%s/%s", hc.mount, hc.relativePath()), nil
+	}
+
+	return hc.endpointPath("data"), nil
+}
+
+// relativePath returns the path of the secret below its mount
+func (hc *HashiVault) relativePath() string {
+	return strings.TrimPrefix(strings.Trim(hc.KeyVaultPath, "/")+"/"+hc.SecretPath, hc.mount+"/")
+}
+
+// endpointPath returns the path of the secret at an endpoint of a KV version 2 mount such as 'metadata'
+func (hc *HashiVault) endpointPath(endpoint string) string {
+	return fmt.Sprintf("%s/%s/%s", hc.mount, endpoint, hc.relativePath())
+}
+
+// currentVersion returns the current version of a KV version 2 secret from its metadata, which
+// is kept when the latest version is deleted. A secret that never existed has version 0
+func (hc *HashiVault) currentVersion(ctx context.Context, client *hcvault.Client) (int, error) {
+	metadata, err := hc.request(ctx, client, http.MethodGet, hc.endpointPath("metadata"), nil)
+	if err != nil || metadata == nil {
+		return 0, err
+	}
+
+	return jsonInt(metadata.Data["current_version"])
+}
+
+// jsonInt returns the number that Vault returned in a JSON document, or 0 when it's missing
+func jsonInt(value interface{}) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("number type assertion failed: %T %#v", value, value)
+	}
+
+	n, err := number.Int64()
+	return int(n), err
+}
+
+// read returns the data of the secret at the path and its version. KV version 1 secrets don't
+// have versions, so their version is always 0
+func (hc *HashiVault) read(ctx context.Context, client *hcvault.Client, kvPath string) (map[string]interface{}, int, error) {
+	secret, err := hc.request(ctx, client, http.MethodGet, kvPath, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return nil, 0, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, kvPath)
+	}
+
+	if hc.version == "1" {
+		return secret.Data, 0, nil
+	}
+
+	if secret.Data["data"] == nil {
+		return nil, 0, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, kvPath)
 	}
 
 	data, ok := secret.Data["data"].(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("data type assertion failed: %T %#v", secret.Data["data"], secret.Data["data"])
+		return nil, 0, fmt.Errorf("data type assertion failed: %T %#v", secret.Data["data"], secret.Data["data"])
+	}
+
+	metadata, _ := secret.Data["metadata"].(map[string]interface{})
+	version, err := jsonInt(metadata["version"])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return data, version, nil
+}
+
+// casConflict reports whether Vault rejected a write because the secret changed after it was read
+func casConflict(err error) bool {
+	var respErr *hcvault.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusBadRequest && strings.Contains(strings.Join(respErr.Errors, " "), "check-and-set")
+}
+
+// update changes the keys of the secret without losing the keys it doesn't change. KV version 2
+// secrets are written with the version they were read at as the check-and-set parameter, and
+// the change is applied again to the current secret when another client wrote it in between.
+// The secret is deleted when the change removes its last key
+func (hc *HashiVault) update(ctx context.Context, change func(data map[string]interface{}) error) error {
+	client, err := hc.newHashiVaultClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	kvPath, err := hc.kvPath(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		data, version, err := hc.read(ctx, client, kvPath)
+		if terraerrors.IsNotFound(err) && hc.version == "2" {
+			data = make(map[string]interface{})
+			version, err = hc.currentVersion(ctx, client)
+		} else if terraerrors.IsNotFound(err) {
+			data, err = make(map[string]interface{}), nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = change(data)
+		if err != nil {
+			return err
+		}
+
+		if len(data) == 0 {
+			_, err = hc.request(ctx, client, http.MethodDelete, kvPath, nil)
+			return err
+		}
+
+		body := data
+		if hc.version == "2" {
+			body = map[string]interface{}{
+				"data":    data,
+				"options": map[string]interface{}{"cas": version},
+			}
+		}
+
+		_, err = hc.request(ctx, client, http.MethodPut, kvPath, body)
+		if !casConflict(err) {
+			return err
+		}
+
+		if attempt == hashiWriteAttempts {
+			return fmt.Errorf("%w: %s was changed by another client %d times while it was written: %w", terraerrors.ErrConflict, kvPath, attempt, err)
+		}
+	}
+}
+
+// kvValue returns the value of the key in the data of the secret
+func kvValue(data map[string]interface{}, kvPath string, key string) (string, error) {
+	if _, ok := data[key]; !ok {
+		return "", fmt.Errorf("%w: %s/%s", terraerrors.ErrNotFound, kvPath, key)
 	}
 
-	key := hc.SecretName
 	value, ok := data[key].(string)
 	if !ok {
-		return nil, fmt.Errorf("value type assertion failed: %T %#v", data[key], data[key])
+		return "", fmt.Errorf("value type assertion failed: %T %#v", data[key], data[key])
+	}
+
+	return value, nil
+}
+
+// Create stores the secret as a key of the secret at the secret path, and keeps its other keys
+func (hc *HashiVault) Create(ctx context.Context, secretValue string, method string) error {
+	return hc.update(ctx, func(data map[string]interface{}) error {
+		data[hc.SecretName] = secretValue
+		return nil
+	})
+}
+
+// Delete removes the secret's key from the secret at the secret path, and keeps its other keys
+func (hc *HashiVault) Delete(ctx context.Context) error {
+	return hc.update(ctx, func(data map[string]interface{}) error {
+		if _, ok := data[hc.SecretName]; !ok {
+			return fmt.Errorf("%w: %s/%s", terraerrors.ErrNotFound, hc.SecretPath, hc.SecretName)
+		}
+
+		delete(data, hc.SecretName)
+		return nil
+	})
+}
+
+func (hc *HashiVault) Get(ctx context.Context) ([]byte, error) {
+	client, err := hc.newHashiVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kvPath, err := hc.kvPath(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := hc.read(ctx, client, kvPath)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := kvValue(data, kvPath, hc.SecretName)
+	if err != nil {
+		return nil, err
 	}
 
 	return []byte(value), err
 }
 
-func (hc *HashiVault) List(secretNames []string) ([]string, error) {
+func (hc *HashiVault) List(ctx context.Context, secretNames []string) ([]string, error) {
 	var secretValues []string
-	client := hc.newHashiVaultClient()
+	client, err := hc.newHashiVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	kvPath := fmt.Sprintf("%s/data/%s", hc.KeyVaultPath, hc.SecretPath)
-	secret, err := client.Logical().Read(kvPath)
-	if secret == nil {
+	kvPath, err := hc.kvPath(ctx, client)
+	if err != nil {
 		return nil, err
 	}
 
-	data, ok := secret.Data["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("data type assertion failed: %T %#v", secret.Data["data"], secret.Data["data"])
+	data, _, err := hc.read(ctx, client, kvPath)
+	if err != nil {
+		return nil, err
 	}
 
 	for _, secret := range secretNames {
-		key := secret
-		value, ok := data[key].(string)
-		if !ok {
-			return nil, fmt.Errorf("value type assertion failed: %T %#v", data[key], data[key])
+		value, err := kvValue(data, kvPath, secret)
+		if err != nil {
+			return nil, err
 		}
 
 		secretValues = append(secretValues, value)
@@ -111,3 +744,118 @@ func (hc *HashiVault) List(secretNames []string) ([]string, error) {
 
 	return secretValues, nil
 }
+
+// Names returns the keys of the secret at the secret path
+func (hc *HashiVault) Names(ctx context.Context) ([]string, error) {
+	client, err := hc.newHashiVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kvPath, err := hc.kvPath(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	data, _, err := hc.read(ctx, client, kvPath)
+	if terraerrors.IsNotFound(err) {
+		return names, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range data {
+		names = append(names, key)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// versioned returns the client when the secret is stored on a KV version 2 mount, since KV
+// version 1 secrets don't keep versions
+func (hc *HashiVault) versioned(ctx context.Context) (*hcvault.Client, error) {
+	client, err := hc.newHashiVaultClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kvPath, err := hc.kvPath(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if hc.version != "2" {
+		return nil, fmt.Errorf("%s is stored on a KV version 1 mount, which doesn't keep the versions of secrets", kvPath)
+	}
+
+	return client, nil
+}
+
+// lifecycle sends the versions to an endpoint of the KV version 2 mount such as 'undelete'.
+// The current version of the secret is sent when no versions are given
+func (hc *HashiVault) lifecycle(ctx context.Context, method string, endpoint string, versions []int) error {
+	client, err := hc.versioned(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		current, err := hc.currentVersion(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		if current == 0 {
+			return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, hc.endpointPath("metadata"))
+		}
+
+		versions = []int{current}
+	}
+
+	_, err = hc.request(ctx, client, method, hc.endpointPath(endpoint), map[string]interface{}{
+		"versions": versions,
+	})
+
+	return err
+}
+
+// SoftDelete deletes the versions of the secret at the secret path, including its other keys.
+// The data of the versions is kept until they're destroyed, so they can be undeleted
+func (hc *HashiVault) SoftDelete(ctx context.Context, versions []int) error {
+	return hc.lifecycle(ctx, http.MethodPost, "delete", versions)
+}
+
+// Undelete restores the soft deleted versions of the secret at the secret path
+func (hc *HashiVault) Undelete(ctx context.Context, versions []int) error {
+	return hc.lifecycle(ctx, http.MethodPost, "undelete", versions)
+}
+
+// Destroy permanently removes the data of the versions of the secret at the secret path
+func (hc *HashiVault) Destroy(ctx context.Context, versions []int) error {
+	return hc.lifecycle(ctx, http.MethodPut, "destroy", versions)
+}
+
+// DeleteMetadata permanently removes every version of the secret at the secret path and its
+// metadata, including the keys of other secrets
+func (hc *HashiVault) DeleteMetadata(ctx context.Context) error {
+	client, err := hc.versioned(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := hc.currentVersion(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if current == 0 {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, hc.endpointPath("metadata"))
+	}
+
+	_, err = hc.request(ctx, client, http.MethodDelete, hc.endpointPath("metadata"), nil)
+	return err
+}
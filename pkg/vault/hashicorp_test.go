@@ -0,0 +1,543 @@
+package vault_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+// writeFile writes the contents to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, contents []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, contents, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// writeClientCert writes a self-signed client certificate and its key, and returns their paths
+func writeClientCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terracreds"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := writeFile(t, "client.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}))
+	keyFile := writeFile(t, "client-key.pem", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	return certFile, keyFile
+}
+
+func TestHashiCorpAuthMethods(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_SECRET_ID", "secret-id")
+	t.Setenv("TC_TEST_CI_JWT", "ci-jwt")
+	t.Setenv("TC_TEST_PASSWORD", "hunter2")
+
+	tests := []struct {
+		name   string
+		login  vaulttest.VaultLogin
+		config vault.ProviderConfig
+	}{
+		{
+			name:   "approle",
+			login:  vaulttest.VaultLogin{Path: "auth/approle/login", Data: map[string]interface{}{"role_id": "role-id", "secret_id": "secret-id"}},
+			config: vault.ProviderConfig{"authMethod": "approle", "roleId": "role-id", "secretIdEnv": "TC_TEST_SECRET_ID"},
+		},
+		{
+			name:   "approle files",
+			login:  vaulttest.VaultLogin{Path: "auth/ci-approle/login", Data: map[string]interface{}{"role_id": "role-id", "secret_id": "secret-id"}},
+			config: vault.ProviderConfig{"authMethod": "approle", "authMount": "ci-approle", "roleIdFile": writeFile(t, "role-id", []byte("role-id\n")), "secretIdFile": writeFile(t, "secret-id", []byte("secret-id\n"))},
+		},
+		{
+			name:   "jwt",
+			login:  vaulttest.VaultLogin{Path: "auth/jwt/login", Data: map[string]interface{}{"jwt": "ci-jwt", "role": "terraform"}},
+			config: vault.ProviderConfig{"authMethod": "jwt", "authRole": "terraform", "jwtEnv": "TC_TEST_CI_JWT"},
+		},
+		{
+			name:   "kubernetes",
+			login:  vaulttest.VaultLogin{Path: "auth/k8s/login", Data: map[string]interface{}{"jwt": "service-account-jwt", "role": "terraform"}},
+			config: vault.ProviderConfig{"authMethod": "kubernetes", "authMount": "k8s", "authRole": "terraform", "jwtFile": writeFile(t, "token", []byte("service-account-jwt"))},
+		},
+		{
+			name:   "userpass",
+			login:  vaulttest.VaultLogin{Path: "auth/userpass/login/terraform", Data: map[string]interface{}{"password": "hunter2"}},
+			config: vault.ProviderConfig{"authMethod": "userpass", "username": "terraform", "passwordEnv": "TC_TEST_PASSWORD"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := vaulttest.NewVaultKV(t, "root-token")
+			fake.AddLogin(test.login)
+
+			test.config["keyVaultPath"] = "kv"
+			test.config["secretPath"] = "tfe"
+			test.config["vaultUri"] = fake.URL
+
+			terraVault := newVault(t, "hashicorp", test.config, "app.terraform.io")
+			err := terraVault.Create(ctx, "my-secret-token", "Created")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := terraVault.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(value) != "my-secret-token" {
+				t.Errorf("expected the value got '%s'", value)
+			}
+
+			if fake.Logins() != 1 {
+				t.Errorf("expected the token to be reused got %d logins", fake.Logins())
+			}
+
+			test.config["vaultUri"] = vaulttest.NewVaultKV(t, "root-token").URL
+			_, err = newVault(t, "hashicorp", test.config, "app.terraform.io").Get(ctx)
+			if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+				t.Errorf("expected ErrPermissionDenied for rejected credentials got %v", err)
+			}
+		})
+	}
+}
+
+func TestHashiCorpTokenFile(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "wrong-token")
+
+	fake := vaulttest.NewVaultKV(t, "agent-token")
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "tfe",
+		"tokenFile":            writeFile(t, "sink", []byte("agent-token\n")),
+		"vaultUri":             fake.URL,
+	}
+
+	err := newVault(t, "hashicorp", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config["tokenFile"] = filepath.Join(t.TempDir(), "missing")
+	_, err = newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied for a missing token file got %v", err)
+	}
+
+	config["authMethod"] = "ldap"
+	_, err = newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err == nil {
+		t.Errorf("expected an error for an unsupported auth method")
+	}
+}
+
+func TestHashiCorpCertAuth(t *testing.T) {
+	ctx := context.Background()
+
+	fake := vaulttest.NewVaultKVTLS(t, "root-token")
+	fake.AddLogin(vaulttest.VaultLogin{Path: "auth/cert/login", Data: map[string]interface{}{"name": "terraform"}, ClientCert: true})
+	t.Setenv("VAULT_CACERT", writeFile(t, "ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: fake.Certificate().Raw})))
+
+	clientCert, clientKey := writeClientCert(t)
+	config := vault.ProviderConfig{
+		"authMethod":   "cert",
+		"authRole":     "terraform",
+		"clientCert":   clientCert,
+		"clientKey":    clientKey,
+		"keyVaultPath": "kv",
+		"secretPath":   "tfe",
+		"vaultUri":     fake.URL,
+	}
+
+	err := newVault(t, "hashicorp", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.Logins() != 1 {
+		t.Errorf("expected a single login got %d", fake.Logins())
+	}
+
+	delete(config, "clientCert")
+	delete(config, "clientKey")
+	_, err = newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err == nil {
+		t.Errorf("expected the 'cert' auth method to need a client certificate")
+	}
+}
+
+func TestHashiCorpKVVersions(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.Mount("legacy", 1)
+	fake.Mount("secret", 2)
+
+	tests := []struct {
+		name   string
+		config vault.ProviderConfig
+		stored string
+	}{
+		{name: "detected version 1", config: vault.ProviderConfig{"keyVaultPath": "legacy"}, stored: "legacy/tfe"},
+		{name: "configured version 1", config: vault.ProviderConfig{"keyVaultPath": "legacy", "kvVersion": "1"}, stored: "legacy/tfe"},
+		{name: "detected version 2", config: vault.ProviderConfig{"keyVaultPath": "secret"}, stored: "secret/tfe"},
+		{name: "path below the mount", config: vault.ProviderConfig{"keyVaultPath": "secret/team"}, stored: "secret/team/tfe"},
+		{name: "undetected", config: vault.ProviderConfig{"keyVaultPath": "kv"}, stored: "kv/tfe"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config["environmentTokenName"] = "TC_TEST_VAULT_TOKEN"
+			test.config["secretPath"] = "tfe"
+			test.config["vaultUri"] = fake.URL
+
+			err := newVault(t, "hashicorp", test.config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, ok := fake.Secret(test.stored)
+			if !ok || data["app.terraform.io"] != "my-secret-token" {
+				t.Fatalf("expected the secret to be stored at '%s' got %v", test.stored, data)
+			}
+
+			terraVault := newVault(t, "hashicorp", test.config, "app.terraform.io")
+			value, err := terraVault.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(value) != "my-secret-token" {
+				t.Errorf("expected the value got '%s'", value)
+			}
+
+			names, err := terraVault.(vault.Enumerator).Names(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(names) != 1 || names[0] != "app.terraform.io" {
+				t.Errorf("expected the names of the keys got %v", names)
+			}
+
+			err = terraVault.Delete(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := fake.Secret(test.stored); ok {
+				t.Errorf("expected the secret at '%s' to be deleted", test.stored)
+			}
+		})
+	}
+
+	config := vault.ProviderConfig{"keyVaultPath": "legacy", "kvVersion": "3", "secretPath": "tfe", "vaultUri": fake.URL}
+	_, err := newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err == nil {
+		t.Errorf("expected an error for an unsupported KV version")
+	}
+}
+
+func TestHashiCorpEnvironment(t *testing.T) {
+	ctx := context.Background()
+
+	fake := vaulttest.NewVaultKV(t, "env-token")
+	fake.Namespace = "platform/terraform"
+	t.Setenv("VAULT_ADDR", fake.URL)
+	t.Setenv("VAULT_NAMESPACE", "platform/terraform")
+	t.Setenv("VAULT_TOKEN", "env-token")
+	t.Setenv("TC_TEST_VAULT_TOKEN", "")
+
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "tfe",
+	}
+
+	err := newVault(t, "hashicorp", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:1")
+	t.Setenv("VAULT_NAMESPACE", "other")
+	t.Setenv("VAULT_TOKEN", "wrong-token")
+	t.Setenv("TC_TEST_VAULT_TOKEN", "env-token")
+	config["namespace"] = "platform/terraform"
+	config["vaultUri"] = fake.URL
+
+	value, err := newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != "my-secret-token" {
+		t.Errorf("expected the settings to take precedence over the environment got '%s'", value)
+	}
+
+	t.Setenv("VAULT_ADDR", "")
+	delete(config, "vaultUri")
+	_, err = newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err == nil {
+		t.Errorf("expected an error when no address is set")
+	}
+}
+
+func TestHashiCorpTLS(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKVTLS(t, "vaulttest")
+	caCert := writeFile(t, "ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: fake.Certificate().Raw}))
+
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "tfe",
+		"vaultUri":             fake.URL,
+	}
+
+	err := newVault(t, "hashicorp", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err == nil {
+		t.Error("expected an unknown certificate to be rejected")
+	}
+
+	for _, settings := range []vault.ProviderConfig{
+		{"caCert": caCert, "tlsServerName": "example.com"},
+		{"insecureSkipVerify": true},
+	} {
+		for key, value := range config {
+			settings[key] = value
+		}
+
+		err := newVault(t, "hashicorp", settings, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+		if err != nil {
+			t.Fatalf("%v: %v", settings, err)
+		}
+	}
+
+	config["caCert"] = caCert
+	config["tlsServerName"] = "vault.corp.example"
+	err = newVault(t, "hashicorp", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err == nil {
+		t.Error("expected a certificate for another name to be rejected")
+	}
+}
+
+func TestHashiCorpMergedWrites(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.Mount("legacy", 1)
+
+	for _, mount := range []string{"kv", "legacy"} {
+		t.Run(mount, func(t *testing.T) {
+			fake.Put(mount+"/tfe", map[string]interface{}{"tfe.corp.example": "sibling-token"})
+			config := vault.ProviderConfig{
+				"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+				"keyVaultPath":         mount,
+				"secretPath":           "tfe",
+				"vaultUri":             fake.URL,
+			}
+
+			// KV version 1 has no check-and-set, so only writes to KV version 2 run concurrently
+			names := []string{"app.terraform.io", "app.eu.terraform.io", "spacelift.io", "scalr.io"}
+			errs := make(chan error, len(names))
+			for _, name := range names {
+				terraVault := newVault(t, "hashicorp", config, name)
+				if mount == "legacy" {
+					errs <- terraVault.Create(ctx, name+"-token", "Created")
+					continue
+				}
+
+				go func(name string) {
+					errs <- terraVault.Create(ctx, name+"-token", "Created")
+				}(name)
+			}
+
+			for range names {
+				if err := <-errs; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			data, _ := fake.Secret(mount + "/tfe")
+			if data["tfe.corp.example"] != "sibling-token" || len(data) != len(names)+1 {
+				t.Errorf("expected every write and the sibling key to be kept got %v", data)
+			}
+
+			err := newVault(t, "hashicorp", config, "app.terraform.io").Delete(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ = fake.Secret(mount + "/tfe")
+			if _, ok := data["app.terraform.io"]; ok || data["tfe.corp.example"] != "sibling-token" {
+				t.Errorf("expected only the deleted key to be removed got %v", data)
+			}
+
+			err = newVault(t, "hashicorp", config, "app.terraform.io").Delete(ctx)
+			if !terraerrors.IsNotFound(err) {
+				t.Errorf("expected ErrNotFound for a deleted key got %v", err)
+			}
+		})
+	}
+
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "recreated",
+		"vaultUri":             fake.URL,
+	}
+
+	for i := 0; i < 2; i++ {
+		terraVault := newVault(t, "hashicorp", config, "app.terraform.io")
+		err := terraVault.Create(ctx, "my-secret-token", "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = terraVault.Delete(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := fake.Secret("kv/recreated"); ok {
+		t.Errorf("expected the secret to be deleted with its last key")
+	}
+}
+
+func TestHashiCorpVersions(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.Mount("legacy", 1)
+	for _, token := range []string{"token-1", "token-2", "token-3"} {
+		fake.Put("kv/tfe", map[string]interface{}{"app.terraform.io": token})
+	}
+
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"keyVaultPath":         "kv",
+		"secretPath":           "tfe",
+		"vaultUri":             fake.URL,
+	}
+
+	versioner, ok := newVault(t, "hashicorp", config, "app.terraform.io").(vault.Versioner)
+	if !ok {
+		t.Fatal("expected the hashicorp provider to implement vault.Versioner")
+	}
+
+	err := versioner.SoftDelete(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = versioner.SoftDelete(ctx, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Secret("kv/tfe"); ok {
+		t.Errorf("expected the latest version to be soft deleted")
+	}
+
+	err = versioner.Undelete(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := newVault(t, "hashicorp", config, "app.terraform.io").Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(secret) != "token-3" {
+		t.Errorf("expected the latest version to be restored got '%s'", secret)
+	}
+
+	err = versioner.Destroy(ctx, []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := fake.Versions("kv/tfe")
+	if !versions[0].Destroyed || !versions[1].Destroyed || versions[1].Deleted || versions[2].Destroyed {
+		t.Errorf("expected only the versions 1 and 2 to be destroyed got %+v", versions)
+	}
+
+	err = versioner.Undelete(ctx, []int{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if versions := fake.Versions("kv/tfe"); versions[0].Data != nil || !versions[0].Destroyed {
+		t.Errorf("expected a destroyed version to stay destroyed got %+v", versions[0])
+	}
+
+	err = versioner.DeleteMetadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if versions := fake.Versions("kv/tfe"); len(versions) != 0 {
+		t.Errorf("expected every version to be deleted got %+v", versions)
+	}
+
+	for name, err := range map[string]error{
+		"soft delete":     versioner.SoftDelete(ctx, nil),
+		"undelete":        versioner.Undelete(ctx, nil),
+		"delete metadata": versioner.DeleteMetadata(ctx),
+	} {
+		if !terraerrors.IsNotFound(err) {
+			t.Errorf("expected %s of a missing secret to return ErrNotFound got %v", name, err)
+		}
+	}
+
+	config["keyVaultPath"] = "legacy"
+	fake.Put("legacy/tfe", map[string]interface{}{"app.terraform.io": "token"})
+	err = newVault(t, "hashicorp", config, "app.terraform.io").(vault.Versioner).SoftDelete(ctx, nil)
+	if err == nil {
+		t.Errorf("expected an error for a KV version 1 mount")
+	}
+}
@@ -0,0 +1,390 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	hcvault "github.com/hashicorp/vault/api"
+	"github.com/mitchellh/go-homedir"
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+const (
+	// DefaultTFCLeaseCache is the path of the file the leases of the tokens are cached in when none is configured
+	DefaultTFCLeaseCache = "~/.terracreds/tfc-leases.json"
+
+	// DefaultTFCMinTTL is the time a cached token must stay valid for to be reused when none is configured
+	DefaultTFCMinTTL = 10 * time.Minute
+
+	// DefaultTFCMount is the path the Terraform Cloud secrets engine is mounted at when none is configured
+	DefaultTFCMount = "terraform"
+)
+
+// HashiTFC reads Terraform Cloud API tokens from the Terraform Cloud secrets engine of HashiCorp
+// Vault, so that no long-lived token is stored. The leases of the tokens are cached on disk and a
+// token is reused until it's close to expiring, when its lease is renewed or replaced by a new one
+type HashiTFC struct {
+	LeaseCache string
+	MinTTL     string
+	Mount      string
+	Role       string
+	Vault      *HashiVault
+}
+
+// hashiLease is a lease of a token in the lease cache
+type hashiLease struct {
+	Expires   time.Time `json:"expires"`
+	LeaseID   string    `json:"leaseId"`
+	Renewable bool      `json:"renewable"`
+	Token     string    `json:"token"`
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "hashicorp-tfc",
+		Key:   "hctfc",
+		Title: "HashiCorp Vault Terraform Cloud Secrets Engine",
+		Usage: "HashiCorp Vault Terraform Cloud secrets engine provider configuration settings",
+		Flags: hashiFlags(
+			Flag{
+				Name:  "lease-cache",
+				Key:   "leaseCache",
+				Usage: fmt.Sprintf("The path to the file the leases of the tokens are cached in. If omitted '%s' is used", DefaultTFCLeaseCache),
+			},
+			Flag{
+				Name:  "min-ttl",
+				Key:   "minTtl",
+				Usage: "The time a cached token must stay valid for to be reused such as '30m'. If omitted '10m' is used",
+			},
+			Flag{
+				Name:  "mount",
+				Key:   "mount",
+				Usage: fmt.Sprintf("The path the Terraform Cloud secrets engine is mounted at. If omitted '%s' is used", DefaultTFCMount),
+			},
+			Flag{
+				Name:     "role",
+				Key:      "role",
+				Usage:    "The role of the Terraform Cloud secrets engine to read tokens from",
+				Required: true,
+			},
+		),
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &HashiTFC{
+				LeaseCache: config.String("leaseCache"),
+				MinTTL:     config.String("minTtl"),
+				Mount:      config.String("mount"),
+				Role:       config.String("role"),
+				Vault:      newHashiVault(config),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// credsPath returns the path the tokens of the role are read from
+func (tfc *HashiTFC) credsPath() (string, error) {
+	if tfc.Role == "" {
+		return "", fmt.Errorf("the role of the Terraform Cloud secrets engine must be set with 'role'")
+	}
+
+	mount := strings.Trim(tfc.Mount, "/")
+	if mount == "" {
+		mount = DefaultTFCMount
+	}
+
+	return fmt.Sprintf("%s/creds/%s", mount, tfc.Role), nil
+}
+
+// leaseKey returns the key of the lease in the cache, so that the tokens of the same role on
+// other Vault instances or namespaces aren't mixed up
+func (tfc *HashiTFC) leaseKey(credsPath string) string {
+	address := tfc.Vault.VaultUri
+	if address == "" {
+		address = os.Getenv(hcvault.EnvVaultAddress)
+	}
+
+	namespace := tfc.Vault.Namespace
+	if namespace == "" {
+		namespace = os.Getenv(hcvault.EnvVaultNamespace)
+	}
+
+	return fmt.Sprintf("%s/%s#%s", strings.TrimRight(address, "/"), strings.Trim(namespace, "/"), credsPath)
+}
+
+// minTTL returns the time a cached token must stay valid for to be reused
+func (tfc *HashiTFC) minTTL() (time.Duration, error) {
+	if tfc.MinTTL == "" {
+		return DefaultTFCMinTTL, nil
+	}
+
+	minTTL, err := time.ParseDuration(tfc.MinTTL)
+	if err != nil {
+		return 0, fmt.Errorf("the minimum TTL '%s' is not a duration such as '30m': %w", tfc.MinTTL, err)
+	}
+
+	return minTTL, nil
+}
+
+// cachePath returns the path of the lease cache
+func (tfc *HashiTFC) cachePath() (string, error) {
+	path := tfc.LeaseCache
+	if path == "" {
+		path = DefaultTFCLeaseCache
+	}
+
+	return homedir.Expand(path)
+}
+
+// readLeases returns the leases in the cache keyed by their lease key. A cache that doesn't
+// exist yet has no leases
+func readLeases(path string) (map[string]hashiLease, error) {
+	leases := make(map[string]hashiLease)
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return leases, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(bytes, &leases)
+	if err != nil {
+		return nil, fmt.Errorf("the lease cache '%s' is not valid: %w", path, err)
+	}
+
+	return leases, nil
+}
+
+// writeLeases replaces the leases in the cache. Leases that expired are left out
+func writeLeases(path string, leases map[string]hashiLease) error {
+	for key, lease := range leases {
+		if time.Now().After(lease.Expires) {
+			delete(leases, key)
+		}
+	}
+
+	bytes, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, bytes)
+}
+
+// newLease returns the lease of a secret returned by Vault
+func newLease(secret *hcvault.Secret, token string) hashiLease {
+	return hashiLease{
+		Expires:   time.Now().Add(time.Duration(secret.LeaseDuration) * time.Second),
+		LeaseID:   secret.LeaseID,
+		Renewable: secret.Renewable,
+		Token:     token,
+	}
+}
+
+// issue reads a new token of the role
+func (tfc *HashiTFC) issue(ctx context.Context, client *hcvault.Client, credsPath string) (hashiLease, error) {
+	secret, err := tfc.Vault.request(ctx, client, http.MethodGet, credsPath, nil)
+	if err != nil {
+		return hashiLease{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return hashiLease{}, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, credsPath)
+	}
+
+	token, ok := secret.Data["token"].(string)
+	if !ok || token == "" {
+		return hashiLease{}, fmt.Errorf("token type assertion failed: %T %#v", secret.Data["token"], secret.Data["token"])
+	}
+
+	return newLease(secret, token), nil
+}
+
+// renew extends the lease of a cached token
+func (tfc *HashiTFC) renew(ctx context.Context, client *hcvault.Client, lease hashiLease) (hashiLease, error) {
+	secret, err := tfc.Vault.request(ctx, client, http.MethodPut, "sys/leases/renew", map[string]interface{}{
+		"lease_id": lease.LeaseID,
+	})
+
+	if err != nil {
+		return hashiLease{}, err
+	}
+
+	if secret == nil {
+		return hashiLease{}, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, lease.LeaseID)
+	}
+
+	return newLease(secret, lease.Token), nil
+}
+
+// revoke revokes the lease of a cached token, which deletes the token in Terraform Cloud
+func (tfc *HashiTFC) revoke(ctx context.Context, client *hcvault.Client, lease hashiLease) error {
+	_, err := tfc.Vault.request(ctx, client, http.MethodPut, "sys/leases/revoke", map[string]interface{}{
+		"lease_id": lease.LeaseID,
+	})
+
+	return err
+}
+
+// token returns the cached token of the role while it stays valid for the minimum TTL. Otherwise
+// its lease is renewed, or it's replaced by a new token when it can't be renewed for long enough.
+// The lease of a replaced token is revoked, so that no token that terracreds has lost track of
+// stays valid. Tokens without a lease, such as the tokens of organization and team roles, aren't
+// cached
+func (tfc *HashiTFC) token(ctx context.Context) (string, error) {
+	credsPath, err := tfc.credsPath()
+	if err != nil {
+		return "", err
+	}
+
+	minTTL, err := tfc.minTTL()
+	if err != nil {
+		return "", err
+	}
+
+	path, err := tfc.cachePath()
+	if err != nil {
+		return "", err
+	}
+
+	unlock, err := lock(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	leases, err := readLeases(path)
+	if err != nil {
+		return "", err
+	}
+
+	key := tfc.leaseKey(credsPath)
+	cached, ok := leases[key]
+	if ok && time.Until(cached.Expires) > minTTL {
+		return cached.Token, nil
+	}
+
+	client, err := tfc.Vault.newHashiVaultClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if ok && cached.Renewable && time.Now().Before(cached.Expires) {
+		renewed, err := tfc.renew(ctx, client, cached)
+		if err != nil {
+			Log(fmt.Sprintf("- the lease of the token of '%s' couldn't be renewed: %s", credsPath, err), "WARNING")
+		} else if time.Until(renewed.Expires) > minTTL {
+			leases[key] = renewed
+			return renewed.Token, writeLeases(path, leases)
+		} else {
+			cached = renewed
+		}
+	}
+
+	lease, err := tfc.issue(ctx, client, credsPath)
+	if err != nil {
+		return "", err
+	}
+
+	if ok && time.Now().Before(cached.Expires) {
+		err = tfc.revoke(ctx, client, cached)
+		if err != nil {
+			Log(fmt.Sprintf("- the lease of the replaced token of '%s' couldn't be revoked: %s", credsPath, err), "WARNING")
+		}
+	}
+
+	delete(leases, key)
+	if lease.LeaseID != "" {
+		leases[key] = lease
+	}
+
+	return lease.Token, writeLeases(path, leases)
+}
+
+// Create returns an error since the tokens are issued by the Terraform Cloud secrets engine
+func (tfc *HashiTFC) Create(ctx context.Context, secretValue string, method string) error {
+	credsPath, err := tfc.credsPath()
+	if err != nil {
+		return err
+	}
+
+	err = errors.New("the tokens are issued by the Terraform Cloud secrets engine and can't be stored")
+	return wrapError(terraerrors.ErrPermissionDenied, err, credsPath)
+}
+
+// Delete revokes the lease of the cached token of the role, which deletes the token in
+// Terraform Cloud, and removes it from the cache
+func (tfc *HashiTFC) Delete(ctx context.Context) error {
+	credsPath, err := tfc.credsPath()
+	if err != nil {
+		return err
+	}
+
+	path, err := tfc.cachePath()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	leases, err := readLeases(path)
+	if err != nil {
+		return err
+	}
+
+	key := tfc.leaseKey(credsPath)
+	cached, ok := leases[key]
+	if !ok {
+		return fmt.Errorf("%w: no cached lease of %s", terraerrors.ErrNotFound, credsPath)
+	}
+
+	client, err := tfc.Vault.newHashiVaultClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = tfc.revoke(ctx, client, cached)
+	if err != nil {
+		return err
+	}
+
+	delete(leases, key)
+	return writeLeases(path, leases)
+}
+
+func (tfc *HashiTFC) Get(ctx context.Context) ([]byte, error) {
+	token, err := tfc.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(token), nil
+}
+
+// List returns the token of the role for each of the secret names, since every secret name
+// that's routed to the provider is served by the same role
+func (tfc *HashiTFC) List(ctx context.Context, secretNames []string) ([]string, error) {
+	token, err := tfc.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for range secretNames {
+		secretValues = append(secretValues, token)
+	}
+
+	return secretValues, nil
+}
@@ -0,0 +1,194 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+// expireLeases rewrites the lease cache so that every cached token expires in a minute
+func expireLeases(t *testing.T, path string) {
+	t.Helper()
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leases map[string]map[string]interface{}
+	err = json.Unmarshal(bytes, &leases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lease := range leases {
+		lease["expires"] = time.Now().Add(time.Minute).Format(time.RFC3339Nano)
+	}
+
+	bytes, err = json.Marshal(leases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, bytes, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashiCorpTFC(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.AddTFCRole("terraform/creds/ci", vaulttest.VaultTFCRole{TTL: time.Hour, MaxTTL: 2 * time.Hour})
+	fake.AddTFCRole("tfc/creds/org", vaulttest.VaultTFCRole{})
+
+	cache := filepath.Join(t.TempDir(), "tfc-leases.json")
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"leaseCache":           cache,
+		"minTtl":               "30m",
+		"role":                 "ci",
+		"vaultUri":             fake.URL,
+	}
+
+	get := func(config vault.ProviderConfig) string {
+		t.Helper()
+
+		token, err := newVault(t, "hashicorp-tfc", config, "app.terraform.io").Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(token)
+	}
+
+	first := get(config)
+	if second := get(config); second != first || len(fake.Leases()) != 1 {
+		t.Errorf("expected the cached token '%s' to be reused got '%s' with %d leases", first, second, len(fake.Leases()))
+	}
+
+	info, err := os.Stat(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the lease cache to be only readable by its owner got %v", info.Mode().Perm())
+	}
+
+	expireLeases(t, cache)
+	if renewed := get(config); renewed != first || fake.Leases()[0].Renewals != 1 {
+		t.Errorf("expected the lease of the token to be renewed got '%s' with %+v", renewed, fake.Leases())
+	}
+
+	config["minTtl"] = "90m"
+	replaced := get(config)
+	leases := fake.Leases()
+	if replaced == first || len(leases) != 2 || !leases[0].Revoked {
+		t.Errorf("expected a token that can't be renewed for the minimum TTL to be replaced and revoked got '%s' with %+v", replaced, leases)
+	}
+
+	err = newVault(t, "hashicorp-tfc", config, "app.terraform.io").Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if leases := fake.Leases(); !leases[1].Revoked {
+		t.Errorf("expected the lease of the cached token to be revoked got %+v", leases[1])
+	}
+
+	err = newVault(t, "hashicorp-tfc", config, "app.terraform.io").Delete(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound without a cached lease got %v", err)
+	}
+
+	org := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"leaseCache":           cache,
+		"mount":                "tfc",
+		"role":                 "org",
+		"vaultUri":             fake.URL,
+	}
+
+	if first, second := get(org), get(org); first != second || len(fake.Leases()) != 2 {
+		t.Errorf("expected the token of a role without leases to be read without a lease got '%s' and '%s'", first, second)
+	}
+
+	config["role"] = "missing"
+	_, err = newVault(t, "hashicorp-tfc", config, "app.terraform.io").Get(ctx)
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound for a role that doesn't exist got %v", err)
+	}
+
+	err = newVault(t, "hashicorp-tfc", org, "app.terraform.io").Create(ctx, "token", "Created")
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied for a token that's stored got %v", err)
+	}
+}
+
+func TestHashiCorpTFCRenewFailure(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TC_TEST_VAULT_TOKEN", "vaulttest")
+
+	fake := vaulttest.NewVaultKV(t, "vaulttest")
+	fake.AddTFCRole("terraform/creds/ci", vaulttest.VaultTFCRole{TTL: time.Hour, MaxTTL: 2 * time.Hour})
+
+	cache := filepath.Join(t.TempDir(), "tfc-leases.json")
+	config := vault.ProviderConfig{
+		"environmentTokenName": "TC_TEST_VAULT_TOKEN",
+		"leaseCache":           cache,
+		"minTtl":               "30m",
+		"role":                 "ci",
+		"vaultUri":             fake.URL,
+	}
+
+	var logged []string
+	vault.Log = func(msg string, level string) {
+		logged = append(logged, level+" "+msg)
+	}
+	t.Cleanup(func() {
+		vault.Log = func(msg string, level string) {}
+	})
+
+	tfc := newVault(t, "hashicorp-tfc", config, "app.terraform.io")
+	first, err := tfc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lease is unknown to Vault, such as when it was revoked by an operator
+	bytes, err := os.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(cache, []byte(strings.ReplaceAll(string(bytes), fake.Leases()[0].ID, "terraform/creds/ci/unknown")), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expireLeases(t, cache)
+	replaced, err := tfc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(replaced) == string(first) || len(fake.Leases()) != 2 {
+		t.Errorf("expected a token whose lease can't be renewed to be replaced got '%s' with %+v", replaced, fake.Leases())
+	}
+
+	if len(logged) != 1 || !strings.HasPrefix(logged[0], "WARNING - the lease of the token of 'terraform/creds/ci' couldn't be renewed") {
+		t.Errorf("expected the failed renewal to be logged got %q", logged)
+	}
+}
@@ -0,0 +1,458 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+const (
+	// DefaultHTTPCreateBody is the body of the create request when none is configured
+	DefaultHTTPCreateBody = `{"value":{{ json .Value }}}`
+
+	// httpResponseLimit is the most bytes read from a response
+	httpResponseLimit = 10 << 20
+)
+
+// httpFuncs are the functions available to the templates of the HTTP provider
+var httpFuncs = template.FuncMap{
+	"env":         os.Getenv,
+	"pathescape":  url.PathEscape,
+	"queryescape": url.QueryEscape,
+	"json": func(value string) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+// httpTemplateData is the data of the URL, body and header templates of the HTTP provider
+type httpTemplateData struct {
+	Name  string
+	Value string
+}
+
+// HTTPVault stores secrets in a JSON API over HTTP whose requests are defined by templates in
+// the configuration. The value and the names of the secrets are read from the responses with
+// JSONPath expressions
+type HTTPVault struct {
+	CACert       string
+	CreateBody   string
+	CreateMethod string
+	CreateURL    string
+	DeleteMethod string
+	DeleteURL    string
+	GetMethod    string
+	GetURL       string
+	Headers      map[string]string
+	ListURL      string
+	NamesPath    string
+	SecretName   string
+	TokenEnv     string
+	UpdateMethod string
+	ValuePath    string
+
+	client *http.Client
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "http",
+		Key:   "http",
+		Title: "an HTTP API",
+		Usage: "Generic HTTP JSON API provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "ca-cert",
+				Key:   "caCert",
+				Usage: "The path to the PEM encoded CA certificate that verifies the API's certificate",
+			},
+			{
+				Name:  "create-body",
+				Key:   "createBody",
+				Usage: fmt.Sprintf("The template of the body of the create request. If omitted '%s' is used", DefaultHTTPCreateBody),
+			},
+			{
+				Name:  "create-method",
+				Key:   "createMethod",
+				Usage: "The method of the create request. If omitted 'PUT' is used",
+			},
+			{
+				Name:  "create-url",
+				Key:   "createUrl",
+				Usage: "The URL template of the create request. If omitted the URL of the get request is used",
+			},
+			{
+				Name:  "delete-method",
+				Key:   "deleteMethod",
+				Usage: "The method of the delete request. If omitted 'DELETE' is used",
+			},
+			{
+				Name:  "delete-url",
+				Key:   "deleteUrl",
+				Usage: "The URL template of the delete request. If omitted the URL of the get request is used",
+			},
+			{
+				Name:  "get-method",
+				Key:   "getMethod",
+				Usage: "The method of the get request. If omitted 'GET' is used",
+			},
+			{
+				Name:     "get-url",
+				Key:      "getUrl",
+				Usage:    "The URL template of the get request such as 'https://secrets.example.com/v1/secrets/{{ .Name }}'",
+				Required: true,
+			},
+			{
+				Name:  "headers",
+				Key:   "headers",
+				Usage: "The headers of every request such as 'X-Team: platform; X-Api-Key: {{ env \"API_KEY\" }}'. Values are templates",
+			},
+			{
+				Name:  "list-url",
+				Key:   "listUrl",
+				Usage: "The URL of the request that returns the names of the secrets for 'list --all'",
+			},
+			{
+				Name:  "names-path",
+				Key:   "namesPath",
+				Usage: "The JSONPath of the names of the secrets in the response of the list request such as '$.secrets[*].name'",
+			},
+			{
+				Name:  "token-env",
+				Key:   "tokenEnv",
+				Usage: "The name of the environment variable that holds a bearer token for the Authorization header",
+			},
+			{
+				Name:  "update-method",
+				Key:   "updateMethod",
+				Usage: "The method of the create request when the secret already exists. If omitted the create method is used",
+			},
+			{
+				Name:  "value-path",
+				Key:   "valuePath",
+				Usage: "The JSONPath of the value in the response of the get request such as '$.data.value'. If omitted the whole body is the value",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			headers, err := httpHeaders(config["headers"])
+			if err != nil {
+				return nil, err
+			}
+
+			vault := &HTTPVault{
+				CACert:       config.String("caCert"),
+				CreateBody:   config.String("createBody"),
+				CreateMethod: config.String("createMethod"),
+				CreateURL:    config.String("createUrl"),
+				DeleteMethod: config.String("deleteMethod"),
+				DeleteURL:    config.String("deleteUrl"),
+				GetMethod:    config.String("getMethod"),
+				GetURL:       config.String("getUrl"),
+				Headers:      headers,
+				ListURL:      config.String("listUrl"),
+				NamesPath:    config.String("namesPath"),
+				SecretName:   secretName,
+				TokenEnv:     config.String("tokenEnv"),
+				UpdateMethod: config.String("updateMethod"),
+				ValuePath:    config.String("valuePath"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// httpHeaders returns the headers of the configuration, which are either a map in the
+// configuration file or a string of 'Name: value' pairs separated by semicolons
+func httpHeaders(value interface{}) (map[string]string, error) {
+	headers := make(map[string]string)
+	switch value := value.(type) {
+	case nil:
+	case string:
+		for _, header := range strings.Split(value, ";") {
+			if strings.TrimSpace(header) == "" {
+				continue
+			}
+
+			name, value, ok := strings.Cut(header, ":")
+			if !ok {
+				return nil, fmt.Errorf("the header '%s' must be in the form 'Name: value'", strings.TrimSpace(header))
+			}
+
+			headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	case map[interface{}]interface{}:
+		for name, value := range value {
+			headers[fmt.Sprint(name)] = fmt.Sprint(value)
+		}
+	case map[string]interface{}:
+		for name, value := range value {
+			headers[name] = fmt.Sprint(value)
+		}
+	default:
+		return nil, fmt.Errorf("the headers must be a map of names to values")
+	}
+
+	return headers, nil
+}
+
+// render executes the template with the name and value of a secret
+func render(name string, text string, data httpTemplateData) (string, error) {
+	tmpl, err := template.New(name).Funcs(httpFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("the %s template is not valid: %w", name, err)
+	}
+
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", err
+	}
+
+	return rendered.String(), nil
+}
+
+// extract returns the values that the JSONPath expression such as '$.data.value' selects from the
+// JSON document. An array that's selected as a whole is returned as its elements
+func extract(expression string, document []byte) ([]string, error) {
+	var data interface{}
+	err := json.Unmarshal(document, &data)
+	if err != nil {
+		return nil, fmt.Errorf("the response is not JSON: %w", err)
+	}
+
+	if !strings.HasPrefix(expression, "{") {
+		expression = strings.TrimPrefix(expression, "$")
+		if !strings.HasPrefix(expression, ".") && !strings.HasPrefix(expression, "[") {
+			expression = "." + expression
+		}
+
+		expression = "{" + expression + "}"
+	}
+
+	path := jsonpath.New("path")
+	err = path.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("the JSONPath '%s' is not valid: %w", expression, err)
+	}
+
+	results, err := path.FindResults(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", terraerrors.ErrNotFound, err)
+	}
+
+	var values []string
+	for _, result := range results {
+		for _, value := range result {
+			if value.Kind() == reflect.Interface {
+				value = value.Elem()
+			}
+
+			items := []reflect.Value{value}
+			if value.Kind() == reflect.Slice {
+				items = nil
+				for i := 0; i < value.Len(); i++ {
+					items = append(items, value.Index(i))
+				}
+			}
+
+			for _, item := range items {
+				if str, ok := item.Interface().(string); ok {
+					values = append(values, str)
+					continue
+				}
+
+				encoded, err := json.Marshal(item.Interface())
+				if err != nil {
+					return nil, err
+				}
+
+				values = append(values, string(encoded))
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// do sends the request defined by the URL and body templates and returns the response body.
+// Responses other than 2xx are returned as errors
+func (hv *HTTPVault) do(ctx context.Context, method string, urlTemplate string, bodyTemplate string, data httpTemplateData) ([]byte, error) {
+	if hv.client == nil {
+		client, err := newHTTPClient(hv.CACert)
+		if err != nil {
+			return nil, err
+		}
+
+		hv.client = client
+	}
+
+	endpoint, err := render("URL", urlTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if bodyTemplate != "" {
+		rendered, err := render("body", bodyTemplate, data)
+		if err != nil {
+			return nil, err
+		}
+
+		body = strings.NewReader(rendered)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	if hv.TokenEnv != "" {
+		token := os.Getenv(hv.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("%w: the environment variable '%s' doesn't hold a token", terraerrors.ErrPermissionDenied, hv.TokenEnv)
+		}
+
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	for name, value := range hv.Headers {
+		rendered, err := render("header", value, data)
+		if err != nil {
+			return nil, err
+		}
+
+		request.Header.Set(name, rendered)
+	}
+
+	response, err := hv.client.Do(request)
+	if err != nil {
+		return nil, transportError(err, data.Name)
+	}
+
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, httpResponseLimit))
+	if err != nil {
+		return nil, transportError(err, data.Name)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		err := fmt.Errorf("the %s request to '%s' returned %s", method, request.URL.Redacted(), response.Status)
+		return nil, statusError(response.StatusCode, err, data.Name)
+	}
+
+	return responseBody, nil
+}
+
+// value reads the secret with the get request
+func (hv *HTTPVault) value(ctx context.Context, secretName string) (string, error) {
+	data := httpTemplateData{Name: secretName}
+	body, err := hv.do(ctx, orDefault(hv.GetMethod, http.MethodGet), hv.GetURL, "", data)
+	if err != nil {
+		return "", err
+	}
+
+	if hv.ValuePath == "" {
+		return string(bytes.TrimSpace(body)), nil
+	}
+
+	values, err := extract(hv.ValuePath, body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, secretName)
+	}
+
+	if len(values) != 1 {
+		return "", fmt.Errorf("%w: the JSONPath '%s' selected %d values for '%s'", terraerrors.ErrNotFound, hv.ValuePath, len(values), secretName)
+	}
+
+	return values[0], nil
+}
+
+// orDefault returns the value, or the default when it's not set
+func orDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// Create sends the create request, or the update request when the secret already exists and
+// an update method is configured
+func (hv *HTTPVault) Create(ctx context.Context, secretValue string, method string) error {
+	requestMethod := orDefault(hv.CreateMethod, http.MethodPut)
+	if method == "Updated" && hv.UpdateMethod != "" {
+		requestMethod = hv.UpdateMethod
+	}
+
+	data := httpTemplateData{Name: hv.SecretName, Value: secretValue}
+	_, err := hv.do(ctx, requestMethod, orDefault(hv.CreateURL, hv.GetURL), orDefault(hv.CreateBody, DefaultHTTPCreateBody), data)
+	return err
+}
+
+func (hv *HTTPVault) Delete(ctx context.Context) error {
+	data := httpTemplateData{Name: hv.SecretName}
+	_, err := hv.do(ctx, orDefault(hv.DeleteMethod, http.MethodDelete), orDefault(hv.DeleteURL, hv.GetURL), "", data)
+	return err
+}
+
+func (hv *HTTPVault) Get(ctx context.Context) ([]byte, error) {
+	value, err := hv.value(ctx, hv.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+func (hv *HTTPVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, err := hv.value(ctx, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names that the names path selects from the response of the list request
+func (hv *HTTPVault) Names(ctx context.Context) ([]string, error) {
+	if hv.ListURL == "" || hv.NamesPath == "" {
+		return nil, fmt.Errorf("the http provider needs a list URL and a names path to list every secret")
+	}
+
+	body, err := hv.do(ctx, http.MethodGet, hv.ListURL, "", httpTemplateData{})
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := extract(hv.NamesPath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
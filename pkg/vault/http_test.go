@@ -0,0 +1,168 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+)
+
+// newSecretAPI starts a small JSON secret API like the internal ones the http provider is
+// configured for. Every request needs the bearer token and the 'X-Team' header
+func newSecretAPI(t *testing.T, token string) *httptest.Server {
+	var mu sync.Mutex
+	secrets := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer "+token || r.Header.Get("X-Team") != "platform" {
+			http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.URL.Path == "/v1/secrets" {
+			var names []map[string]string
+			for name := range secrets {
+				names = append(names, map[string]string{"name": name})
+			}
+
+			sort.Slice(names, func(i, j int) bool { return names[i]["name"] < names[j]["name"] })
+			json.NewEncoder(w).Encode(map[string]interface{}{"secrets": names})
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/v1/secrets/")
+		value, exists := secrets[name]
+
+		switch r.Method {
+		case http.MethodGet:
+			if !exists {
+				http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				return
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"name": name, "value": value}})
+		case http.MethodPost, http.MethodPut:
+			if r.Method == http.MethodPost && exists {
+				http.Error(w, `{"error":"exists"}`, http.StatusConflict)
+				return
+			}
+
+			if r.Method == http.MethodPut && !exists {
+				http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				return
+			}
+
+			var body struct {
+				Secret string `json:"secret"`
+			}
+
+			err := json.NewDecoder(r.Body).Decode(&body)
+			if err != nil {
+				http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+				return
+			}
+
+			secrets[name] = body.Secret
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			if !exists {
+				http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				return
+			}
+
+			delete(secrets, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+// secretAPIConfig returns the http provider configuration for the secret API
+func secretAPIConfig(url string) vault.ProviderConfig {
+	return vault.ProviderConfig{
+		"createBody":   `{"secret":{{ json .Value }}}`,
+		"createMethod": "POST",
+		"getUrl":       url + "/v1/secrets/{{ pathescape .Name }}",
+		"headers":      map[interface{}]interface{}{"X-Team": "{{ env \"TC_TEAM\" }}"},
+		"listUrl":      url + "/v1/secrets",
+		"namesPath":    "$.secrets[*].name",
+		"tokenEnv":     "TC_HTTP_TOKEN",
+		"updateMethod": "PUT",
+		"valuePath":    "$.data.value",
+	}
+}
+
+func TestHTTPRequests(t *testing.T) {
+	t.Setenv("TC_HTTP_TOKEN", "vaulttest")
+	t.Setenv("TC_TEAM", "platform")
+	ctx := context.Background()
+
+	config := secretAPIConfig(newSecretAPI(t, "vaulttest").URL)
+	config["headers"] = `X-Team: {{ env "TC_TEAM" }}; X-Request-Source: terracreds`
+
+	for _, name := range []string{"tfe.example.com", "app.terraform.io"} {
+		err := newVault(t, "http", config, name).Create(ctx, `token "`+name+`"`, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	terraVault := newVault(t, "http", config, "app.terraform.io")
+	value, err := terraVault.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != `token "app.terraform.io"` {
+		t.Errorf("expected the value to be escaped in the body got '%s'", value)
+	}
+
+	names, err := terraVault.(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "tfe.example.com"}) {
+		t.Errorf("expected the names selected by the names path got %q", names)
+	}
+
+	err = terraVault.Create(ctx, "another-token", "Created")
+	if !errors.Is(err, terraerrors.ErrConflict) {
+		t.Errorf("expected ErrConflict creating an existing secret got %v", err)
+	}
+
+	t.Setenv("TC_TEAM", "data")
+	_, err = terraVault.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied with the wrong header got %v", err)
+	}
+
+	config["valuePath"] = "$.data.missing"
+	t.Setenv("TC_TEAM", "platform")
+	_, err = newVault(t, "http", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a value path that selects nothing got %v", err)
+	}
+}
+
+func TestHTTPInvalidHeaders(t *testing.T) {
+	provider, _ := vault.Lookup("http")
+	_, err := provider.New(vault.ProviderConfig{"getUrl": "http://localhost", "headers": "X-Team"}, "app.terraform.io")
+	if err == nil {
+		t.Error("expected a header without a value to be rejected")
+	}
+}
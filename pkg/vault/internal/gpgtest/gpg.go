@@ -0,0 +1,51 @@
+// Package gpgtest generates the GPG keys that the tests of the GPG based vault providers encrypt
+// to. It doesn't import the vault package, so the internal tests of that package can use it
+package gpgtest
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// NewKey generates a GPG key without a passphrase in a temporary GNUPGHOME that's set for the
+// test, and returns its fingerprint. The test is skipped when gpg is not installed
+func NewKey(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	// The agent's socket path must be short, so the home isn't nested in the test's temp dir
+	home, err := os.MkdirTemp("", "gnupg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(home)
+	})
+
+	output, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "Terracreds Test <test@example.com>", "default", "default", "never").CombinedOutput()
+	if err != nil {
+		t.Fatalf("gpg: %v: %s", err, output)
+	}
+
+	output, err = exec.Command("gpg", "--batch", "--with-colons", "--list-secret-keys").Output()
+	if err != nil {
+		t.Fatalf("gpg: %v", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && len(fields) > 9 {
+			return fields[9]
+		}
+	}
+
+	t.Fatalf("gpg listed no fingerprint:\n%s", output)
+	return ""
+}
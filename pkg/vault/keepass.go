@@ -0,0 +1,497 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/tobischo/gokeepasslib/v3"
+	w "github.com/tobischo/gokeepasslib/v3/wrappers"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/helpers"
+)
+
+// DefaultKeePassPasswordEnv is the environment variable that holds the master password of the
+// KeePass database when none is configured
+const DefaultKeePassPasswordEnv = "TC_KEEPASS_PASSWORD"
+
+// KeePassVault stores secrets as entries in a group of a KeePass KDBX database. The title of
+// each entry is the secret name and its password is the secret value
+type KeePassVault struct {
+	Group       string
+	KeyFile     string
+	KeyFileOnly bool
+	PasswordEnv string
+	Path        string
+	SecretName  string
+
+	// Prompt reads the master password when the environment variable doesn't hold it. If nil
+	// the master password is read from the terminal
+	Prompt func(prompt string) (string, error)
+
+	credentials *gokeepasslib.DBCredentials
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "keepass",
+		Key:   "keepass",
+		Title: "a KeePass database",
+		Usage: "KeePass KDBX database provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "group",
+				Key:   "group",
+				Usage: "The path of the group that holds the secrets such as 'Infrastructure/Terraform'. If omitted the root group is used",
+			},
+			{
+				Name:  "key-file",
+				Key:   "keyFile",
+				Usage: "The path to the key file of the database",
+			},
+			{
+				Name:  "key-file-only",
+				Key:   "keyFileOnly",
+				Usage: "Open the database with the key file alone, without a master password",
+				Bool:  true,
+			},
+			{
+				Name:  "password-env",
+				Key:   "passwordEnv",
+				Usage: fmt.Sprintf("The name of the environment variable that holds the master password. If omitted '%s' is used", DefaultKeePassPasswordEnv),
+			},
+			{
+				Name:     "path",
+				Key:      "path",
+				Usage:    "The path to the KDBX database file",
+				Required: true,
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &KeePassVault{
+				Group:       config.String("group"),
+				KeyFile:     config.String("keyFile"),
+				KeyFileOnly: config.Bool("keyFileOnly"),
+				PasswordEnv: config.String("passwordEnv"),
+				Path:        config.String("path"),
+				SecretName:  secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// path returns the expanded path of the database
+func (kv *KeePassVault) path() (string, error) {
+	if kv.Path == "" {
+		return "", fmt.Errorf("the path of the KeePass database is not configured")
+	}
+
+	return homedir.Expand(kv.Path)
+}
+
+// Credentials returns the composite key of the database from the master password, the key file
+// or both. The master password is read from the environment variable or the terminal
+func (kv *KeePassVault) Credentials() (*gokeepasslib.DBCredentials, error) {
+	if kv.credentials != nil {
+		return kv.credentials, nil
+	}
+
+	var keyFile string
+	if kv.KeyFile != "" {
+		path, err := homedir.Expand(kv.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		keyFile = path
+	}
+
+	if kv.KeyFileOnly {
+		if keyFile == "" {
+			return nil, fmt.Errorf("a key file must be configured to open the KeePass database without a master password")
+		}
+
+		credentials, err := gokeepasslib.NewKeyCredentials(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to read the key file: %w", terraerrors.ErrPermissionDenied, err)
+		}
+
+		kv.credentials = credentials
+		return credentials, nil
+	}
+
+	password, err := kv.password()
+	if err != nil {
+		return nil, err
+	}
+
+	if keyFile == "" {
+		kv.credentials = gokeepasslib.NewPasswordCredentials(password)
+		return kv.credentials, nil
+	}
+
+	credentials, err := gokeepasslib.NewPasswordAndKeyCredentials(password, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to read the key file: %w", terraerrors.ErrPermissionDenied, err)
+	}
+
+	kv.credentials = credentials
+	return credentials, nil
+}
+
+// password returns the master password from the environment variable or the terminal. When the
+// database doesn't exist yet a prompted master password is asked for twice
+func (kv *KeePassVault) password() (string, error) {
+	env := kv.PasswordEnv
+	if env == "" {
+		env = DefaultKeePassPasswordEnv
+	}
+
+	if password := os.Getenv(env); password != "" {
+		return password, nil
+	}
+
+	path, err := kv.path()
+	if err != nil {
+		return "", err
+	}
+
+	prompt := kv.Prompt
+	if prompt == nil {
+		prompt = helpers.ReadPassword
+	}
+
+	password, err := prompt(fmt.Sprintf("Enter the master password for the KeePass database '%s': ", path))
+	if err != nil {
+		return "", fmt.Errorf("%w: the master password isn't set in '%s' and can't be prompted for: %w", terraerrors.ErrPermissionDenied, env, err)
+	}
+
+	if password == "" {
+		return "", fmt.Errorf("%w: the master password is empty", terraerrors.ErrPermissionDenied)
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		confirm, err := prompt("Enter the master password again to create the KeePass database: ")
+		if err != nil {
+			return "", err
+		}
+
+		if confirm != password {
+			return "", fmt.Errorf("%w: the master passwords don't match", terraerrors.ErrPermissionDenied)
+		}
+	}
+
+	return password, nil
+}
+
+// open decodes the database and unlocks its protected values. A database that doesn't exist
+// yet is created in the KDBX4 format with an empty root group
+func (kv *KeePassVault) open(path string) (*gokeepasslib.Database, error) {
+	credentials, err := kv.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		db := gokeepasslib.NewDatabase(gokeepasslib.WithDatabaseKDBXVersion4())
+		db.Credentials = credentials
+
+		root := gokeepasslib.NewGroup()
+		root.Name = "Root"
+		db.Content.Root.Groups = []gokeepasslib.Group{root}
+		return db, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
+	}
+	defer file.Close()
+
+	db := gokeepasslib.NewDatabase()
+	db.Credentials = credentials
+	err = gokeepasslib.NewDecoder(file).Decode(db)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the KeePass database '%s' can't be opened, the credentials are wrong or the file is corrupted: %w", terraerrors.ErrPermissionDenied, path, err)
+	}
+
+	err = db.UnlockProtectedEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(db.Content.Root.Groups) == 0 {
+		return nil, fmt.Errorf("the KeePass database '%s' has no root group", path)
+	}
+
+	return db, nil
+}
+
+// save locks the protected values and atomically replaces the database. The master seed and
+// the IV are renewed so that each save is encrypted differently, since the header of an opened
+// database is otherwise written back as it was read
+func (kv *KeePassVault) save(path string, db *gokeepasslib.Database) error {
+	headers := db.Header.FileHeaders
+	for _, field := range [][]byte{headers.MasterSeed, headers.EncryptionIV} {
+		_, err := rand.Read(field)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := db.LockProtectedEntries()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = gokeepasslib.NewEncoder(&buf).Encode(db)
+	if err != nil {
+		return err
+	}
+
+	info, statErr := os.Stat(path)
+	err = writeFileAtomic(path, buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// Keep the permissions of a database that's shared with a team
+	if statErr == nil {
+		return os.Chmod(path, info.Mode().Perm())
+	}
+
+	return nil
+}
+
+// group returns the configured group below the root group. Missing groups are created when
+// create is true, otherwise nil is returned
+func (kv *KeePassVault) group(db *gokeepasslib.Database, create bool) *gokeepasslib.Group {
+	group := &db.Content.Root.Groups[0]
+	for _, name := range strings.Split(kv.Group, "/") {
+		if name == "" {
+			continue
+		}
+
+		var next *gokeepasslib.Group
+		for i := range group.Groups {
+			if group.Groups[i].Name == name {
+				next = &group.Groups[i]
+				break
+			}
+		}
+
+		if next == nil {
+			if !create {
+				return nil
+			}
+
+			child := gokeepasslib.NewGroup()
+			child.Name = name
+			group.Groups = append(group.Groups, child)
+			next = &group.Groups[len(group.Groups)-1]
+		}
+
+		group = next
+	}
+
+	return group
+}
+
+// findEntry returns the index of the entry titled with the secret name in the group or -1
+func findEntry(group *gokeepasslib.Group, secretName string) int {
+	if group == nil {
+		return -1
+	}
+
+	for i := range group.Entries {
+		if group.Entries[i].GetTitle() == secretName {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// update applies the change to the database while holding the lock
+func (kv *KeePassVault) update(ctx context.Context, change func(db *gokeepasslib.Database) error) error {
+	path, err := kv.path()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lock(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	db, err := kv.open(path)
+	if err != nil {
+		return err
+	}
+
+	err = change(db)
+	if err != nil {
+		return err
+	}
+
+	return kv.save(path, db)
+}
+
+// Create stores the secret as the password of the entry titled with the secret name. The
+// previous version of an existing entry is kept in its history like KeePass does
+func (kv *KeePassVault) Create(ctx context.Context, secretValue string, method string) error {
+	return kv.update(ctx, func(db *gokeepasslib.Database) error {
+		group := kv.group(db, true)
+		password := gokeepasslib.ValueData{
+			Key:   "Password",
+			Value: gokeepasslib.V{Content: secretValue, Protected: w.NewBoolWrapper(true)},
+		}
+
+		i := findEntry(group, kv.SecretName)
+		if i < 0 {
+			newEntry := gokeepasslib.NewEntry()
+			newEntry.Values = []gokeepasslib.ValueData{
+				{Key: "Title", Value: gokeepasslib.V{Content: kv.SecretName}},
+				password,
+			}
+
+			group.Entries = append(group.Entries, newEntry)
+			return nil
+		}
+
+		existing := &group.Entries[i]
+		previous := *existing
+		previous.Histories = nil
+		previous.Values = append([]gokeepasslib.ValueData(nil), existing.Values...)
+
+		if len(existing.Histories) == 0 {
+			existing.Histories = []gokeepasslib.History{{}}
+		}
+
+		existing.Histories[0].Entries = append(existing.Histories[0].Entries, previous)
+
+		if index := existing.GetPasswordIndex(); index >= 0 {
+			existing.Values[index] = password
+		} else {
+			existing.Values = append(existing.Values, password)
+		}
+
+		now := w.Now()
+		existing.Times.LastModificationTime = &now
+		return nil
+	})
+}
+
+// Delete removes the entry and records its deletion so that synchronized copies of the
+// database remove it too
+func (kv *KeePassVault) Delete(ctx context.Context) error {
+	return kv.update(ctx, func(db *gokeepasslib.Database) error {
+		group := kv.group(db, false)
+		i := findEntry(group, kv.SecretName)
+		if i < 0 {
+			return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, kv.SecretName)
+		}
+
+		now := w.Now()
+		db.Content.Root.DeletedObjects = append(db.Content.Root.DeletedObjects, gokeepasslib.DeletedObjectData{
+			UUID:         group.Entries[i].UUID,
+			DeletionTime: &now,
+		})
+
+		group.Entries = append(group.Entries[:i], group.Entries[i+1:]...)
+		return nil
+	})
+}
+
+// entries returns the passwords of the entries in the group by title
+func (kv *KeePassVault) entries() (map[string]string, error) {
+	path, err := kv.path()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return secrets, nil
+	}
+
+	db, err := kv.open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	group := kv.group(db, false)
+	if group == nil {
+		return secrets, nil
+	}
+
+	for i := range group.Entries {
+		title := group.Entries[i].GetTitle()
+		if _, ok := secrets[title]; !ok {
+			secrets[title] = group.Entries[i].GetPassword()
+		}
+	}
+
+	return secrets, nil
+}
+
+// Get retrieves the password of the entry
+func (kv *KeePassVault) Get(ctx context.Context) ([]byte, error) {
+	secrets, err := kv.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := secrets[kv.SecretName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, kv.SecretName)
+	}
+
+	return []byte(value), nil
+}
+
+// List retrieves the passwords of the entries
+func (kv *KeePassVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	secrets, err := kv.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, ok := secrets[secretName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the titles of the entries in the group
+func (kv *KeePassVault) Names(ctx context.Context) ([]string, error) {
+	secrets, err := kv.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,251 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// newKeePassVault returns a KeePass vault for a database in a temporary directory that's
+// opened with the master password and a key file
+func newKeePassVault(t *testing.T, group string) *KeePassVault {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "terracreds.keyx")
+	err := os.WriteFile(keyFile, []byte("a key file with arbitrary content"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &KeePassVault{
+		Group:      group,
+		KeyFile:    keyFile,
+		Path:       filepath.Join(dir, "team.kdbx"),
+		SecretName: "app.terraform.io",
+		Prompt: func(prompt string) (string, error) {
+			return "correct horse battery staple", nil
+		},
+	}
+}
+
+// openKeePass decodes the database with the vault's credentials
+func openKeePass(t *testing.T, kv *KeePassVault) *gokeepasslib.Database {
+	db, err := kv.open(kv.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestKeePassVaultEntries(t *testing.T) {
+	ctx := context.Background()
+	kv := newKeePassVault(t, "Infrastructure/Terraform")
+
+	err := kv.Create(ctx, "first-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := os.ReadFile(kv.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(before, []byte("first-token")) {
+		t.Error("expected the database to be encrypted")
+	}
+
+	err = kv.Create(ctx, "second-token", "Updated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := openKeePass(t, kv)
+	if !db.Header.IsKdbx4() {
+		t.Error("expected a new database to use the KDBX4 format")
+	}
+
+	root := db.Content.Root.Groups[0]
+	if len(root.Groups) != 1 || root.Groups[0].Name != "Infrastructure" || len(root.Groups[0].Groups) != 1 || root.Groups[0].Groups[0].Name != "Terraform" {
+		t.Fatalf("expected the entry in the 'Infrastructure/Terraform' group got %+v", root.Groups)
+	}
+
+	entries := root.Groups[0].Groups[0].Entries
+	if len(entries) != 1 || entries[0].GetTitle() != "app.terraform.io" || entries[0].GetPassword() != "second-token" {
+		t.Fatalf("expected a single updated entry got %+v", entries)
+	}
+
+	if len(entries[0].Histories) != 1 || len(entries[0].Histories[0].Entries) != 1 || entries[0].Histories[0].Entries[0].GetPassword() != "first-token" {
+		t.Errorf("expected the previous password in the entry's history got %+v", entries[0].Histories)
+	}
+
+	if bytes.Contains(before, db.Header.FileHeaders.MasterSeed) {
+		t.Error("expected each save to use a new master seed")
+	}
+
+	uuid := entries[0].UUID
+	err = kv.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db = openKeePass(t, kv)
+	deleted := db.Content.Root.DeletedObjects
+	if len(deleted) != 1 || !deleted[0].UUID.Compare(uuid) {
+		t.Errorf("expected the deletion of the entry to be recorded got %+v", deleted)
+	}
+
+	err = kv.Delete(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting a missing entry got %v", err)
+	}
+}
+
+func TestKeePassVaultCredentials(t *testing.T) {
+	ctx := context.Background()
+	kv := newKeePassVault(t, "")
+
+	err := kv.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TC_TEST_KEEPASS_PASSWORD", "correct horse battery staple")
+	env := &KeePassVault{
+		KeyFile:     kv.KeyFile,
+		PasswordEnv: "TC_TEST_KEEPASS_PASSWORD",
+		Path:        kv.Path,
+		SecretName:  kv.SecretName,
+	}
+
+	value, err := env.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != "my-secret-token" {
+		t.Errorf("expected 'my-secret-token' got '%s'", value)
+	}
+
+	withoutKeyFile := &KeePassVault{
+		PasswordEnv: "TC_TEST_KEEPASS_PASSWORD",
+		Path:        kv.Path,
+		SecretName:  kv.SecretName,
+	}
+
+	_, err = withoutKeyFile.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied without the key file got %v", err)
+	}
+
+	keyFileOnly := &KeePassVault{
+		KeyFile:     kv.KeyFile,
+		KeyFileOnly: true,
+		Path:        filepath.Join(t.TempDir(), "key-only.kdbx"),
+		SecretName:  kv.SecretName,
+	}
+
+	err = keyFileOnly.Create(ctx, "key-only-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err = keyFileOnly.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != "key-only-token" {
+		t.Errorf("expected 'key-only-token' got '%s'", value)
+	}
+}
+
+func TestKeePassVaultNames(t *testing.T) {
+	ctx := context.Background()
+	kv := newKeePassVault(t, "Terraform")
+
+	for _, name := range []string{"tfe.example.com", "app.terraform.io"} {
+		kv.SecretName = name
+		err := kv.Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := *kv
+	other.Group = "Email"
+	other.SecretName = "personal"
+	err := other.Create(ctx, "hunter2", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := kv.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"app.terraform.io", "tfe.example.com"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the names %q got %q", expected, names)
+	}
+
+	kv.Group = "Missing"
+	names, err = kv.Names(ctx)
+	if err != nil || len(names) != 0 {
+		t.Errorf("expected no names in a missing group got %q, %v", names, err)
+	}
+}
+
+func TestKeePassVaultPromptConfirm(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "team.kdbx")
+	prompted := func(passwords ...string) *KeePassVault {
+		return &KeePassVault{
+			Path:       path,
+			SecretName: "app.terraform.io",
+			Prompt: func(prompt string) (string, error) {
+				if len(passwords) == 0 {
+					t.Errorf("unexpected prompt: %s", prompt)
+					return "", errors.New("no terminal")
+				}
+
+				password := passwords[0]
+				passwords = passwords[1:]
+				return password, nil
+			},
+		}
+	}
+
+	err := prompted("").Create(ctx, "token", "Created")
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected an empty master password to be refused: %v", err)
+	}
+
+	err = prompted("first", "second").Create(ctx, "token", "Created")
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected the mismatched master passwords to be refused: %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected no database to be created: %v", err)
+	}
+
+	err = prompted("first", "first").Create(ctx, "token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := prompted("first").Get(ctx)
+	if err != nil || string(secret) != "token" {
+		t.Errorf("expected the master password to be asked for once for an existing database got '%s', %v", secret, err)
+	}
+}
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultKernelKeyringPrefix is prepended to the secret name to form the description of each key
+const DefaultKernelKeyringPrefix = "terracreds:"
+
+// KernelKeyringVault stores secrets as 'user' keys in a Linux kernel keyring, so they're held only
+// in kernel memory and are gone when the keyring is. It's used in ephemeral CI containers that
+// have neither a disk that should hold secrets nor a D-Bus session
+type KernelKeyringVault struct {
+	// Keyring is 'session' or 'user'
+	Keyring string
+
+	// Permissions is the permission mask of each key. If zero the kernel's default is kept
+	Permissions uint32
+	Prefix      string
+	SecretName  string
+
+	// Timeout expires each key after the duration. If zero the keys don't expire
+	Timeout time.Duration
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "kernel-keyring",
+		Key:   "kernelKeyring",
+		Title: "the Linux kernel keyring",
+		Usage: "Linux kernel keyring provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "keyring",
+				Key:   "keyring",
+				Usage: "The keyring that holds the keys: 'session' or 'user'. If omitted 'session' is used",
+			},
+			{
+				Name:  "permissions",
+				Key:   "permissions",
+				Usage: "The permission mask of each key in hexadecimal such as '3f010000'. If omitted the kernel's default is used",
+			},
+			{
+				Name:  "prefix",
+				Key:   "prefix",
+				Usage: fmt.Sprintf("The prefix of the description of each key. If omitted '%s' is used", DefaultKernelKeyringPrefix),
+			},
+			{
+				Name:  "timeout",
+				Key:   "timeout",
+				Usage: "The duration after which each key expires such as '2h'. If omitted the keys don't expire",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			keyring := config.String("keyring")
+			switch keyring {
+			case "":
+				keyring = "session"
+			case "session", "user":
+			case "process":
+				return nil, fmt.Errorf("the kernel keyring 'process' can't be used since its keys are gone when terracreds exits. Use 'session' or 'user'")
+			default:
+				return nil, fmt.Errorf("the kernel keyring '%s' is not supported. Use 'session' or 'user'", keyring)
+			}
+
+			var permissions uint32
+			if value := config.String("permissions"); value != "" {
+				mask, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("the kernel keyring permissions '%s' are not a hexadecimal mask: %w", value, err)
+				}
+
+				permissions = uint32(mask)
+			}
+
+			var timeout time.Duration
+			if value := config.String("timeout"); value != "" {
+				duration, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("the kernel keyring timeout '%s' is not a duration: %w", value, err)
+				}
+
+				if duration < time.Second {
+					return nil, fmt.Errorf("the kernel keyring timeout '%s' must be at least one second", value)
+				}
+
+				timeout = duration
+			}
+
+			prefix := config.String("prefix")
+			if prefix == "" {
+				prefix = DefaultKernelKeyringPrefix
+			}
+
+			vault := &KernelKeyringVault{
+				Keyring:     keyring,
+				Permissions: permissions,
+				Prefix:      prefix,
+				SecretName:  secretName,
+				Timeout:     timeout,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// description returns the description of the secret's key
+func (kk *KernelKeyringVault) description(secretName string) string {
+	return kk.Prefix + secretName
+}
@@ -0,0 +1,196 @@
+//go:build linux
+
+package vault
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// keyringID returns the serial number of the configured keyring. A process without a session
+// keyring uses the user session keyring, so that keys outlive the terracreds process instead
+// of being added to a new session keyring that only it can see
+func (kk *KernelKeyringVault) keyringID() (int, error) {
+	var id int
+	var err error
+	switch kk.Keyring {
+	case "user":
+		id, err = unix.KeyctlGetKeyringID(unix.KEY_SPEC_USER_KEYRING, true)
+	default:
+		id, err = unix.KeyctlGetKeyringID(unix.KEY_SPEC_SESSION_KEYRING, false)
+	}
+
+	if err != nil {
+		return 0, kernelKeyringError(err, "")
+	}
+
+	return id, nil
+}
+
+// kernelKeyringError converts the errors of the keyctl system calls into the typed errors
+func kernelKeyringError(err error, secretName string) error {
+	switch {
+	case errors.Is(err, unix.ENOKEY), errors.Is(err, unix.EKEYEXPIRED), errors.Is(err, unix.EKEYREVOKED):
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+	case errors.Is(err, unix.EACCES):
+		return fmt.Errorf("%w: the permissions of the key or keyring don't allow it: %w", terraerrors.ErrPermissionDenied, err)
+	case errors.Is(err, unix.ENOSYS), errors.Is(err, unix.EPERM):
+		return fmt.Errorf("%w: the kernel keyring can't be used, the container's seccomp profile may block the keyctl system calls: %w", terraerrors.ErrUnavailable, err)
+	}
+
+	return fmt.Errorf("keyctl: %w", err)
+}
+
+// search returns the serial number of the secret's key
+func (kk *KernelKeyringVault) search(secretName string) (int, error) {
+	ring, err := kk.keyringID()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := unix.KeyctlSearch(ring, "user", kk.description(secretName), 0)
+	if err != nil {
+		return 0, kernelKeyringError(err, secretName)
+	}
+
+	return id, nil
+}
+
+// readKey returns the payload of the key
+func readKey(id int) ([]byte, error) {
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, size)
+	size, err = unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:min(size, len(buf))], nil
+}
+
+// Create adds the secret's key to the keyring or updates its payload, then applies the
+// timeout and the permission mask
+func (kk *KernelKeyringVault) Create(ctx context.Context, secretValue string, method string) error {
+	ring, err := kk.keyringID()
+	if err != nil {
+		return err
+	}
+
+	id, err := unix.AddKey("user", kk.description(kk.SecretName), []byte(secretValue), ring)
+	if err != nil {
+		return kernelKeyringError(err, kk.SecretName)
+	}
+
+	if kk.Timeout > 0 {
+		_, err = unix.KeyctlInt(unix.KEYCTL_SET_TIMEOUT, id, int(kk.Timeout.Seconds()), 0, 0)
+		if err != nil {
+			return kernelKeyringError(err, kk.SecretName)
+		}
+	}
+
+	// The mask is set last since it may take away the permission to change the timeout
+	if kk.Permissions != 0 {
+		err = unix.KeyctlSetperm(id, kk.Permissions)
+		if err != nil {
+			return kernelKeyringError(err, kk.SecretName)
+		}
+	}
+
+	return nil
+}
+
+// Delete invalidates the secret's key, which removes it from every keyring
+func (kk *KernelKeyringVault) Delete(ctx context.Context) error {
+	id, err := kk.search(kk.SecretName)
+	if err != nil {
+		return err
+	}
+
+	_, err = unix.KeyctlInt(unix.KEYCTL_INVALIDATE, id, 0, 0, 0)
+	if err != nil {
+		return kernelKeyringError(err, kk.SecretName)
+	}
+
+	return nil
+}
+
+// Get reads the payload of the secret's key
+func (kk *KernelKeyringVault) Get(ctx context.Context) ([]byte, error) {
+	id, err := kk.search(kk.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := readKey(id)
+	if err != nil {
+		return nil, kernelKeyringError(err, kk.SecretName)
+	}
+
+	return value, nil
+}
+
+// List reads the payloads of the secrets' keys
+func (kk *KernelKeyringVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	for _, secretName := range secretNames {
+		id, err := kk.search(secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := readKey(id)
+		if err != nil {
+			return nil, kernelKeyringError(err, secretName)
+		}
+
+		secretValues = append(secretValues, string(value))
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the secrets whose keys are linked to the keyring. Keys that
+// expired or can't be described are skipped
+func (kk *KernelKeyringVault) Names(ctx context.Context) ([]string, error) {
+	ring, err := kk.keyringID()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := readKey(ring)
+	if err != nil {
+		return nil, kernelKeyringError(err, "")
+	}
+
+	var names []string
+	for i := 0; i+4 <= len(contents); i += 4 {
+		id := int(int32(binary.NativeEndian.Uint32(contents[i:])))
+		description, err := unix.KeyctlString(unix.KEYCTL_DESCRIBE, id)
+		if err != nil {
+			continue
+		}
+
+		// The description is 'type;uid;gid;perm;description'
+		fields := strings.SplitN(description, ";", 5)
+		if len(fields) != 5 || fields[0] != "user" || !strings.HasPrefix(fields[4], kk.Prefix) {
+			continue
+		}
+
+		names = append(names, strings.TrimPrefix(fields[4], kk.Prefix))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,144 @@
+//go:build linux
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// newKernelKeyringVault returns a kernel keyring vault with a prefix unique to the test and
+// removes the test's keys when it ends. The test is skipped when keyctl is blocked
+func newKernelKeyringVault(t *testing.T, keyring string) *KernelKeyringVault {
+	kk := &KernelKeyringVault{
+		Keyring:    keyring,
+		Prefix:     fmt.Sprintf("terracreds-test-%d-%s:", os.Getpid(), t.Name()),
+		SecretName: "app.terraform.io",
+	}
+
+	_, err := kk.keyringID()
+	if errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Skip(err)
+	}
+
+	t.Cleanup(func() {
+		names, _ := kk.Names(context.Background())
+		for _, name := range names {
+			cleanup := *kk
+			cleanup.SecretName = name
+			cleanup.Delete(context.Background())
+		}
+	})
+
+	return kk
+}
+
+func TestKernelKeyringVaultKeyrings(t *testing.T) {
+	ctx := context.Background()
+	for _, keyring := range []string{"session", "user"} {
+		t.Run(keyring, func(t *testing.T) {
+			kk := newKernelKeyringVault(t, keyring)
+			err := kk.Create(ctx, "my-secret-token", "Created")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := kk.Get(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(value) != "my-secret-token" {
+				t.Errorf("expected 'my-secret-token' got '%s'", value)
+			}
+
+			names, err := kk.Names(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(names, []string{"app.terraform.io"}) {
+				t.Errorf("expected the key in the %s keyring got %q", keyring, names)
+			}
+		})
+	}
+}
+
+func TestKernelKeyringVaultPermissions(t *testing.T) {
+	ctx := context.Background()
+	kk := newKernelKeyringVault(t, "session")
+	kk.Permissions = 0x3f0b0000
+
+	err := kk.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := kk.search(kk.SecretName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	description, err := unix.KeyctlString(unix.KEYCTL_DESCRIBE, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fields := strings.Split(description, ";"); fields[3] != "3f0b0000" {
+		t.Errorf("expected the permissions '3f0b0000' got '%s'", fields[3])
+	}
+}
+
+func TestKernelKeyringVaultTimeout(t *testing.T) {
+	ctx := context.Background()
+	kk := newKernelKeyringVault(t, "session")
+	kk.Timeout = time.Second
+
+	err := kk.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		_, err = kk.Get(ctx)
+		if errors.Is(err, terraerrors.ErrNotFound) {
+			return
+		}
+	}
+
+	t.Errorf("expected the key to expire after its timeout got %v", err)
+}
+
+func TestKernelKeyringVaultConfig(t *testing.T) {
+	provider, ok := Lookup("kernel-keyring")
+	if !ok {
+		t.Fatal("the 'kernel-keyring' provider is not registered")
+	}
+
+	terraVault, err := provider.New(ProviderConfig{"permissions": "0x3f010000", "timeout": "2h"}, "app.terraform.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kk := terraVault.(*KernelKeyringVault)
+	if kk.Keyring != "session" || kk.Permissions != 0x3f010000 || kk.Timeout != 2*time.Hour || kk.Prefix != DefaultKernelKeyringPrefix {
+		t.Errorf("expected the configured settings got %+v", kk)
+	}
+
+	for _, config := range []ProviderConfig{{"keyring": "thread"}, {"keyring": "process"}, {"permissions": "rwx"}, {"timeout": "500ms"}} {
+		_, err := provider.New(config, "app.terraform.io")
+		if err == nil {
+			t.Errorf("expected the configuration %v to be rejected", config)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+//go:build !linux
+
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// errKernelKeyring is returned by every operation on platforms without a kernel keyring
+var errKernelKeyring = fmt.Errorf("%w: the kernel keyring is only available on Linux", terraerrors.ErrUnavailable)
+
+// Create is not supported on this platform
+func (kk *KernelKeyringVault) Create(ctx context.Context, secretValue string, method string) error {
+	return errKernelKeyring
+}
+
+// Delete is not supported on this platform
+func (kk *KernelKeyringVault) Delete(ctx context.Context) error {
+	return errKernelKeyring
+}
+
+// Get is not supported on this platform
+func (kk *KernelKeyringVault) Get(ctx context.Context) ([]byte, error) {
+	return nil, errKernelKeyring
+}
+
+// List is not supported on this platform
+func (kk *KernelKeyringVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	return nil, errKernelKeyring
+}
+
+// Names is not supported on this platform
+func (kk *KernelKeyringVault) Names(ctx context.Context) ([]string, error) {
+	return nil, errKernelKeyring
+}
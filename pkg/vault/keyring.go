@@ -0,0 +1,129 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/user"
+
+	"github.com/zalando/go-keyring"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// Keyring is the local operating system's credential vault. It's used when the
+// vault is part of a Chain, otherwise the platform handles the keyring directly
+type Keyring struct {
+	SecretName string
+	User       string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "keyring",
+		Title: "the local operating system's credential vault",
+		Usage: "The local operating system's credential vault",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			user, err := user.Current()
+			if err != nil {
+				return nil, err
+			}
+
+			vault := &Keyring{
+				SecretName: secretName,
+				User:       user.Username,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// keyringError converts a keyring not found error into ErrNotFound, and the error of a keyring
+// that can't be reached into ErrUnavailable
+func keyringError(err error, secretName string) error {
+	if err == keyring.ErrNotFound {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+	}
+
+	if KeyringUnavailable(err) {
+		return wrapError(terraerrors.ErrUnavailable, err, secretName)
+	}
+
+	return err
+}
+
+// KeyringUnavailable reports whether the error of the local operating system's credential vault
+// is returned because the vault can't be reached or can't be unlocked, rather than because
+// access to the secret is denied
+func KeyringUnavailable(err error) bool {
+	if err == nil || err == keyring.ErrNotFound {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return true
+	}
+
+	return keyringUnreachable(err)
+}
+
+// Create stores a secret in the operating system's credential vault
+func (kr *Keyring) Create(ctx context.Context, secretValue string, method string) error {
+	err := keyring.Set(kr.SecretName, kr.User, secretValue)
+	if err != nil {
+		return keyringError(err, kr.SecretName)
+	}
+
+	return nil
+}
+
+// Delete removes a secret from the operating system's credential vault
+func (kr *Keyring) Delete(ctx context.Context) error {
+	err := keyring.Delete(kr.SecretName, kr.User)
+	return keyringError(err, kr.SecretName)
+}
+
+// Get retrieves a secret from the operating system's credential vault
+func (kr *Keyring) Get(ctx context.Context) ([]byte, error) {
+	secret, err := keyring.Get(kr.SecretName, kr.User)
+	if err != nil {
+		return nil, keyringError(err, kr.SecretName)
+	}
+
+	return []byte(secret), nil
+}
+
+// List retrieves the secrets from the operating system's credential vault
+func (kr *Keyring) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	for _, secret := range secretNames {
+		value, err := keyring.Get(secret, kr.User)
+		if err != nil {
+			return nil, keyringError(err, secret)
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// SecretServiceAvailable reports whether the local operating system's credential vault can be
+// reached. On Linux it's the Secret Service D-Bus API, which headless hosts and containers
+// usually don't run. It's only unavailable when the session bus or the Secret Service can't be
+// reached, and other errors such as a locked collection or a dismissed unlock prompt are returned
+func SecretServiceAvailable() (bool, error) {
+	_, err := keyring.Get("terracreds", "terracreds-probe")
+	if err == nil || err == keyring.ErrNotFound {
+		return true, nil
+	}
+
+	if secretServiceUnreachable() {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("%w: the Secret Service: %w", terraerrors.ErrUnavailable, err)
+}
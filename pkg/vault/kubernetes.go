@@ -0,0 +1,393 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+const (
+	// DefaultKubernetesKey is the key that holds the value when each secret has its own Secret
+	DefaultKubernetesKey = "token"
+
+	// kubernetesPageSize is the most Secrets that are listed in a single request
+	kubernetesPageSize = 250
+
+	// kubernetesManagedBy is the label that marks the Secrets that terracreds manages
+	kubernetesManagedBy = "app.kubernetes.io/managed-by"
+)
+
+// KubernetesVault stores secrets in Kubernetes Secrets of a namespace. Each secret is either a
+// key of a single Secret, or has a Secret of its own that's found by its labels
+type KubernetesVault struct {
+	Context    string
+	Key        string
+	Kubeconfig string
+	Labels     string
+	Namespace  string
+	Prefix     string
+	Secret     string
+	SecretName string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "kubernetes",
+		Key:   "kubernetes",
+		Title: "Kubernetes Secrets",
+		Usage: "Kubernetes Secrets provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "context",
+				Key:   "context",
+				Usage: "The kubeconfig context to use. If omitted the current context is used",
+			},
+			{
+				Name:  "key",
+				Key:   "key",
+				Usage: fmt.Sprintf("The key that holds the value when each secret has its own Secret. If omitted '%s' is used", DefaultKubernetesKey),
+			},
+			{
+				Name:  "kubeconfig",
+				Key:   "kubeconfig",
+				Usage: "The path to the kubeconfig file. If omitted 'KUBECONFIG' or '~/.kube/config' is used, or the in-cluster configuration when neither exists",
+			},
+			{
+				Name:  "labels",
+				Key:   "labels",
+				Usage: "The labels to apply to the Secrets such as 'team=platform,env=prod'. They're also used to find the Secrets",
+			},
+			{
+				Name:  "namespace",
+				Key:   "namespace",
+				Usage: "The namespace of the Secrets. If omitted the namespace of the context or of the pod is used",
+			},
+			{
+				Name:  "prefix",
+				Key:   "prefix",
+				Usage: "The prefix of the Secret names when each secret has its own Secret such as 'terracreds-'",
+			},
+			{
+				Name:  "secret",
+				Key:   "secret",
+				Usage: "The name of a single Secret that holds each secret as a key. If omitted each secret has its own Secret",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &KubernetesVault{
+				Context:    config.String("context"),
+				Key:        config.String("key"),
+				Kubeconfig: config.String("kubeconfig"),
+				Labels:     config.String("labels"),
+				Namespace:  config.String("namespace"),
+				Prefix:     config.String("prefix"),
+				Secret:     config.String("secret"),
+				SecretName: secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// newSecretsClient returns a client for the Secrets of the namespace. The kubeconfig is loaded
+// the way kubectl loads it, and the in-cluster configuration is used when there's none
+func (kv *KubernetesVault) newSecretsClient() (typedcorev1.SecretInterface, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kv.Kubeconfig != "" {
+		path, err := homedir.Expand(kv.Kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+
+		rules.ExplicitPath = path
+	}
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: kv.Context,
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the Kubernetes configuration: %w", err)
+	}
+
+	namespace := kv.Namespace
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return clientset.CoreV1().Secrets(namespace), nil
+}
+
+// kubernetesError converts a Kubernetes API error into the kind of failure it represents
+func kubernetesError(err error, name string) error {
+	var statusErr *apierrors.StatusError
+	if errors.As(err, &statusErr) {
+		return statusError(int(statusErr.Status().Code), err, name)
+	}
+
+	return transportError(err, name)
+}
+
+// labels returns the configured labels and the label that marks the Secrets terracreds manages
+func (kv *KubernetesVault) labels() (labels.Set, error) {
+	set, err := labels.ConvertSelectorToLabelsMap(kv.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("the labels '%s' are not valid: %w", kv.Labels, err)
+	}
+
+	set[kubernetesManagedBy] = "terracreds"
+	return set, nil
+}
+
+// key returns the key of the secret's value in its Secret
+func (kv *KubernetesVault) key(secretName string) string {
+	if kv.Secret != "" {
+		return secretName
+	}
+
+	if kv.Key == "" {
+		return DefaultKubernetesKey
+	}
+
+	return kv.Key
+}
+
+// name returns the name of the Secret that holds the secret
+func (kv *KubernetesVault) name(secretName string) string {
+	if kv.Secret != "" {
+		return kv.Secret
+	}
+
+	return kv.Prefix + secretName
+}
+
+// value reads the secret from its Secret
+func (kv *KubernetesVault) value(ctx context.Context, client typedcorev1.SecretInterface, secretName string) ([]byte, error) {
+	name := kv.name(secretName)
+	secret, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, kubernetesError(err, name)
+	}
+
+	value, ok := secret.Data[kv.key(secretName)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s/%s", terraerrors.ErrNotFound, name, kv.key(secretName))
+	}
+
+	return value, nil
+}
+
+// Create stores the secret and applies the labels to its Secret. Secrets are updated with the
+// resource version they were read at, so a concurrent change returns ErrConflict
+func (kv *KubernetesVault) Create(ctx context.Context, secretValue string, method string) error {
+	client, err := kv.newSecretsClient()
+	if err != nil {
+		return err
+	}
+
+	set, err := kv.labels()
+	if err != nil {
+		return err
+	}
+
+	name := kv.name(kv.SecretName)
+	secret, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: set,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{
+				kv.key(kv.SecretName): []byte(secretValue),
+			},
+		}
+
+		_, err = client.Create(ctx, secret, metav1.CreateOptions{})
+		if err != nil {
+			return kubernetesError(err, name)
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return kubernetesError(err, name)
+	}
+
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+
+	for label, value := range set {
+		secret.Labels[label] = value
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+
+	secret.Data[kv.key(kv.SecretName)] = []byte(secretValue)
+	_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return kubernetesError(err, name)
+	}
+
+	return nil
+}
+
+// Delete removes the secret's key from the single Secret, or removes the secret's own Secret.
+// A Secret of its own is only removed when it has the label that marks the Secrets terracreds
+// manages, and when it wasn't changed since it was read
+func (kv *KubernetesVault) Delete(ctx context.Context) error {
+	client, err := kv.newSecretsClient()
+	if err != nil {
+		return err
+	}
+
+	name := kv.name(kv.SecretName)
+	secret, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return kubernetesError(err, name)
+	}
+
+	if kv.Secret == "" {
+		if secret.Labels[kubernetesManagedBy] != "terracreds" {
+			err = fmt.Errorf("the Secret doesn't have the '%s=terracreds' label and isn't removed", kubernetesManagedBy)
+			return wrapError(terraerrors.ErrPermissionDenied, err, name)
+		}
+
+		err = client.Delete(ctx, name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{ResourceVersion: &secret.ResourceVersion},
+		})
+
+		if err != nil {
+			return kubernetesError(err, name)
+		}
+
+		return nil
+	}
+
+	if _, ok := secret.Data[kv.SecretName]; !ok {
+		return fmt.Errorf("%w: %s/%s", terraerrors.ErrNotFound, name, kv.SecretName)
+	}
+
+	delete(secret.Data, kv.SecretName)
+	_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return kubernetesError(err, name)
+	}
+
+	return nil
+}
+
+func (kv *KubernetesVault) Get(ctx context.Context) ([]byte, error) {
+	client, err := kv.newSecretsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.value(ctx, client, kv.SecretName)
+}
+
+func (kv *KubernetesVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	client, err := kv.newSecretsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, err := kv.value(ctx, client, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		secretValues = append(secretValues, string(value))
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the keys of the single Secret, or the names of the Secrets that have the
+// labels and the prefix
+func (kv *KubernetesVault) Names(ctx context.Context) ([]string, error) {
+	client, err := kv.newSecretsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	if kv.Secret != "" {
+		secret, err := client.Get(ctx, kv.Secret, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return names, nil
+		}
+
+		if err != nil {
+			return nil, kubernetesError(err, kv.Secret)
+		}
+
+		for key := range secret.Data {
+			names = append(names, key)
+		}
+
+		sort.Strings(names)
+		return names, nil
+	}
+
+	set, err := kv.labels()
+	if err != nil {
+		return nil, err
+	}
+
+	options := metav1.ListOptions{LabelSelector: set.String(), Limit: kubernetesPageSize}
+	for {
+		secrets, err := client.List(ctx, options)
+		if err != nil {
+			return nil, kubernetesError(err, set.String())
+		}
+
+		for _, secret := range secrets.Items {
+			if _, ok := secret.Data[kv.key(secret.Name)]; !ok || !strings.HasPrefix(secret.Name, kv.Prefix) {
+				continue
+			}
+
+			names = append(names, strings.TrimPrefix(secret.Name, kv.Prefix))
+		}
+
+		if secrets.Continue == "" {
+			break
+		}
+
+		options.Continue = secrets.Continue
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
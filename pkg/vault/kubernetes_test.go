@@ -0,0 +1,147 @@
+package vault_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+func TestKubernetesSecretPerName(t *testing.T) {
+	ctx := context.Background()
+	fake := vaulttest.NewKubernetesAPI(t, "vaulttest")
+	config := vault.ProviderConfig{
+		"key":        "tfe-token",
+		"kubeconfig": fake.Kubeconfig(t, "atlantis"),
+		"labels":     "team=platform",
+		"prefix":     "terracreds-",
+	}
+
+	for _, name := range []string{"app.terraform.io", "tfe.example.com"} {
+		err := newVault(t, "kubernetes", config, name).Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	secret, ok := fake.Secret("atlantis", "terracreds-app.terraform.io")
+	if !ok {
+		t.Fatal("expected a Secret in the namespace of the context")
+	}
+
+	expected := map[string]string{"app.kubernetes.io/managed-by": "terracreds", "team": "platform"}
+	if !reflect.DeepEqual(secret.Labels, expected) {
+		t.Errorf("expected the labels %v got %v", expected, secret.Labels)
+	}
+
+	if string(secret.Data["tfe-token"]) != "token-app.terraform.io" || secret.Type != corev1.SecretTypeOpaque {
+		t.Errorf("expected an Opaque Secret with the value in the key got %+v", secret)
+	}
+
+	// Secrets of another team, or that terracreds doesn't manage, aren't discovered
+	fake.Put(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "terracreds-other.example.com", Namespace: "atlantis", Labels: map[string]string{"app.kubernetes.io/managed-by": "terracreds", "team": "data"}},
+		Data:       map[string][]byte{"tfe-token": []byte("other-token")},
+	})
+
+	fake.Put(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "terracreds-unmanaged.example.com", Namespace: "atlantis"},
+		Data:       map[string][]byte{"tfe-token": []byte("unmanaged-token")},
+	})
+
+	names, err := newVault(t, "kubernetes", config, "").(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "tfe.example.com"}) {
+		t.Errorf("expected the names of the labeled Secrets got %q", names)
+	}
+
+	err = newVault(t, "kubernetes", config, "unmanaged.example.com").Delete(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied for a Secret that terracreds doesn't manage got %v", err)
+	}
+
+	if _, ok := fake.Secret("atlantis", "terracreds-unmanaged.example.com"); !ok {
+		t.Error("expected the Secret that terracreds doesn't manage to be kept")
+	}
+
+	err = newVault(t, "kubernetes", config, "tfe.example.com").Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Secret("atlantis", "terracreds-tfe.example.com"); ok {
+		t.Error("expected the managed Secret to be removed")
+	}
+
+	config["namespace"] = "other"
+	_, err = newVault(t, "kubernetes", config, "app.terraform.io").Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound in another namespace got %v", err)
+	}
+}
+
+func TestKubernetesSingleSecret(t *testing.T) {
+	ctx := context.Background()
+	fake := vaulttest.NewKubernetesAPI(t, "vaulttest")
+	fake.Put(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfe-tokens", Namespace: "flux-system", Labels: map[string]string{"app": "tf-controller"}},
+		Data:       map[string][]byte{"existing.example.com": []byte("existing-token")},
+	})
+
+	config := vault.ProviderConfig{
+		"kubeconfig": fake.Kubeconfig(t, "default"),
+		"namespace":  "flux-system",
+		"secret":     "tfe-tokens",
+	}
+
+	terraVault := newVault(t, "kubernetes", config, "app.terraform.io")
+	err := terraVault.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, _ := fake.Secret("flux-system", "tfe-tokens")
+	if secret.Labels["app"] != "tf-controller" || secret.Labels["app.kubernetes.io/managed-by"] != "terracreds" {
+		t.Errorf("expected the labels to be merged got %v", secret.Labels)
+	}
+
+	names, err := terraVault.(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "existing.example.com"}) {
+		t.Errorf("expected the keys of the Secret got %q", names)
+	}
+
+	err = terraVault.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, ok := fake.Secret("flux-system", "tfe-tokens")
+	if !ok || string(secret.Data["existing.example.com"]) != "existing-token" || len(secret.Data) != 1 {
+		t.Errorf("expected only the key to be removed got %+v", secret)
+	}
+}
+
+func TestKubernetesUnauthorized(t *testing.T) {
+	fake := vaulttest.NewKubernetesAPI(t, "vaulttest")
+	kubeconfig := fake.Kubeconfig(t, "default")
+	fake.Token = "rotated"
+
+	_, err := newVault(t, "kubernetes", vault.ProviderConfig{"kubeconfig": kubeconfig}, "app.terraform.io").Get(context.Background())
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied got %v", err)
+	}
+}
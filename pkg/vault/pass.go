@@ -0,0 +1,343 @@
+package vault
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// PassVault stores secrets in a pass password store, a directory tree of files encrypted with GPG
+// to the recipients in the store's .gpg-id files. Changes are committed when the store is a git repository
+type PassVault struct {
+	Prefix     string
+	SecretName string
+	StoreDir   string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "pass",
+		Key:   "pass",
+		Title: "the pass password store",
+		Usage: "pass password store provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "prefix",
+				Key:   "prefix",
+				Usage: "The folder inside of the password store that holds the secrets such as 'terraform'. If omitted the secrets are stored at the root of the store",
+			},
+			{
+				Name:  "store-dir",
+				Key:   "storeDir",
+				Usage: "The path of the password store. If omitted PASSWORD_STORE_DIR or '~/.password-store' is used",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &PassVault{
+				Prefix:     config.String("prefix"),
+				SecretName: secretName,
+				StoreDir:   config.String("storeDir"),
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// storeDir returns the path of the password store
+func (pv *PassVault) storeDir() (string, error) {
+	dir := pv.StoreDir
+	if dir == "" {
+		dir = os.Getenv("PASSWORD_STORE_DIR")
+	}
+
+	if dir == "" {
+		dir = "~/.password-store"
+	}
+
+	return homedir.Expand(dir)
+}
+
+// entry returns the path of the password store and the path of the secret's file inside of it
+// relative to the store. Subfolders in the secret name are kept as namespaces
+func (pv *PassVault) entry(secretName string) (string, string, error) {
+	dir, err := pv.storeDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	name := filepath.ToSlash(filepath.Join(pv.Prefix, secretName))
+	if secretName == "" || !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", "", fmt.Errorf("the secret name '%s' is not a valid path in the password store", secretName)
+	}
+
+	return dir, filepath.FromSlash(name) + ".gpg", nil
+}
+
+// recipients returns the GPG recipients from the .gpg-id file nearest to the secret's folder
+func recipients(dir string, entry string) ([]string, error) {
+	folder := filepath.Dir(filepath.Join(dir, entry))
+	for {
+		file, err := os.Open(filepath.Join(folder, ".gpg-id"))
+		if err == nil {
+			defer file.Close()
+
+			var ids []string
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				id := strings.TrimSpace(scanner.Text())
+				if id != "" && !strings.HasPrefix(id, "#") {
+					ids = append(ids, id)
+				}
+			}
+
+			return ids, scanner.Err()
+		}
+
+		if folder == dir || !strings.HasPrefix(folder, dir) {
+			return nil, fmt.Errorf("the password store '%s' has no .gpg-id file. Run 'pass init' to initialize it", dir)
+		}
+
+		folder = filepath.Dir(folder)
+	}
+}
+
+// gpg runs gpg with the options from PASSWORD_STORE_GPG_OPTS and returns its stdout
+func gpg(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	options := []string{"--quiet", "--yes", "--batch", "--compress-algo=none", "--no-encrypt-to"}
+	options = append(options, strings.Fields(os.Getenv("PASSWORD_STORE_GPG_OPTS"))...)
+
+	cmd := exec.CommandContext(ctx, "gpg", append(options, args...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: gpg: %w", terraerrors.ErrUnavailable, ctx.Err())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("gpg: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// isGitRepo reports whether the password store is a git repository
+func isGitRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// git runs git in the password store
+func git(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// commit commits the staged change to the secret's file. Nothing is committed when the
+// file has no staged change, such as a secret that was never committed
+func commit(ctx context.Context, dir string, entry string, message string) error {
+	err := exec.CommandContext(ctx, "git", "-C", dir, "diff", "--cached", "--quiet", "--", entry).Run()
+	if err == nil {
+		return nil
+	}
+
+	return git(ctx, dir, "commit", "--quiet", "-m", message, "--", entry)
+}
+
+// Create encrypts the secret to the store's recipients and commits it
+func (pv *PassVault) Create(ctx context.Context, secretValue string, method string) error {
+	dir, entry, err := pv.entry(pv.SecretName)
+	if err != nil {
+		return err
+	}
+
+	ids, err := recipients(dir, entry)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, entry)
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt"}
+	for _, id := range ids {
+		args = append(args, "--recipient", id)
+	}
+
+	ciphertext, err := gpg(ctx, []byte(secretValue+"\n"), args...)
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(path, ciphertext)
+	if err != nil {
+		return err
+	}
+
+	if !isGitRepo(dir) {
+		return nil
+	}
+
+	err = git(ctx, dir, "add", "--", entry)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSuffix(filepath.ToSlash(entry), ".gpg")
+	return commit(ctx, dir, entry, fmt.Sprintf("Add given password for %s to store.", name))
+}
+
+// Delete removes the secret from the store and commits the removal
+func (pv *PassVault) Delete(ctx context.Context) error {
+	dir, entry, err := pv.entry(pv.SecretName)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, entry)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, pv.SecretName)
+	}
+
+	if isGitRepo(dir) {
+		err = git(ctx, dir, "rm", "--quiet", "--cached", "--ignore-unmatch", "--", entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return err
+	}
+
+	// Remove the folders the secret leaves empty, like pass does
+	for folder := filepath.Dir(path); folder != dir && strings.HasPrefix(folder, dir); folder = filepath.Dir(folder) {
+		if os.Remove(folder) != nil {
+			break
+		}
+	}
+
+	if !isGitRepo(dir) {
+		return nil
+	}
+
+	name := strings.TrimSuffix(filepath.ToSlash(entry), ".gpg")
+	return commit(ctx, dir, entry, fmt.Sprintf("Remove %s from store.", name))
+}
+
+// decrypt returns the first line of the secret's file, which is the password by pass's convention
+func (pv *PassVault) decrypt(ctx context.Context, secretName string) (string, error) {
+	dir, entry, err := pv.entry(secretName)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, entry)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+	}
+
+	plaintext, err := gpg(ctx, nil, "--decrypt", path)
+	if err != nil {
+		if terraerrors.ExitCode(err) == terraerrors.ExitUnavailable {
+			return "", err
+		}
+
+		return "", fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
+	}
+
+	password, _, _ := strings.Cut(string(plaintext), "\n")
+	return strings.TrimSuffix(password, "\r"), nil
+}
+
+// Get decrypts the secret
+func (pv *PassVault) Get(ctx context.Context) ([]byte, error) {
+	value, err := pv.decrypt(ctx, pv.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// List decrypts the secrets
+func (pv *PassVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, err := pv.decrypt(ctx, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names walks the store below the prefix and returns the names of the secrets in it
+func (pv *PassVault) Names(ctx context.Context) ([]string, error) {
+	dir, err := pv.storeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(dir, pv.Prefix)
+	var names []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return nil
+			}
+
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".gpg") {
+			return nil
+		}
+
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(name), ".gpg"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
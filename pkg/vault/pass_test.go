@@ -0,0 +1,229 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault/internal/gpgtest"
+)
+
+// newPassStore generates a GPG key in a temporary home and returns a password store that's
+// initialized for it and is a git repository
+func newPassStore(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	gpgtest.NewKey(t)
+
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "Terracreds Test")
+	}
+
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "test@example.com")
+	}
+
+	store := t.TempDir()
+	err := os.WriteFile(filepath.Join(store, ".gpg-id"), []byte("test@example.com\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, store, "git", "init", "--quiet")
+	run(t, store, "git", "add", ".gpg-id")
+	run(t, store, "git", "commit", "--quiet", "-m", "Set GPG id to test@example.com.")
+	return store
+}
+
+// run runs the command in the directory and returns its output
+func run(t *testing.T, dir string, name string, args ...string) string {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %s: %v: %s", name, strings.Join(args, " "), err, output)
+	}
+
+	return string(output)
+}
+
+func TestPassVaultCommits(t *testing.T) {
+	ctx := context.Background()
+	store := newPassStore(t)
+	pv := &PassVault{
+		Prefix:     "terraform",
+		SecretName: "app.terraform.io",
+		StoreDir:   store,
+	}
+
+	err := pv.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(store, "terraform", "app.terraform.io.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(contents, []byte("my-secret-token")) {
+		t.Error("expected the entry to be encrypted")
+	}
+
+	err = pv.Create(ctx, "my-secret-token", "Updated")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pv.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store, "terraform")); !os.IsNotExist(err) {
+		t.Error("expected the empty prefix folder to be removed")
+	}
+
+	log := strings.Split(strings.TrimSpace(run(t, store, "git", "log", "--format=%s")), "\n")
+	expected := []string{
+		"Remove terraform/app.terraform.io from store.",
+		"Add given password for terraform/app.terraform.io to store.",
+		"Add given password for terraform/app.terraform.io to store.",
+		"Set GPG id to test@example.com.",
+	}
+
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected the commits %q got %q", expected, log)
+	}
+
+	if status := run(t, store, "git", "status", "--porcelain"); status != "" {
+		t.Errorf("expected a clean work tree got:\n%s", status)
+	}
+}
+
+func TestPassVaultRecipients(t *testing.T) {
+	ctx := context.Background()
+	store := newPassStore(t)
+
+	// A subfolder that's encrypted to a recipient without a key can't be written to
+	err := os.MkdirAll(filepath.Join(store, "team"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(store, "team", ".gpg-id"), []byte("# the team\nnobody@example.com\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := recipients(store, filepath.Join("team", "nested", "app.terraform.io.gpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []string{"nobody@example.com"}) {
+		t.Errorf("expected the recipients of the subfolder got %q", ids)
+	}
+
+	pv := &PassVault{
+		Prefix:     "team",
+		SecretName: "app.terraform.io",
+		StoreDir:   store,
+	}
+
+	err = pv.Create(ctx, "my-secret-token", "Created")
+	if err == nil {
+		t.Error("expected encrypting to a recipient without a key to fail")
+	}
+
+	err = os.Remove(filepath.Join(store, ".gpg-id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pv.Prefix = ""
+	err = pv.Create(ctx, "my-secret-token", "Created")
+	if err == nil || !strings.Contains(err.Error(), "pass init") {
+		t.Errorf("expected a store without a .gpg-id file to fail got %v", err)
+	}
+}
+
+func TestPassVaultNames(t *testing.T) {
+	ctx := context.Background()
+	store := newPassStore(t)
+
+	for _, name := range []string{"app.terraform.io", "tfe/prod.example.com", "tfe/dev.example.com"} {
+		pv := &PassVault{
+			Prefix:     "terraform",
+			SecretName: name,
+			StoreDir:   store,
+		}
+
+		err := pv.Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := &PassVault{
+		SecretName: "email/personal",
+		StoreDir:   store,
+	}
+
+	err := other.Create(ctx, "hunter2", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pv := &PassVault{
+		Prefix:   "terraform",
+		StoreDir: store,
+	}
+
+	names, err := pv.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"app.terraform.io", "tfe/dev.example.com", "tfe/prod.example.com"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected the names %q got %q", expected, names)
+	}
+
+	values, err := pv.List(ctx, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values[1] != "token-tfe/dev.example.com" {
+		t.Errorf("expected the value of 'tfe/dev.example.com' got '%s'", values[1])
+	}
+
+	pv.Prefix = "missing"
+	names, err = pv.Names(ctx)
+	if err != nil || len(names) != 0 {
+		t.Errorf("expected no names in a missing prefix got %q, %v", names, err)
+	}
+}
+
+func TestPassVaultInvalidName(t *testing.T) {
+	pv := &PassVault{
+		SecretName: "../outside",
+		StoreDir:   t.TempDir(),
+	}
+
+	_, err := pv.Get(context.Background())
+	if err == nil || errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected a name outside of the store to be rejected got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/tonedefdev/terracreds/pkg/plugin"
+)
+
+// PluginPrefix is the prefix of the name of a plugin binary such as 'terracreds-provider-acme'
+const PluginPrefix = "terracreds-provider-"
+
+// Log receives messages from vault providers, such as the lines a plugin writes to stderr.
+// It's set by terracreds to write to its log
+var Log = func(msg string, level string) {}
+
+// PluginVault is a vault provider implemented by an external plugin binary that speaks the
+// JSON protocol of the plugin package over stdin and stdout
+type PluginVault struct {
+	Client     *plugin.Client
+	SecretName string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "plugin",
+		Key:   "plugin",
+		Title: "an external provider plugin",
+		Usage: "External provider plugin configuration settings",
+		Flags: []Flag{
+			{
+				Name:     "name",
+				Key:      "name",
+				Usage:    "The name of the plugin. Terracreds runs the 'terracreds-provider-<name>' binary found in the PATH or next to terracreds",
+				Required: true,
+			},
+			{
+				Name:  "path",
+				Key:   "path",
+				Usage: "The path to the plugin binary. If omitted Terracreds will look up the binary by the name of the plugin",
+			},
+			{
+				Name:  "secret-name",
+				Key:   "secretName",
+				Usage: "The name of the secret stored by the plugin. If omitted Terracreds will use the hostname value instead",
+			},
+		},
+		SecretNameKey: "secretName",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			name := config.String("name")
+			path, err := pluginPath(name, config.String("path"))
+			if err != nil {
+				return nil, err
+			}
+
+			vault := &PluginVault{
+				Client: &plugin.Client{
+					Name:   name,
+					Path:   path,
+					Config: config,
+					Stderr: func(line string) {
+						Log(fmt.Sprintf("- plugin '%s': %s", name, line), "INFO")
+					},
+				},
+				SecretName: secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// pluginPath returns the path to the plugin binary. The binary is looked up in the PATH and then
+// in the directory of the terracreds binary when no path is set
+func pluginPath(name string, path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("the name of the plugin is not set in the 'plugin' block")
+	}
+
+	binary := PluginPrefix + name
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	path, err := exec.LookPath(binary)
+	if err == nil {
+		return path, nil
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		path = filepath.Join(filepath.Dir(executable), binary)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("the plugin binary '%s' was not found in the PATH or next to terracreds", binary)
+}
+
+// Create stores a secret with the plugin
+func (pv *PluginVault) Create(ctx context.Context, secretValue string, method string) error {
+	return pv.Client.Create(ctx, pv.SecretName, secretValue, method)
+}
+
+// Delete removes a secret with the plugin
+func (pv *PluginVault) Delete(ctx context.Context) error {
+	return pv.Client.Delete(ctx, pv.SecretName)
+}
+
+// Get retrieves a secret from the plugin
+func (pv *PluginVault) Get(ctx context.Context) ([]byte, error) {
+	secret, err := pv.Client.Get(ctx, pv.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(secret), nil
+}
+
+// List retrieves the secrets from the plugin
+func (pv *PluginVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	return pv.Client.List(ctx, secretNames)
+}
@@ -0,0 +1,156 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Flag describes a command line flag that sets a key in a vault provider's configuration block
+type Flag struct {
+	// Name is the name of the flag such as 'vault-uri'
+	Name string
+
+	// Aliases are the alternate names of the flag
+	Aliases []string
+
+	// Key is the key in the configuration block such as 'vaultUri'
+	Key string
+
+	// Usage is the help text of the flag
+	Usage string
+
+	// Required marks a flag that must be set when the provider is configured
+	Required bool
+
+	// Bool marks a flag that's set without a value, otherwise the flag takes a string
+	Bool bool
+}
+
+// Provider describes a vault provider. Each provider registers itself with Register,
+// and the configuration file, the 'config' command and the fallback chain are built
+// from the registered providers
+type Provider struct {
+	// Name is the name of the provider used on the command line and in a fallback chain
+	Name string
+
+	// Key is the key of the provider's configuration block in the configuration file. A provider
+	// without a key has no settings, such as the local operating system's credential vault
+	Key string
+
+	// Title is the display name of the provider such as 'AWS Secrets Manager'
+	Title string
+
+	// Usage is the help text of the provider's 'config' command
+	Usage string
+
+	// Flags are the settings of the provider's configuration block
+	Flags []Flag
+
+	// SecretNameKey is the key in the configuration block that overrides the name of the secret
+	SecretNameKey string
+
+	// New creates the provider's TerraVault from its configuration block and the name of the secret
+	New func(config ProviderConfig, secretName string) (TerraVault, error)
+}
+
+// ProviderConfig is a vault provider's configuration block as read from the configuration file
+type ProviderConfig map[string]interface{}
+
+// String returns the value of the key as a string, or an empty string when it's not set
+func (config ProviderConfig) String(key string) string {
+	value, ok := config[key]
+	if !ok || value == nil {
+		return ""
+	}
+
+	if str, ok := value.(string); ok {
+		return str
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// Bool returns the value of the key as a bool, or false when it's not set
+func (config ProviderConfig) Bool(key string) bool {
+	value, ok := config[key].(bool)
+	return ok && value
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Provider)
+)
+
+// Register makes a vault provider available by its name. It panics if the provider
+// is incomplete or a provider with the same name or key has already been registered
+func Register(provider *Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if provider == nil || provider.Name == "" || provider.New == nil {
+		panic("vault: Register provider must have a name and a New function")
+	}
+
+	if _, dup := registry[provider.Name]; dup {
+		panic(fmt.Sprintf("vault: Register called twice for provider '%s'", provider.Name))
+	}
+
+	for _, registered := range registry {
+		if provider.Key != "" && registered.Key == provider.Key {
+			panic(fmt.Sprintf("vault: Register called twice for the configuration key '%s'", provider.Key))
+		}
+	}
+
+	registry[provider.Name] = provider
+}
+
+// Lookup returns the vault provider registered with the name
+func Lookup(name string) (*Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	provider, ok := registry[name]
+	return provider, ok
+}
+
+// LookupKey returns the vault provider whose configuration block has the key
+func LookupKey(key string) (*Provider, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	for _, provider := range registry {
+		if provider.Key != "" && provider.Key == key {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+// Registered returns the registered vault providers sorted by name
+func Registered() []*Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providers := make([]*Provider, 0, len(registry))
+	for _, provider := range registry {
+		providers = append(providers, provider)
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+
+	return providers
+}
+
+// Names returns the names of the registered vault providers sorted by name
+func Names() []string {
+	var names []string
+	for _, provider := range Registered() {
+		names = append(names, provider.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,51 @@
+package vault
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRegistered(t *testing.T) {
+	names := Names()
+	for _, name := range []string{"aws", "azure", "gcp", "hashicorp", "keyring", "plugin"} {
+		if !slices.Contains(names, name) {
+			t.Errorf("expected the '%s' provider to be registered: %v", name, names)
+		}
+	}
+
+	if !slices.IsSorted(names) {
+		t.Errorf("expected the providers to be sorted by name: %v", names)
+	}
+
+	provider, ok := LookupKey("hcvault")
+	if !ok || provider.Name != "hashicorp" {
+		t.Errorf("expected the 'hcvault' block to belong to the 'hashicorp' provider")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Register to panic for a duplicate provider")
+		}
+	}()
+
+	Register(&Provider{
+		Name: "aws",
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			return nil, nil
+		},
+	})
+}
+
+func TestProviderConfig(t *testing.T) {
+	config := ProviderConfig{"region": "us-east-1", "port": 8200, "tls": true}
+
+	if config.String("region") != "us-east-1" || config.String("port") != "8200" || config.String("missing") != "" {
+		t.Errorf("unexpected string values from %v", config)
+	}
+
+	if !config.Bool("tls") || config.Bool("region") {
+		t.Errorf("unexpected bool values from %v", config)
+	}
+}
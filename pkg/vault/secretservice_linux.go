@@ -0,0 +1,50 @@
+//go:build linux
+
+package vault
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/godbus/dbus"
+	ss "github.com/zalando/go-keyring/secret_service"
+)
+
+// secretServiceUnreachable reports whether the session bus can't be connected to or no Secret
+// Service is registered on it
+func secretServiceUnreachable() bool {
+	svc, err := ss.NewSecretService()
+	if err != nil {
+		return true
+	}
+
+	session, err := svc.OpenSession()
+	if err == nil {
+		svc.Close(session)
+		return false
+	}
+
+	var dbusErr dbus.Error
+	return errors.As(err, &dbusErr) && (dbusErr.Name == "org.freedesktop.DBus.Error.ServiceUnknown" || dbusErr.Name == "org.freedesktop.DBus.Error.NameHasNoOwner")
+}
+
+// keyringUnreachable reports whether the error of the Secret Service is returned because the
+// session bus or the Secret Service can't be reached, or the collection is locked and can't be
+// unlocked
+func keyringUnreachable(err error) bool {
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		switch dbusErr.Name {
+		case "org.freedesktop.DBus.Error.ServiceUnknown", "org.freedesktop.DBus.Error.NameHasNoOwner",
+			"org.freedesktop.DBus.Error.NoReply", "org.freedesktop.DBus.Error.Timeout",
+			"org.freedesktop.DBus.Error.Disconnected", "org.freedesktop.DBus.Error.NoServer",
+			"org.freedesktop.Secret.Error.IsLocked":
+			return true
+		}
+
+		return false
+	}
+
+	// the session bus can't be connected to, or the unlock prompt wasn't answered
+	return strings.HasPrefix(err.Error(), "dbus: ") || strings.HasPrefix(err.Error(), "failed to unlock")
+}
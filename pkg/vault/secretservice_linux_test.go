@@ -0,0 +1,39 @@
+package vault
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/zalando/go-keyring"
+)
+
+func TestSecretServiceUnreachable(t *testing.T) {
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path="+filepath.Join(t.TempDir(), "bus"))
+
+	if !secretServiceUnreachable() {
+		t.Errorf("expected the Secret Service to be unreachable without a session bus")
+	}
+}
+
+func TestKeyringUnavailable(t *testing.T) {
+	tests := []struct {
+		err         error
+		unavailable bool
+	}{
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}, true},
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}, true},
+		{dbus.Error{Name: "org.freedesktop.Secret.Error.IsLocked"}, true},
+		{errors.New("dbus: DBUS_SESSION_BUS_ADDRESS not set"), true},
+		{errors.New("failed to unlock correct collection '/org/freedesktop/secrets/aliases/default'"), true},
+		{dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}, false},
+		{keyring.ErrNotFound, false},
+	}
+
+	for _, test := range tests {
+		if unavailable := KeyringUnavailable(test.err); unavailable != test.unavailable {
+			t.Errorf("KeyringUnavailable(%v) is %t expected %t", test.err, unavailable, test.unavailable)
+		}
+	}
+}
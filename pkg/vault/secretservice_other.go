@@ -0,0 +1,14 @@
+//go:build !linux
+
+package vault
+
+// secretServiceUnreachable reports false since the credential vault of this platform isn't the Secret Service
+func secretServiceUnreachable() bool {
+	return false
+}
+
+// keyringUnreachable reports false since the errors of the credential vault of this platform
+// are treated as denials
+func keyringUnreachable(err error) bool {
+	return false
+}
@@ -0,0 +1,275 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// sopsCouldNotRetrieveKey is the exit code of sops when none of the file's recipients can
+// decrypt its data key
+const sopsCouldNotRetrieveKey = 128
+
+// SopsVault stores secrets as keys of a sops encrypted YAML, JSON or dotenv file. The file is
+// read and written with the sops binary, so its recipients, creation rules and metadata are
+// kept as they are
+type SopsVault struct {
+	Path       string
+	Prefix     string
+	SecretName string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "sops",
+		Key:   "sops",
+		Title: "a sops encrypted file",
+		Usage: "sops encrypted file provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:     "path",
+				Key:      "path",
+				Usage:    "The path to the sops encrypted file",
+				Required: true,
+			},
+			{
+				Name:  "prefix",
+				Key:   "prefix",
+				Usage: "The key of the map in the file that holds the secrets such as 'terraform', with nested keys separated by '/'. If omitted the secrets are top-level keys",
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &SopsVault{
+				Path:       config.String("path"),
+				Prefix:     config.String("prefix"),
+				SecretName: secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+// path returns the expanded path of the file. The file must exist since its recipients
+// come from it
+func (sv *SopsVault) path() (string, error) {
+	if sv.Path == "" {
+		return "", fmt.Errorf("the path of the sops file is not configured")
+	}
+
+	path, err := homedir.Expand(sv.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: the sops file '%s' doesn't exist. Create it with 'sops' first so that it has recipients", terraerrors.ErrNotFound, path)
+	}
+
+	return path, nil
+}
+
+// keys returns the keys from the top of the file to the secret
+func (sv *SopsVault) keys(secretName string) []string {
+	var keys []string
+	for _, key := range strings.Split(sv.Prefix, "/") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return append(keys, secretName)
+}
+
+// index returns the sops index of the secret such as '["terraform"]["app.terraform.io"]'
+func (sv *SopsVault) index(secretName string) string {
+	var index strings.Builder
+	for _, key := range sv.keys(secretName) {
+		quoted, _ := json.Marshal(key)
+		fmt.Fprintf(&index, "[%s]", quoted)
+	}
+
+	return index.String()
+}
+
+// sops runs the sops binary and returns its stdout
+func sops(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "sops", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("%w: sops: %w", terraerrors.ErrUnavailable, ctx.Err())
+	}
+
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%w: the sops binary is not installed or not in the PATH", terraerrors.ErrUnavailable)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == sopsCouldNotRetrieveKey {
+		return nil, fmt.Errorf("%w: sops can't decrypt the data key with any of the file's recipients: %s", terraerrors.ErrPermissionDenied, strings.TrimSpace(stderr.String()))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("sops %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// secrets decrypts the file and returns the map that holds the secrets
+func (sv *SopsVault) secrets(ctx context.Context) (map[string]any, error) {
+	path, err := sv.path()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := sops(ctx, nil, "decrypt", "--output-type", "json", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	err = json.Unmarshal(plaintext, &tree)
+	if err != nil {
+		return nil, fmt.Errorf("the sops file '%s' is not a map: %w", path, err)
+	}
+
+	keys := sv.keys("")
+	for _, key := range keys[:len(keys)-1] {
+		branch, ok := tree[key].(map[string]any)
+		if !ok {
+			return map[string]any{}, nil
+		}
+
+		tree = branch
+	}
+
+	return tree, nil
+}
+
+// sopsValue returns the secret's value from the map as a string
+func sopsValue(secrets map[string]any, secretName string) (string, error) {
+	switch value := secrets[secretName].(type) {
+	case nil:
+		return "", fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+	case string:
+		return value, nil
+	case map[string]any, []any:
+		return "", fmt.Errorf("the key '%s' in the sops file holds a map or a list instead of a secret", secretName)
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// Create sets the secret's key in the file. The value is passed on stdin so it doesn't show
+// up in process listings, which needs sops 3.11 or later
+func (sv *SopsVault) Create(ctx context.Context, secretValue string, method string) error {
+	path, err := sv.path()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(secretValue)
+	if err != nil {
+		return err
+	}
+
+	_, err = sops(ctx, encoded, "set", "--value-stdin", path, sv.index(sv.SecretName))
+	if err != nil && strings.Contains(err.Error(), "flag provided but not defined") {
+		return fmt.Errorf("storing secrets in a sops file requires sops 3.11 or later: %w", err)
+	}
+
+	return err
+}
+
+// Delete unsets the secret's key in the file
+func (sv *SopsVault) Delete(ctx context.Context) error {
+	secrets, err := sv.secrets(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[sv.SecretName]; !ok {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, sv.SecretName)
+	}
+
+	path, err := sv.path()
+	if err != nil {
+		return err
+	}
+
+	_, err = sops(ctx, nil, "unset", path, sv.index(sv.SecretName))
+	return err
+}
+
+// Get decrypts the file and returns the secret's value
+func (sv *SopsVault) Get(ctx context.Context) ([]byte, error) {
+	secrets, err := sv.secrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := sopsValue(secrets, sv.SecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// List decrypts the file once and returns the secrets' values
+func (sv *SopsVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	secrets, err := sv.secrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, err := sopsValue(secrets, secretName)
+		if err != nil {
+			return nil, err
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the keys that hold secrets, skipping nested maps and lists
+func (sv *SopsVault) Names(ctx context.Context) ([]string, error) {
+	secrets, err := sv.secrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, secret := range secrets {
+		switch secret.(type) {
+		case map[string]any, []any:
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault/internal/gpgtest"
+)
+
+// newSopsFile encrypts the content to a new GPG key with sops and returns the path of the file
+// and the key's fingerprint. The test is skipped when sops is not installed
+func newSopsFile(t *testing.T, name string, content string) (string, string) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		t.Skip("sops is not installed")
+	}
+
+	fingerprint := gpgtest.NewKey(t)
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, "", "sops", "encrypt", "--pgp", fingerprint, "--in-place", path)
+	return path, fingerprint
+}
+
+func TestSopsVaultKeepsFile(t *testing.T) {
+	ctx := context.Background()
+	path, fingerprint := newSopsFile(t, "secrets.yaml", "database:\n  password: hunter2\nterraform:\n  app.terraform.io: first-token\n")
+	sv := &SopsVault{
+		Path:       path,
+		Prefix:     "terraform",
+		SecretName: "tfe.example.com",
+	}
+
+	err := sv.Create(ctx, "second-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(contents), "second-token") {
+		t.Error("expected the secret to be encrypted")
+	}
+
+	if !strings.Contains(string(contents), fingerprint) {
+		t.Errorf("expected the file to keep its recipient:\n%s", contents)
+	}
+
+	plaintext := run(t, "", "sops", "decrypt", path)
+	expected := "database:\n    password: hunter2\nterraform:\n    app.terraform.io: first-token\n    tfe.example.com: second-token\n"
+	if plaintext != expected {
+		t.Errorf("expected the other keys to be kept:\n%s\ngot:\n%s", expected, plaintext)
+	}
+
+	values, err := sv.List(ctx, []string{"app.terraform.io", "tfe.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"first-token", "second-token"}) {
+		t.Errorf("expected both values got %q", values)
+	}
+
+	names, err := sv.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "tfe.example.com"}) {
+		t.Errorf("expected the names in the prefix got %q", names)
+	}
+
+	err = sv.Delete(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sv.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after the delete got %v", err)
+	}
+}
+
+func TestSopsVaultJSON(t *testing.T) {
+	ctx := context.Background()
+	path, _ := newSopsFile(t, "secrets.json", `{"port": 5432, "nested": {"key": "value"}}`)
+	sv := &SopsVault{
+		Path:       path,
+		SecretName: "app.terraform.io",
+	}
+
+	err := sv.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := sv.Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io", "port"}) {
+		t.Errorf("expected the keys that hold secrets got %q", names)
+	}
+
+	values, err := sv.List(ctx, []string{"app.terraform.io", "port"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, []string{"my-secret-token", "5432"}) {
+		t.Errorf("expected the values as strings got %q", values)
+	}
+
+	sv.SecretName = "nested"
+	_, err = sv.Get(ctx)
+	if err == nil || errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected a map to be rejected got %v", err)
+	}
+}
+
+func TestSopsVaultErrors(t *testing.T) {
+	ctx := context.Background()
+	path, _ := newSopsFile(t, "secrets.yaml", "app.terraform.io: my-secret-token\n")
+
+	missing := &SopsVault{
+		Path:       filepath.Join(t.TempDir(), "missing.yaml"),
+		SecretName: "app.terraform.io",
+	}
+
+	_, err := missing.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing file got %v", err)
+	}
+
+	// Without the private key the data key can't be decrypted
+	t.Setenv("GNUPGHOME", t.TempDir())
+	sv := &SopsVault{
+		Path:       path,
+		SecretName: "app.terraform.io",
+	}
+
+	_, err = sv.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied without the key got %v", err)
+	}
+}
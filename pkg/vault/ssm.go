@@ -0,0 +1,257 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+// ssmBatchSize is the most parameters that a single GetParameters call returns
+const ssmBatchSize = 10
+
+// AwsParameterStore stores secrets as SecureString parameters in AWS Systems Manager Parameter
+// Store below a hierarchical path
+type AwsParameterStore struct {
+	Description string
+	Endpoint    string
+	KmsKeyID    string
+	Path        string
+	Region      string
+	SecretName  string
+}
+
+func init() {
+	Register(&Provider{
+		Name:  "ssm",
+		Key:   "ssm",
+		Title: "AWS Systems Manager Parameter Store",
+		Usage: "AWS Systems Manager Parameter Store provider configuration settings",
+		Flags: []Flag{
+			{
+				Name:  "description",
+				Key:   "description",
+				Usage: "A description to provide to the parameters",
+			},
+			{
+				Name:  "endpoint",
+				Key:   "endpoint",
+				Usage: "The URL of the Systems Manager endpoint such as a VPC endpoint or LocalStack. If omitted the endpoint of the region is used",
+			},
+			{
+				Name:  "kms-key-id",
+				Key:   "kmsKeyId",
+				Usage: "The ID, ARN or alias of the KMS key that encrypts the parameters. If omitted the AWS managed key is used",
+			},
+			{
+				Name:  "path",
+				Key:   "path",
+				Usage: "The hierarchy that holds the parameters such as '/terraform/tokens'. If omitted the parameters are stored at the root such as '/app.terraform.io'",
+			},
+			{
+				Name:     "region",
+				Key:      "region",
+				Usage:    "The region where the Parameter Store is hosted",
+				Required: true,
+			},
+		},
+		New: func(config ProviderConfig, secretName string) (TerraVault, error) {
+			vault := &AwsParameterStore{
+				Description: config.String("description"),
+				Endpoint:    config.String("endpoint"),
+				KmsKeyID:    config.String("kmsKeyId"),
+				Path:        config.String("path"),
+				Region:      config.String("region"),
+				SecretName:  secretName,
+			}
+
+			return vault, nil
+		},
+	})
+}
+
+func (aps *AwsParameterStore) getParameterStore(ctx context.Context) (*ssm.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(aps.Region))
+	if err != nil {
+		return nil, err
+	}
+
+	svc := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		if aps.Endpoint != "" {
+			o.BaseEndpoint = aws.String(aps.Endpoint)
+		}
+	})
+
+	return svc, nil
+}
+
+// path returns the hierarchy with a leading slash and without a trailing one, or an empty
+// string when the parameters are stored at the root
+func (aps *AwsParameterStore) path() string {
+	path := strings.Trim(aps.Path, "/")
+	if path == "" {
+		return ""
+	}
+
+	return "/" + path
+}
+
+// name returns the name of the secret's parameter. The name always starts with a slash, even
+// at the root, since GetParametersByPath only returns hierarchical parameters
+func (aps *AwsParameterStore) name(secretName string) string {
+	return aps.path() + "/" + secretName
+}
+
+// Create stores the secret as a SecureString parameter encrypted with the KMS key
+func (aps *AwsParameterStore) Create(ctx context.Context, secretValue string, method string) error {
+	svc, err := aps.getParameterStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	input := &ssm.PutParameterInput{
+		Name:      aws.String(aps.name(aps.SecretName)),
+		Overwrite: aws.Bool(method == "Updated"),
+		Type:      types.ParameterTypeSecureString,
+		Value:     aws.String(secretValue),
+	}
+
+	if aps.Description != "" {
+		input.Description = aws.String(aps.Description)
+	}
+
+	if aps.KmsKeyID != "" {
+		input.KeyId = aws.String(aps.KmsKeyID)
+	}
+
+	_, err = svc.PutParameter(ctx, input)
+	if err != nil {
+		return awsError(err, aps.SecretName)
+	}
+
+	return nil
+}
+
+// Delete removes the secret's parameter
+func (aps *AwsParameterStore) Delete(ctx context.Context) error {
+	svc, err := aps.getParameterStore(ctx)
+	if err != nil {
+		return err
+	}
+
+	input := &ssm.DeleteParameterInput{
+		Name: aws.String(aps.name(aps.SecretName)),
+	}
+
+	_, err = svc.DeleteParameter(ctx, input)
+	if err != nil {
+		return awsError(err, aps.SecretName)
+	}
+
+	return nil
+}
+
+// Get decrypts the secret's parameter
+func (aps *AwsParameterStore) Get(ctx context.Context) ([]byte, error) {
+	svc, err := aps.getParameterStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &ssm.GetParameterInput{
+		Name:           aws.String(aps.name(aps.SecretName)),
+		WithDecryption: aws.Bool(true),
+	}
+
+	result, err := svc.GetParameter(ctx, input)
+	if err != nil {
+		return nil, awsError(err, aps.SecretName)
+	}
+
+	return []byte(aws.ToString(result.Parameter.Value)), nil
+}
+
+// List decrypts the secrets' parameters with GetParameters in batches of ten
+func (aps *AwsParameterStore) List(ctx context.Context, secretNames []string) ([]string, error) {
+	svc, err := aps.getParameterStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(secretNames))
+	for start := 0; start < len(secretNames); start += ssmBatchSize {
+		batch := secretNames[start:min(start+ssmBatchSize, len(secretNames))]
+		names := make([]string, 0, len(batch))
+		for _, secretName := range batch {
+			names = append(names, aps.name(secretName))
+		}
+
+		input := &ssm.GetParametersInput{
+			Names:          names,
+			WithDecryption: aws.Bool(true),
+		}
+
+		result, err := svc.GetParameters(ctx, input)
+		if err != nil {
+			return nil, awsError(err, strings.Join(batch, ","))
+		}
+
+		for _, parameter := range result.Parameters {
+			values[aws.ToString(parameter.Name)] = aws.ToString(parameter.Value)
+		}
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, ok := values[aps.name(secretName)]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the parameters below the path with GetParametersByPath
+func (aps *AwsParameterStore) Names(ctx context.Context) ([]string, error) {
+	svc, err := aps.getParameterStore(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := aps.path()
+	if path == "" {
+		path = "/"
+	}
+
+	input := &ssm.GetParametersByPathInput{
+		Path:      aws.String(path),
+		Recursive: aws.Bool(true),
+	}
+
+	var names []string
+	paginator := ssm.NewGetParametersByPathPaginator(svc, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, awsError(err, path)
+		}
+
+		for _, parameter := range page.Parameters {
+			name := strings.TrimPrefix(aws.ToString(parameter.Name), aps.path())
+			names = append(names, strings.TrimPrefix(name, "/"))
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
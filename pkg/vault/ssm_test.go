@@ -0,0 +1,137 @@
+package vault_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+	"github.com/tonedefdev/terracreds/pkg/vault/vaulttest"
+)
+
+func TestSsmSecureString(t *testing.T) {
+	setAwsEnv(t)
+	ctx := context.Background()
+
+	fake := vaulttest.NewParameterStore(t)
+	config := vault.ProviderConfig{
+		"description": "Terraform token",
+		"endpoint":    fake.URL,
+		"kmsKeyId":    "alias/terracreds",
+		"path":        "/terraform/tokens/",
+		"region":      "us-east-1",
+	}
+
+	terraVault := newVault(t, "ssm", config, "app.terraform.io")
+	err := terraVault.Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parameter, ok := fake.Parameter("/terraform/tokens/app.terraform.io")
+	if !ok {
+		t.Fatal("expected the parameter below the path")
+	}
+
+	if parameter.Type != "SecureString" || parameter.KeyID != "alias/terracreds" || parameter.Description != "Terraform token" {
+		t.Errorf("expected a SecureString encrypted with the KMS key got %+v", parameter)
+	}
+
+	err = terraVault.Create(ctx, "second-token", "Created")
+	if !errors.Is(err, terraerrors.ErrConflict) {
+		t.Errorf("expected ErrConflict creating an existing parameter got %v", err)
+	}
+
+	err = newVault(t, "ssm", config, "tfe.example.com").Create(ctx, "my-secret-token", "Updated")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSsmListBatches(t *testing.T) {
+	setAwsEnv(t)
+	ctx := context.Background()
+
+	fake := vaulttest.NewParameterStore(t)
+	config := vault.ProviderConfig{
+		"endpoint": fake.URL,
+		"path":     "/terraform",
+		"region":   "us-east-1",
+	}
+
+	var secretNames, expected []string
+	for i := 0; i < 25; i++ {
+		name := fmt.Sprintf("team-%02d/tfe.example.com", i)
+		err := newVault(t, "ssm", config, name).Create(ctx, "token-"+name, "Created")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secretNames = append(secretNames, name)
+		expected = append(expected, "token-"+name)
+	}
+
+	terraVault := newVault(t, "ssm", config, "")
+	values, err := terraVault.List(ctx, secretNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected the values in order got %q", values)
+	}
+
+	if requests := fake.Requests("GetParameters"); requests != 3 {
+		t.Errorf("expected 25 parameters in 3 batches got %d requests", requests)
+	}
+
+	names, err := terraVault.(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, secretNames) {
+		t.Errorf("expected the names below the path got %q", names)
+	}
+
+	if requests := fake.Requests("GetParametersByPath"); requests != 3 {
+		t.Errorf("expected the names to be paginated got %d requests", requests)
+	}
+
+	_, err = terraVault.List(ctx, []string{secretNames[0], "missing"})
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing parameter got %v", err)
+	}
+}
+
+func TestSsmRoot(t *testing.T) {
+	setAwsEnv(t)
+	ctx := context.Background()
+
+	fake := vaulttest.NewParameterStore(t)
+	config := vault.ProviderConfig{
+		"endpoint": fake.URL,
+		"region":   "us-east-1",
+	}
+
+	err := newVault(t, "ssm", config, "app.terraform.io").Create(ctx, "my-secret-token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fake.Parameter("/app.terraform.io"); !ok {
+		t.Fatal("expected the parameter at the root to start with a slash")
+	}
+
+	names, err := newVault(t, "ssm", config, "").(vault.Enumerator).Names(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"app.terraform.io"}) {
+		t.Errorf("expected the names at the root got %q", names)
+	}
+}
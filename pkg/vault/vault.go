@@ -1,10 +1,105 @@
 package vault
 
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/mitchellh/go-homedir"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
 // TerraVault implements an interface that handles secret lifecycle mananagement
-// for a credential vault provider
+// for a credential vault provider. Each method honors the deadline of its context,
+// and errors wrap ErrNotFound, ErrPermissionDenied, ErrUnavailable or ErrConflict
+// when the failure is one of those kinds
 type TerraVault interface {
-	Create(secretValue string, method string) error
-	Delete() error
-	Get() ([]byte, error)
-	List(secretNames []string) ([]string, error)
+	Create(ctx context.Context, secretValue string, method string) error
+	Delete(ctx context.Context) error
+	Get(ctx context.Context) ([]byte, error)
+	List(ctx context.Context, secretNames []string) ([]string, error)
+}
+
+// Enumerator is implemented by vault providers that can list the names of the secrets they
+// hold, so that every secret can be listed without naming each one
+type Enumerator interface {
+	Names(ctx context.Context) ([]string, error)
+}
+
+// Versioner is implemented by vault providers that keep the versions of the secrets they hold,
+// so that deleted versions can be restored or permanently destroyed. The versions belong to
+// the secret the provider stores the secret in, which can hold other secrets as well. When no
+// versions are given the latest version is used
+type Versioner interface {
+	SoftDelete(ctx context.Context, versions []int) error
+	Undelete(ctx context.Context, versions []int) error
+	Destroy(ctx context.Context, versions []int) error
+	DeleteMetadata(ctx context.Context) error
+}
+
+// wrapError annotates the error with the kind of failure and the name of the secret
+func wrapError(kind error, err error, secretName string) error {
+	return fmt.Errorf("%w: %s: %w", kind, secretName, err)
+}
+
+// statusError wraps an error returned with an HTTP response by the kind of failure its
+// status code represents. Status codes that don't map to a kind are left as-is
+func statusError(statusCode int, err error, secretName string) error {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return wrapError(terraerrors.ErrNotFound, err, secretName)
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return wrapError(terraerrors.ErrPermissionDenied, err, secretName)
+	case statusCode == http.StatusConflict, statusCode == http.StatusPreconditionFailed:
+		return wrapError(terraerrors.ErrConflict, err, secretName)
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests, statusCode >= 500:
+		return wrapError(terraerrors.ErrUnavailable, err, secretName)
+	}
+
+	return err
+}
+
+// transportError wraps errors that occur before a vault answers, such as timeouts and
+// network failures, with ErrUnavailable. Any other error is returned as-is
+func transportError(err error, secretName string) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return wrapError(terraerrors.ErrUnavailable, err, secretName)
+	}
+
+	return err
+}
+
+// newHTTPClient returns a client for a vault's HTTP API. When the path of a PEM encoded CA
+// certificate is set, the client trusts it instead of the system's certificates
+func newHTTPClient(caCert string) (*http.Client, error) {
+	if caCert == "" {
+		return &http.Client{}, nil
+	}
+
+	path, err := homedir.Expand(caCert)
+	if err != nil {
+		return nil, err
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the CA certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("the CA certificate '%s' has no PEM encoded certificates", caCert)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return &http.Client{Transport: transport}, nil
 }
@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		want       error
+	}{
+		{statusCode: http.StatusNotFound, want: terraerrors.ErrNotFound},
+		{statusCode: http.StatusUnauthorized, want: terraerrors.ErrPermissionDenied},
+		{statusCode: http.StatusForbidden, want: terraerrors.ErrPermissionDenied},
+		{statusCode: http.StatusConflict, want: terraerrors.ErrConflict},
+		{statusCode: http.StatusTooManyRequests, want: terraerrors.ErrUnavailable},
+		{statusCode: http.StatusServiceUnavailable, want: terraerrors.ErrUnavailable},
+	}
+
+	for _, test := range tests {
+		err := statusError(test.statusCode, errors.New("response error"), "app.terraform.io")
+		if !errors.Is(err, test.want) {
+			t.Errorf("status %d returned '%v' expected '%v'", test.statusCode, err, test.want)
+		}
+	}
+
+	err := statusError(http.StatusBadRequest, errors.New("response error"), "app.terraform.io")
+	if terraerrors.ExitCode(err) != terraerrors.ExitError {
+		t.Errorf("status 400 returned '%v' expected an unclassified error", err)
+	}
+}
+
+func TestGcpError(t *testing.T) {
+	err := gcpError(status.Error(codes.PermissionDenied, "denied"), "app-terraform-io")
+	if terraerrors.IsNotFound(err) || !errors.Is(err, terraerrors.ErrPermissionDenied) {
+		t.Errorf("expected ErrPermissionDenied got: %v", err)
+	}
+
+	err = gcpError(status.Error(codes.NotFound, "missing"), "app-terraform-io")
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound got: %v", err)
+	}
+}
+
+func newTestHashiVault(t *testing.T, handler http.HandlerFunc) *HashiVault {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &HashiVault{
+		KeyVaultPath: "kv",
+		SecretName:   "app.terraform.io",
+		SecretPath:   "terraform",
+		VaultUri:     server.URL,
+	}
+}
+
+func TestHashiVaultGetPermissionDenied(t *testing.T) {
+	hc := newTestHashiVault(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+	})
+
+	_, err := hc.Get(context.Background())
+	if terraerrors.IsNotFound(err) {
+		t.Fatalf("a permission error was reported as a missing secret: %v", err)
+	}
+
+	if code := terraerrors.ExitCode(err); code != terraerrors.ExitPermissionDenied {
+		t.Errorf("exit code is %d expected %d: %v", code, terraerrors.ExitPermissionDenied, err)
+	}
+}
+
+func TestHashiVaultGetNotFound(t *testing.T) {
+	hc := newTestHashiVault(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors":[]}`))
+	})
+
+	_, err := hc.Get(context.Background())
+	if !terraerrors.IsNotFound(err) {
+		t.Errorf("expected ErrNotFound got: %v", err)
+	}
+}
+
+func TestHashiVaultGetTimeout(t *testing.T) {
+	hc := newTestHashiVault(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := hc.Get(ctx)
+	if code := terraerrors.ExitCode(err); code != terraerrors.ExitUnavailable {
+		t.Errorf("exit code is %d expected %d: %v", code, terraerrors.ExitUnavailable, err)
+	}
+}
+
+func TestHashiVaultCreateConflict(t *testing.T) {
+	writes := 0
+	hc := newTestHashiVault(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/kv/data/terraform" && r.Method == http.MethodGet:
+			w.Write([]byte(`{"data":{"data":{"tfe.corp.example":"sibling-token"},"metadata":{"version":3}}}`))
+		case r.URL.Path == "/v1/kv/data/terraform" && r.Method == http.MethodPut:
+			writes++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":["check-and-set parameter did not match the current version"]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	})
+
+	err := hc.Create(context.Background(), "my-secret-token", "Updated")
+	if code := terraerrors.ExitCode(err); code != terraerrors.ExitConflict {
+		t.Errorf("exit code is %d expected %d: %v", code, terraerrors.ExitConflict, err)
+	}
+
+	if writes != hashiWriteAttempts {
+		t.Errorf("expected %d writes got %d", hashiWriteAttempts, writes)
+	}
+}
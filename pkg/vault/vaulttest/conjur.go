@@ -0,0 +1,267 @@
+package vaulttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Conjur is a local fake of the CyberArk Conjur REST API. Every login that authenticates can
+// read, write and declare every variable, and policies only declare and delete variables
+type Conjur struct {
+	*httptest.Server
+
+	// Account is the Conjur account the fake serves
+	Account string
+
+	// APIKeys maps the logins that authn accepts to their API keys
+	APIKeys map[string]string
+
+	// JWTs maps the service IDs of authn-jwt to the JWT each one accepts
+	JWTs map[string]string
+
+	mu              sync.Mutex
+	authentications int
+	tokens          map[string]string
+	variables       map[string]*string
+}
+
+// NewConjur starts a fake Conjur server for the account that's closed when the test ends. Set
+// the API keys and JWTs it accepts before the first request
+func NewConjur(t *testing.T, account string) *Conjur {
+	conjur := &Conjur{
+		Account:   account,
+		APIKeys:   make(map[string]string),
+		JWTs:      make(map[string]string),
+		tokens:    make(map[string]string),
+		variables: make(map[string]*string),
+	}
+
+	conjur.Server = httptest.NewServer(http.HandlerFunc(conjur.handle))
+	t.Cleanup(conjur.Close)
+
+	return conjur
+}
+
+// Authentications returns the number of access tokens the fake issued
+func (conjur *Conjur) Authentications() int {
+	conjur.mu.Lock()
+	defer conjur.mu.Unlock()
+
+	return conjur.authentications
+}
+
+// Expire expires every access token the fake issued
+func (conjur *Conjur) Expire() {
+	conjur.mu.Lock()
+	defer conjur.mu.Unlock()
+
+	conjur.tokens = make(map[string]string)
+}
+
+// Variable returns the value of the variable such as 'terraform/app.terraform.io', and whether
+// the variable is declared
+func (conjur *Conjur) Variable(id string) (string, bool) {
+	conjur.mu.Lock()
+	defer conjur.mu.Unlock()
+
+	value, ok := conjur.variables[id]
+	if !ok || value == nil {
+		return "", ok
+	}
+
+	return *value, true
+}
+
+// issue writes a new access token for the login
+func (conjur *Conjur) issue(w http.ResponseWriter, login string) {
+	conjur.authentications++
+	token, _ := json.Marshal(map[string]string{
+		"protected": "eyJhbGciOiJjb25qdXIub3JnL3Nsb3NpbG8vdjIifQ==",
+		"payload":   base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":%q,"iat":%d}`, login, conjur.authentications))),
+		"signature": "c2lnbmF0dXJl",
+	})
+
+	conjur.tokens[string(token)] = login
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(token)
+}
+
+func (conjur *Conjur) handle(w http.ResponseWriter, r *http.Request) {
+	conjur.mu.Lock()
+	defer conjur.mu.Unlock()
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		segment, err := url.PathUnescape(segment)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		segments = append(segments, segment)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(segments) == 4 && segments[0] == "authn" && segments[3] == "authenticate" && r.Method == http.MethodPost:
+		apiKey, ok := conjur.APIKeys[segments[2]]
+		if segments[1] != conjur.Account || !ok || apiKey != string(body) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		conjur.issue(w, segments[2])
+		return
+	case (len(segments) == 4 || len(segments) == 5) && segments[0] == "authn-jwt" && segments[len(segments)-1] == "authenticate" && r.Method == http.MethodPost:
+		form, err := url.ParseQuery(string(body))
+		jwt, ok := conjur.JWTs[segments[1]]
+		if err != nil || segments[2] != conjur.Account || !ok || form.Get("jwt") != jwt {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		login := "host/" + segments[1]
+		if len(segments) == 5 {
+			login = segments[3]
+		}
+
+		conjur.issue(w, login)
+		return
+	}
+
+	token, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("Authorization"), `Token token="`), `"`))
+	if _, ok := conjur.tokens[string(token)]; err != nil || !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "secrets" && r.Method == http.MethodGet:
+		values := make(map[string]string)
+		for _, id := range strings.Split(r.URL.Query().Get("variable_ids"), ",") {
+			variable, ok := strings.CutPrefix(id, conjur.Account+":variable:")
+			if !ok || conjur.variables[variable] == nil {
+				http.Error(w, fmt.Sprintf("Variable '%s' not found", id), http.StatusNotFound)
+				return
+			}
+
+			values[id] = *conjur.variables[variable]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(values)
+	case len(segments) == 4 && segments[0] == "secrets" && segments[1] == conjur.Account && segments[2] == "variable":
+		value, declared := conjur.variables[segments[3]]
+		if !declared {
+			http.Error(w, fmt.Sprintf("Variable '%s' not found in account '%s'", segments[3], conjur.Account), http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			if value == nil {
+				http.Error(w, fmt.Sprintf("Variable '%s' is empty or not found.", segments[3]), http.StatusNotFound)
+				return
+			}
+
+			w.Write([]byte(*value))
+		case http.MethodPost:
+			secret := string(body)
+			conjur.variables[segments[3]] = &secret
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case len(segments) == 4 && segments[0] == "policies" && segments[1] == conjur.Account && segments[2] == "policy":
+		if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := conjur.loadPolicy(segments[3], string(body), r.Method == http.MethodPatch)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"created_roles": map[string]interface{}{}, "version": 1})
+	case len(segments) == 3 && segments[0] == "resources" && segments[1] == conjur.Account && segments[2] == "variable" && r.Method == http.MethodGet:
+		var ids []string
+		for id := range conjur.variables {
+			ids = append(ids, id)
+		}
+
+		sort.Strings(ids)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit == 0 {
+			limit = len(ids)
+		}
+
+		resources := []map[string]string{}
+		for _, id := range ids[min(offset, len(ids)):min(offset+limit, len(ids))] {
+			resources = append(resources, map[string]string{"id": conjur.Account + ":variable:" + id})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resources)
+	default:
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}
+}
+
+// loadPolicy declares the '!variable' records of the policy in the branch, and deletes the
+// '!delete' records when deletion is allowed
+func (conjur *Conjur) loadPolicy(branch string, policy string, allowDelete bool) error {
+	prefix := branch + "/"
+	if branch == "root" {
+		prefix = ""
+	}
+
+	deleting := false
+	for _, line := range strings.Split(policy, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case line == "- !delete":
+			if !allowDelete {
+				return fmt.Errorf("the !delete statement can't be used in a policy that's loaded with POST")
+			}
+
+			deleting = true
+		case strings.HasPrefix(line, "- !variable "), strings.HasPrefix(line, "record: !variable "):
+			_, id, _ := strings.Cut(line, "!variable ")
+			if unquoted, err := strconv.Unquote(id); err == nil {
+				id = unquoted
+			}
+
+			if deleting {
+				delete(conjur.variables, prefix+id)
+				deleting = false
+			} else if _, ok := conjur.variables[prefix+id]; !ok {
+				conjur.variables[prefix+id] = nil
+			}
+		default:
+			return fmt.Errorf("the policy statement '%s' is not supported by the fake", line)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// consulEntry is a key stored in the ConsulKV fake
+type consulEntry struct {
+	createIndex uint64
+	modifyIndex uint64
+	value       []byte
+}
+
+// ConsulKV is a local fake of the Consul KV store HTTP API with ACLs, datacenters and Consul
+// Enterprise namespaces. Each namespace holds its own keys
+type ConsulKV struct {
+	*httptest.Server
+
+	// Datacenter is the only datacenter the fake serves
+	Datacenter string
+
+	// Token is the ACL token the fake accepts
+	Token string
+
+	mu         sync.Mutex
+	index      uint64
+	namespaces map[string]map[string]*consulEntry
+}
+
+// NewConsulKV starts a fake Consul agent in the 'dc1' datacenter that accepts the token and
+// is closed when the test ends
+func NewConsulKV(t *testing.T, token string) *ConsulKV {
+	ckv := newConsulKV(token)
+	ckv.Server = httptest.NewServer(http.HandlerFunc(ckv.handle))
+	t.Cleanup(ckv.Close)
+
+	return ckv
+}
+
+// NewConsulKVTLS starts a fake Consul agent like NewConsulKV that's served over HTTPS with the
+// certificate returned by Certificate
+func NewConsulKVTLS(t *testing.T, token string) *ConsulKV {
+	ckv := newConsulKV(token)
+	ckv.Server = httptest.NewTLSServer(http.HandlerFunc(ckv.handle))
+	t.Cleanup(ckv.Close)
+
+	return ckv
+}
+
+func newConsulKV(token string) *ConsulKV {
+	return &ConsulKV{
+		Datacenter: "dc1",
+		Token:      token,
+		namespaces: make(map[string]map[string]*consulEntry),
+	}
+}
+
+// Value returns the value of the key in the namespace, where an empty namespace is 'default',
+// and whether the key exists
+func (ckv *ConsulKV) Value(namespace string, key string) (string, bool) {
+	ckv.mu.Lock()
+	defer ckv.mu.Unlock()
+
+	entry, ok := ckv.entries(namespace)[key]
+	if !ok {
+		return "", false
+	}
+
+	return string(entry.value), true
+}
+
+// Put writes the key in the namespace as another client would
+func (ckv *ConsulKV) Put(namespace string, key string, value string) {
+	ckv.mu.Lock()
+	defer ckv.mu.Unlock()
+
+	ckv.put(ckv.entries(namespace), key, []byte(value))
+}
+
+// entries returns the keys of the namespace
+func (ckv *ConsulKV) entries(namespace string) map[string]*consulEntry {
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	entries, ok := ckv.namespaces[namespace]
+	if !ok {
+		entries = make(map[string]*consulEntry)
+		ckv.namespaces[namespace] = entries
+	}
+
+	return entries
+}
+
+func (ckv *ConsulKV) put(entries map[string]*consulEntry, key string, value []byte) {
+	ckv.index++
+	entry, ok := entries[key]
+	if !ok {
+		entry = &consulEntry{createIndex: ckv.index}
+		entries[key] = entry
+	}
+
+	entry.modifyIndex = ckv.index
+	entry.value = value
+}
+
+func (ckv *ConsulKV) handle(w http.ResponseWriter, r *http.Request) {
+	ckv.mu.Lock()
+	defer ckv.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(ckv.index, 10))
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+
+	if r.Header.Get("X-Consul-Token") != ckv.Token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	if dc := query.Get("dc"); dc != "" && dc != ckv.Datacenter {
+		http.Error(w, "No path to datacenter", http.StatusInternalServerError)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/v1/kv/")
+	if !ok {
+		http.Error(w, "Invalid URL path: not a KV endpoint", http.StatusNotFound)
+		return
+	}
+
+	entries := ckv.entries(query.Get("ns"))
+	entry, exists := entries[key]
+
+	var cas *uint64
+	if query.Has("cas") {
+		index, err := strconv.ParseUint(query.Get("cas"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid CAS index", http.StatusBadRequest)
+			return
+		}
+
+		cas = &index
+	}
+
+	// casMatches is whether a check-and-set index matches the key, where 0 means it must not exist
+	casMatches := cas == nil || (*cas == 0 && !exists) || (exists && *cas == entry.modifyIndex)
+
+	switch r.Method {
+	case http.MethodGet:
+		var keys []string
+		for name := range entries {
+			if query.Has("recurse") || query.Has("keys") {
+				if strings.HasPrefix(name, key) {
+					keys = append(keys, name)
+				}
+			} else if name == key {
+				keys = append(keys, name)
+			}
+		}
+
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		sort.Strings(keys)
+		w.Header().Set("Content-Type", "application/json")
+		if query.Has("keys") {
+			json.NewEncoder(w).Encode(keys)
+			return
+		}
+
+		pairs := []map[string]interface{}{}
+		for _, name := range keys {
+			pairs = append(pairs, map[string]interface{}{
+				"CreateIndex": entries[name].createIndex,
+				"Flags":       0,
+				"Key":         name,
+				"LockIndex":   0,
+				"ModifyIndex": entries[name].modifyIndex,
+				"Value":       entries[name].value,
+			})
+		}
+
+		json.NewEncoder(w).Encode(pairs)
+	case http.MethodPut:
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if casMatches {
+			ckv.put(entries, key, value)
+		}
+
+		json.NewEncoder(w).Encode(casMatches)
+	case http.MethodDelete:
+		if casMatches {
+			delete(entries, key)
+		}
+
+		json.NewEncoder(w).Encode(casMatches)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,15 @@
+package vaulttest
+
+import (
+	"testing"
+
+	"github.com/tonedefdev/terracreds/pkg/vault/internal/gpgtest"
+)
+
+// NewGPGKey generates a GPG key without a passphrase in a temporary GNUPGHOME that's set for
+// the test, and returns its fingerprint. The test is skipped when gpg is not installed
+func NewGPGKey(t *testing.T) string {
+	t.Helper()
+
+	return gpgtest.NewKey(t)
+}
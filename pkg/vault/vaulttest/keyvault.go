@@ -0,0 +1,144 @@
+package vaulttest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// keyVaultName is the set of characters Key Vault accepts in a secret name
+var keyVaultName = regexp.MustCompile(`^[0-9a-zA-Z-]{1,127}$`)
+
+// KeyVault is a local fake of the Azure Key Vault secrets API. Like Key Vault it rejects secret
+// names that contain periods. Deleted secrets are purged at once rather than soft deleted
+type KeyVault struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewKeyVault starts a fake Key Vault that's closed when the test ends. Use its URL as the vault
+// URI and Credential to authenticate
+func NewKeyVault(t *testing.T) *KeyVault {
+	kv := &KeyVault{
+		secrets: make(map[string]string),
+	}
+
+	kv.Server = httptest.NewServer(http.HandlerFunc(kv.handle))
+	t.Cleanup(kv.Close)
+
+	return kv
+}
+
+// Secret returns the value of the secret and whether it exists
+func (kv *KeyVault) Secret(name string) (string, bool) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	value, ok := kv.secrets[name]
+	return value, ok
+}
+
+func (kv *KeyVault) handle(w http.ResponseWriter, r *http.Request) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	// The SDK sends a request without a token first and authenticates with the challenge
+	if r.Header.Get("Authorization") == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer authorization="https://login.microsoftonline.com/00000000-0000-0000-0000-000000000000", resource="https://vault.azure.net"`)
+		keyVaultFault(w, http.StatusUnauthorized, "Unauthorized", "AKV10000: Request is missing a Bearer or PoP token.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 {
+		keyVaultFault(w, http.StatusNotFound, "NotFound", "The requested resource was not found.")
+		return
+	}
+
+	collection, name := parts[0], parts[1]
+	if collection == "deletedsecrets" {
+		keyVaultFault(w, http.StatusNotFound, "SecretNotFound", fmt.Sprintf("Deleted Secret not found: %s", name))
+		return
+	}
+
+	if collection != "secrets" || !keyVaultName.MatchString(name) {
+		keyVaultFault(w, http.StatusBadRequest, "BadParameter", "The request URI contains an invalid name: "+name)
+		return
+	}
+
+	value, exists := kv.secrets[name]
+	if r.Method != http.MethodPut && !exists {
+		keyVaultFault(w, http.StatusNotFound, "SecretNotFound", fmt.Sprintf("A secret with (name/id) %s was not found in this key vault.", name))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var body struct {
+			Value string `json:"value"`
+		}
+
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			keyVaultFault(w, http.StatusBadRequest, "BadParameter", err.Error())
+			return
+		}
+
+		value = body.Value
+		kv.secrets[name] = value
+	case http.MethodGet:
+	case http.MethodDelete:
+		delete(kv.secrets, name)
+	default:
+		keyVaultFault(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":          fmt.Sprintf("%s/secrets/%s/1", kv.URL, name),
+		"value":       value,
+		"contentType": "password",
+		"attributes": map[string]interface{}{
+			"enabled": true,
+		},
+	})
+}
+
+// keyVaultFault writes an error in the format of the Key Vault API
+func keyVaultFault(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
+
+// Credential is an azcore.TokenCredential that returns a static token for the fake Key Vault
+type Credential struct{}
+
+// GetToken returns a token that expires in an hour
+func (c *Credential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	token := azcore.AccessToken{
+		Token:     "vaulttest",
+		ExpiresOn: time.Now().Add(time.Hour),
+	}
+
+	return token, nil
+}
@@ -0,0 +1,267 @@
+package vaulttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KubernetesAPI is a local fake of the Secrets of the Kubernetes core API. Updates must carry the
+// resource version of the Secret, and lists are paginated by the limit of the request
+type KubernetesAPI struct {
+	*httptest.Server
+
+	// Token is the bearer token the fake accepts
+	Token string
+
+	mu              sync.Mutex
+	resourceVersion int
+	secrets         map[string]*corev1.Secret
+}
+
+// NewKubernetesAPI starts a fake API server that accepts the token and is closed when the test
+// ends. It's served over HTTPS since the credentials of a kubeconfig are only sent over TLS
+func NewKubernetesAPI(t *testing.T, token string) *KubernetesAPI {
+	api := &KubernetesAPI{
+		Token:   token,
+		secrets: make(map[string]*corev1.Secret),
+	}
+
+	api.Server = httptest.NewTLSServer(http.HandlerFunc(api.handle))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// Kubeconfig writes a kubeconfig with a 'vaulttest' context for the fake and the namespace,
+// and returns its path
+func (api *KubernetesAPI) Kubeconfig(t *testing.T, namespace string) string {
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw})
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: vaulttest
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+users:
+- name: vaulttest
+  user:
+    token: %s
+contexts:
+- name: vaulttest
+  context:
+    cluster: vaulttest
+    namespace: %s
+    user: vaulttest
+current-context: vaulttest
+`, api.URL, base64.StdEncoding.EncodeToString(certificate), api.Token, namespace)
+
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	err := os.WriteFile(path, []byte(kubeconfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// Secret returns a copy of the Secret in the namespace and whether it exists
+func (api *KubernetesAPI) Secret(namespace string, name string) (*corev1.Secret, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	secret, ok := api.secrets[namespace+"/"+name]
+	if !ok {
+		return nil, false
+	}
+
+	return secret.DeepCopy(), true
+}
+
+// Put stores the Secret as another client would
+func (api *KubernetesAPI) Put(secret *corev1.Secret) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.store(secret.DeepCopy())
+}
+
+// store saves the Secret with the next resource version
+func (api *KubernetesAPI) store(secret *corev1.Secret) {
+	api.resourceVersion++
+	secret.ResourceVersion = strconv.Itoa(api.resourceVersion)
+	api.secrets[secret.Namespace+"/"+secret.Name] = secret
+}
+
+func (api *KubernetesAPI) handle(w http.ResponseWriter, r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+api.Token {
+		kubernetesFault(w, apierrors.NewUnauthorized("Unauthorized"))
+		return
+	}
+
+	resource := schema.GroupResource{Resource: "secrets"}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/"), "/"), "/")
+	if !strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/") || len(parts) < 2 || len(parts) > 3 || parts[1] != "secrets" {
+		kubernetesFault(w, apierrors.NewNotFound(schema.GroupResource{}, r.URL.Path))
+		return
+	}
+
+	namespace := parts[0]
+	if len(parts) == 2 {
+		switch r.Method {
+		case http.MethodGet:
+			api.list(w, r, namespace)
+		case http.MethodPost:
+			var secret corev1.Secret
+			if !decodeSecret(w, r, &secret) {
+				return
+			}
+
+			if _, exists := api.secrets[namespace+"/"+secret.Name]; exists {
+				kubernetesFault(w, apierrors.NewAlreadyExists(resource, secret.Name))
+				return
+			}
+
+			secret.Namespace = namespace
+			api.store(&secret)
+			kubernetesResponse(w, http.StatusCreated, &secret)
+		default:
+			kubernetesFault(w, apierrors.NewMethodNotSupported(resource, r.Method))
+		}
+
+		return
+	}
+
+	name := parts[2]
+	existing, exists := api.secrets[namespace+"/"+name]
+	if !exists {
+		kubernetesFault(w, apierrors.NewNotFound(resource, name))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		kubernetesResponse(w, http.StatusOK, existing)
+	case http.MethodPut:
+		var secret corev1.Secret
+		if !decodeSecret(w, r, &secret) {
+			return
+		}
+
+		if secret.ResourceVersion != "" && secret.ResourceVersion != existing.ResourceVersion {
+			message := fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again")
+			kubernetesFault(w, apierrors.NewConflict(resource, name, message))
+			return
+		}
+
+		secret.Namespace = namespace
+		api.store(&secret)
+		kubernetesResponse(w, http.StatusOK, &secret)
+	case http.MethodDelete:
+		var options metav1.DeleteOptions
+		json.NewDecoder(r.Body).Decode(&options)
+		if options.Preconditions != nil && options.Preconditions.ResourceVersion != nil && *options.Preconditions.ResourceVersion != existing.ResourceVersion {
+			message := fmt.Errorf("the ResourceVersion in the precondition (%s) does not match the ResourceVersion in record (%s)", *options.Preconditions.ResourceVersion, existing.ResourceVersion)
+			kubernetesFault(w, apierrors.NewConflict(resource, name, message))
+			return
+		}
+
+		delete(api.secrets, namespace+"/"+name)
+		kubernetesResponse(w, http.StatusOK, &metav1.Status{Status: metav1.StatusSuccess})
+	default:
+		kubernetesFault(w, apierrors.NewMethodNotSupported(resource, r.Method))
+	}
+}
+
+// list writes the Secrets of the namespace that match the label selector
+func (api *KubernetesAPI) list(w http.ResponseWriter, r *http.Request, namespace string) {
+	query := r.URL.Query()
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		kubernetesFault(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	var names []string
+	for key, secret := range api.secrets {
+		if secret.Namespace == namespace && selector.Matches(labels.Set(secret.Labels)) {
+			names = append(names, key)
+		}
+	}
+
+	sort.Strings(names)
+	start, _ := strconv.Atoi(query.Get("continue"))
+	end := len(names)
+	if limit, _ := strconv.Atoi(query.Get("limit")); limit > 0 {
+		end = min(start+limit, len(names))
+	}
+
+	list := &corev1.SecretList{
+		ListMeta: metav1.ListMeta{ResourceVersion: strconv.Itoa(api.resourceVersion)},
+		Items:    []corev1.Secret{},
+	}
+
+	for _, key := range names[start:end] {
+		list.Items = append(list.Items, *api.secrets[key])
+	}
+
+	if end < len(names) {
+		list.Continue = strconv.Itoa(end)
+	}
+
+	kubernetesResponse(w, http.StatusOK, list)
+}
+
+// decodeSecret reads the Secret in the request body and writes an error when it's not valid
+func decodeSecret(w http.ResponseWriter, r *http.Request, secret *corev1.Secret) bool {
+	err := json.NewDecoder(r.Body).Decode(secret)
+	if err != nil || secret.Name == "" {
+		kubernetesFault(w, apierrors.NewBadRequest("the Secret must have a name"))
+		return false
+	}
+
+	return true
+}
+
+// kubernetesFault writes the error as a Status with its code
+func kubernetesFault(w http.ResponseWriter, err *apierrors.StatusError) {
+	status := err.Status()
+	kubernetesResponse(w, int(status.Code), &status)
+}
+
+// kubernetesResponse writes the object with the kind and API version set
+func kubernetesResponse(w http.ResponseWriter, status int, object runtime.Object) {
+	switch object := object.(type) {
+	case *corev1.Secret:
+		object.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	case *corev1.SecretList:
+		object.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "SecretList"}
+	case *metav1.Status:
+		object.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Status"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(object)
+}
@@ -0,0 +1,459 @@
+package vaulttest
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// VaultLogin is a login that the fake accepts. A successful login issues a new token
+type VaultLogin struct {
+	// Path is the path of the login such as 'auth/approle/login'
+	Path string
+
+	// Data is the body the login must be sent with such as the role ID and secret ID
+	Data map[string]interface{}
+
+	// ClientCert requires the login to present a client certificate
+	ClientCert bool
+}
+
+// VaultVersion is a version of a secret on a KV version 2 mount
+type VaultVersion struct {
+	// Data is the data of the version, which is nil once the version is destroyed
+	Data map[string]interface{}
+
+	// Deleted is set when the version is soft deleted and can still be undeleted
+	Deleted bool
+
+	// Destroyed is set when the data of the version is permanently removed
+	Destroyed bool
+}
+
+// VaultKV is a local fake of the HashiCorp Vault KV secrets engine. Mounts are KV version 2 unless
+// they're added with Mount, and keep every version of their secrets with the delete, undelete,
+// destroy and metadata endpoints of Vault. Writes to them honor the check-and-set parameter. Like
+// a Vault older than 0.10, the fake only answers the preflight request that detects the version of
+// a mount for the mounts that are added with Mount. The roles of the Terraform Cloud secrets engine
+// that are added with AddTFCRole issue leased tokens
+type VaultKV struct {
+	*httptest.Server
+
+	// Token is the Vault token the fake accepts
+	Token string
+
+	// Namespace is the Vault Enterprise namespace that every request must be sent to
+	Namespace string
+
+	mu       sync.Mutex
+	leases   []*VaultLease
+	logins   []VaultLogin
+	mounts   map[string]int
+	issued   map[string]bool
+	roles    map[string]VaultTFCRole
+	secrets  map[string]map[string]interface{}
+	versions map[string][]VaultVersion
+}
+
+// NewVaultKV starts a fake Vault that accepts the token and is closed when the test ends
+func NewVaultKV(t *testing.T, token string) *VaultKV {
+	kv := newVaultKV(token)
+	kv.Server = httptest.NewServer(http.HandlerFunc(kv.handle))
+	t.Cleanup(kv.Close)
+
+	return kv
+}
+
+// NewVaultKVTLS starts a fake Vault like NewVaultKV that's served over HTTPS with the certificate
+// returned by Certificate, and that asks the clients for a certificate
+func NewVaultKVTLS(t *testing.T, token string) *VaultKV {
+	kv := newVaultKV(token)
+	kv.Server = httptest.NewUnstartedServer(http.HandlerFunc(kv.handle))
+	kv.Server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	kv.StartTLS()
+	t.Cleanup(kv.Close)
+
+	return kv
+}
+
+func newVaultKV(token string) *VaultKV {
+	return &VaultKV{
+		Token:    token,
+		issued:   make(map[string]bool),
+		mounts:   make(map[string]int),
+		roles:    make(map[string]VaultTFCRole),
+		secrets:  make(map[string]map[string]interface{}),
+		versions: make(map[string][]VaultVersion),
+	}
+}
+
+// AddLogin makes the fake accept the login
+func (kv *VaultKV) AddLogin(login VaultLogin) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.logins = append(kv.logins, login)
+}
+
+// Put writes the data to the path such as 'kv/tfe' as another client would
+func (kv *VaultKV) Put(path string, data map[string]interface{}) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if _, version, _ := kv.mount(path); version == 1 {
+		kv.secrets[path] = data
+		return
+	}
+
+	kv.versions[path] = append(kv.versions[path], VaultVersion{Data: data})
+}
+
+// Mount adds a mount of the KV secrets engine at the path such as 'secret/legacy' with the version
+func (kv *VaultKV) Mount(path string, version int) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.mounts[strings.Trim(path, "/")] = version
+}
+
+// mount returns the mount of the path and its version. Paths outside of the added mounts
+// belong to a KV version 2 mount at their first segment
+func (kv *VaultKV) mount(path string) (string, int, bool) {
+	mount := ""
+	for candidate := range kv.mounts {
+		if (path == candidate || strings.HasPrefix(path, candidate+"/")) && len(candidate) > len(mount) {
+			mount = candidate
+		}
+	}
+
+	if mount != "" {
+		return mount, kv.mounts[mount], true
+	}
+
+	mount, _, _ = strings.Cut(path, "/")
+	return mount, 2, false
+}
+
+// Logins returns the number of tokens the fake issued
+func (kv *VaultKV) Logins() int {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return len(kv.issued)
+}
+
+// Secret returns the data stored at the path, such as 'kv/tfe', and whether it exists. The data
+// of a KV version 2 secret is the data of its latest version unless that version was deleted
+func (kv *VaultKV) Secret(path string) (map[string]interface{}, bool) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return kv.latest(path)
+}
+
+// Versions returns the versions of the secret at the path on a KV version 2 mount, oldest first
+func (kv *VaultKV) Versions(path string) []VaultVersion {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	return append([]VaultVersion(nil), kv.versions[path]...)
+}
+
+// latest returns the data of the secret at the path and whether it exists
+func (kv *VaultKV) latest(path string) (map[string]interface{}, bool) {
+	if _, version, _ := kv.mount(path); version == 1 {
+		data, ok := kv.secrets[path]
+		return data, ok
+	}
+
+	versions := kv.versions[path]
+	if len(versions) == 0 || versions[len(versions)-1].Deleted || versions[len(versions)-1].Destroyed {
+		return nil, false
+	}
+
+	return versions[len(versions)-1].Data, true
+}
+
+func (kv *VaultKV) handle(w http.ResponseWriter, r *http.Request) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Namespace") != kv.Namespace {
+		vaultFault(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/v1/auth/") {
+		kv.login(w, r)
+		return
+	}
+
+	if token := r.Header.Get("X-Vault-Token"); token != kv.Token && !kv.issued[token] {
+		vaultFault(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	route := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if kv.tfc(w, r, route) {
+		return
+	}
+
+	if path, ok := strings.CutPrefix(route, "sys/internal/ui/mounts/"); ok {
+		mount, version, added := kv.mount(path)
+		if !added || r.Method != http.MethodGet {
+			vaultFault(w, http.StatusNotFound)
+			return
+		}
+
+		var options map[string]interface{}
+		if version == 2 {
+			options = map[string]interface{}{"version": "2"}
+		}
+
+		vaultResponse(w, map[string]interface{}{
+			"path":    mount + "/",
+			"type":    "kv",
+			"options": options,
+		})
+
+		return
+	}
+
+	mount, version, _ := kv.mount(route)
+	path := strings.TrimPrefix(route, mount+"/")
+	endpoint := "data"
+	if version == 2 {
+		endpoint, path, _ = strings.Cut(path, "/")
+	}
+
+	if path == "" || path == route || !kvEndpoints[endpoint] {
+		vaultFault(w, http.StatusNotFound, "no handler for route '"+r.URL.Path+"'")
+		return
+	}
+
+	key := mount + "/" + path
+	switch endpoint {
+	case "data":
+		kv.data(w, r, key, version)
+	case "metadata":
+		kv.metadata(w, r, key)
+	default:
+		kv.lifecycle(w, r, key, endpoint)
+	}
+}
+
+// kvEndpoints are the endpoints of a KV version 2 mount that the fake answers
+var kvEndpoints = map[string]bool{
+	"data":     true,
+	"delete":   true,
+	"destroy":  true,
+	"metadata": true,
+	"undelete": true,
+}
+
+// data reads, writes and deletes the secret. Deleting a KV version 2 secret soft deletes its
+// latest version
+func (kv *VaultKV) data(w http.ResponseWriter, r *http.Request, key string, version int) {
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		var body struct {
+			Data    map[string]interface{} `json:"data"`
+			Options struct {
+				CAS *int `json:"cas"`
+			} `json:"options"`
+		}
+
+		var err error
+		if version == 2 {
+			err = json.NewDecoder(r.Body).Decode(&body)
+		} else {
+			err = json.NewDecoder(r.Body).Decode(&body.Data)
+		}
+
+		if err != nil || body.Data == nil {
+			vaultFault(w, http.StatusBadRequest, "no data provided")
+			return
+		}
+
+		if version == 1 {
+			kv.secrets[key] = body.Data
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if body.Options.CAS != nil && *body.Options.CAS != len(kv.versions[key]) {
+			vaultFault(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			return
+		}
+
+		kv.versions[key] = append(kv.versions[key], VaultVersion{Data: body.Data})
+		vaultResponse(w, map[string]interface{}{
+			"version": len(kv.versions[key]),
+		})
+	case http.MethodGet:
+		data, exists := kv.latest(key)
+		if !exists {
+			vaultFault(w, http.StatusNotFound)
+			return
+		}
+
+		if version == 1 {
+			vaultResponse(w, data)
+			return
+		}
+
+		vaultResponse(w, map[string]interface{}{
+			"data": data,
+			"metadata": map[string]interface{}{
+				"version": len(kv.versions[key]),
+			},
+		})
+	case http.MethodDelete:
+		delete(kv.secrets, key)
+		if versions := kv.versions[key]; len(versions) > 0 {
+			versions[len(versions)-1].Deleted = true
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		vaultFault(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+// metadata reads the versions of the secret, or deletes the secret with every version
+func (kv *VaultKV) metadata(w http.ResponseWriter, r *http.Request, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		if len(kv.versions[key]) == 0 {
+			vaultFault(w, http.StatusNotFound)
+			return
+		}
+
+		versions := make(map[string]interface{})
+		for i, version := range kv.versions[key] {
+			deletionTime := ""
+			if version.Deleted {
+				deletionTime = "2018-03-22T02:24:06.945319214Z"
+			}
+
+			versions[fmt.Sprint(i+1)] = map[string]interface{}{
+				"deletion_time": deletionTime,
+				"destroyed":     version.Destroyed,
+			}
+		}
+
+		vaultResponse(w, map[string]interface{}{
+			"current_version": len(kv.versions[key]),
+			"versions":        versions,
+		})
+	case http.MethodDelete:
+		delete(kv.versions, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		vaultFault(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+// lifecycle soft deletes, undeletes or destroys the versions of the secret in the request.
+// Versions that don't exist are ignored like Vault does
+func (kv *VaultKV) lifecycle(w http.ResponseWriter, r *http.Request, key string, endpoint string) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		vaultFault(w, http.StatusMethodNotAllowed, "unsupported operation")
+		return
+	}
+
+	var body struct {
+		Versions []int `json:"versions"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Versions) == 0 {
+		vaultFault(w, http.StatusBadRequest, "no version number provided")
+		return
+	}
+
+	versions := kv.versions[key]
+	for _, number := range body.Versions {
+		if number < 1 || number > len(versions) {
+			continue
+		}
+
+		version := &versions[number-1]
+		switch endpoint {
+		case "delete":
+			version.Deleted = true
+		case "undelete":
+			version.Deleted = version.Deleted && version.Destroyed
+		case "destroy":
+			version.Data = nil
+			version.Destroyed = true
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// login issues a token when the request matches a login the fake accepts
+func (kv *VaultKV) login(w http.ResponseWriter, r *http.Request) {
+	var data map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&data)
+	if err != nil || (r.Method != http.MethodPut && r.Method != http.MethodPost) {
+		vaultFault(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	for _, login := range kv.logins {
+		if login.Path != path || !reflect.DeepEqual(login.Data, data) {
+			continue
+		}
+
+		if login.ClientCert && (r.TLS == nil || len(r.TLS.PeerCertificates) == 0) {
+			vaultFault(w, http.StatusBadRequest, "client certificate must be supplied")
+			return
+		}
+
+		token := fmt.Sprintf("hvs.vaulttest%d", len(kv.issued)+1)
+		kv.issued[token] = true
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   token,
+				"lease_duration": 3600,
+				"renewable":      true,
+			},
+		})
+
+		return
+	}
+
+	vaultFault(w, http.StatusBadRequest, "invalid credentials")
+}
+
+// vaultResponse writes the data in the format of a Vault secret
+func vaultResponse(w http.ResponseWriter, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": data,
+	})
+}
+
+// vaultFault writes an error in the format of the Vault API
+func vaultFault(w http.ResponseWriter, status int, errs ...string) {
+	if errs == nil {
+		errs = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": errs,
+	})
+}
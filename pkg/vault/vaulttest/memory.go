@@ -0,0 +1,178 @@
+// Package vaulttest provides an in-memory TerraVault, local HTTP fakes of the vault provider
+// APIs, and a conformance suite that every TerraVault implementation runs
+package vaulttest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+)
+
+// Memory is an in-memory vault. The TerraVaults it returns share its secrets, so a secret
+// stored through one of them can be read through another one with the same name
+type Memory struct {
+	mu      sync.Mutex
+	err     error
+	secrets map[string]string
+}
+
+// MemoryVault is the TerraVault for a single secret in a Memory vault
+type MemoryVault struct {
+	Memory     *Memory
+	SecretName string
+}
+
+// NewMemory returns an empty in-memory vault
+func NewMemory() *Memory {
+	return &Memory{
+		secrets: make(map[string]string),
+	}
+}
+
+// Vault returns the TerraVault for the secret
+func (m *Memory) Vault(secretName string) vault.TerraVault {
+	return &MemoryVault{
+		Memory:     m,
+		SecretName: secretName,
+	}
+}
+
+// Secret returns the value of the secret and whether it exists
+func (m *Memory) Secret(secretName string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.secrets[secretName]
+	return value, ok
+}
+
+// Len returns the number of secrets in the vault
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.secrets)
+}
+
+// SetError makes every operation fail with the error until it's set to nil, to simulate
+// a vault that's unavailable or denies access
+func (m *Memory) SetError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+}
+
+// check returns the error set with SetError or the error of the context
+func (m *Memory) check(ctx context.Context) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", terraerrors.ErrUnavailable, err)
+	}
+
+	return nil
+}
+
+// Create stores the secret. A secret that's created when it already exists returns ErrConflict
+// and a secret that's updated when it doesn't exist returns ErrNotFound
+func (mv *MemoryVault) Create(ctx context.Context, secretValue string, method string) error {
+	mv.Memory.mu.Lock()
+	defer mv.Memory.mu.Unlock()
+
+	if err := mv.Memory.check(ctx); err != nil {
+		return err
+	}
+
+	_, exists := mv.Memory.secrets[mv.SecretName]
+	if method == "Created" && exists {
+		return fmt.Errorf("%w: %s already exists", terraerrors.ErrConflict, mv.SecretName)
+	}
+
+	if method == "Updated" && !exists {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, mv.SecretName)
+	}
+
+	mv.Memory.secrets[mv.SecretName] = secretValue
+	return nil
+}
+
+// Delete removes the secret
+func (mv *MemoryVault) Delete(ctx context.Context) error {
+	mv.Memory.mu.Lock()
+	defer mv.Memory.mu.Unlock()
+
+	if err := mv.Memory.check(ctx); err != nil {
+		return err
+	}
+
+	if _, ok := mv.Memory.secrets[mv.SecretName]; !ok {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, mv.SecretName)
+	}
+
+	delete(mv.Memory.secrets, mv.SecretName)
+	return nil
+}
+
+// Get returns the value of the secret
+func (mv *MemoryVault) Get(ctx context.Context) ([]byte, error) {
+	mv.Memory.mu.Lock()
+	defer mv.Memory.mu.Unlock()
+
+	if err := mv.Memory.check(ctx); err != nil {
+		return nil, err
+	}
+
+	value, ok := mv.Memory.secrets[mv.SecretName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, mv.SecretName)
+	}
+
+	return []byte(value), nil
+}
+
+// List returns the values of the secrets
+func (mv *MemoryVault) List(ctx context.Context, secretNames []string) ([]string, error) {
+	mv.Memory.mu.Lock()
+	defer mv.Memory.mu.Unlock()
+
+	if err := mv.Memory.check(ctx); err != nil {
+		return nil, err
+	}
+
+	var secretValues []string
+	for _, secretName := range secretNames {
+		value, ok := mv.Memory.secrets[secretName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+		}
+
+		secretValues = append(secretValues, value)
+	}
+
+	return secretValues, nil
+}
+
+// Names returns the names of the secrets in the vault
+func (mv *MemoryVault) Names(ctx context.Context) ([]string, error) {
+	mv.Memory.mu.Lock()
+	defer mv.Memory.mu.Unlock()
+
+	if err := mv.Memory.check(ctx); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(mv.Memory.secrets))
+	for name := range mv.Memory.secrets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
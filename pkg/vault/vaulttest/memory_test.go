@@ -0,0 +1,43 @@
+package vaulttest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	memory := NewMemory()
+
+	Run(t, func(t *testing.T, secretName string) vault.TerraVault {
+		return memory.Vault(secretName)
+	})
+
+	if memory.Len() != 0 {
+		t.Errorf("expected the suite to remove its secrets, %d are left", memory.Len())
+	}
+}
+
+func TestMemorySetError(t *testing.T) {
+	memory := NewMemory()
+	memory.SetError(terraerrors.ErrUnavailable)
+
+	_, err := memory.Vault("app.terraform.io").Get(context.Background())
+	if !errors.Is(err, terraerrors.ErrUnavailable) {
+		t.Errorf("expected the error set with SetError: %v", err)
+	}
+
+	memory.SetError(nil)
+	err = memory.Vault("app.terraform.io").Create(context.Background(), "token", "Created")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = memory.Vault("app.terraform.io").Create(context.Background(), "token", "Created")
+	if !errors.Is(err, terraerrors.ErrConflict) {
+		t.Errorf("expected a conflict when creating an existing secret: %v", err)
+	}
+}
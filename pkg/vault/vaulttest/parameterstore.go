@@ -0,0 +1,212 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Parameter is a parameter stored in the ParameterStore fake
+type Parameter struct {
+	Description string
+	KeyID       string
+	Type        string
+	Value       string
+	Version     int
+}
+
+// ParameterStore is a local fake of the AWS Systems Manager Parameter Store JSON API. Values
+// are stored in plain text and a SecureString is only masked when it's read without decryption
+type ParameterStore struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	parameters map[string]*Parameter
+	requests   map[string]int
+}
+
+// NewParameterStore starts a fake Parameter Store that's closed when the test ends. Point the
+// AWS SDK at its URL as the base endpoint
+func NewParameterStore(t *testing.T) *ParameterStore {
+	ps := &ParameterStore{
+		parameters: make(map[string]*Parameter),
+		requests:   make(map[string]int),
+	}
+
+	ps.Server = httptest.NewServer(http.HandlerFunc(ps.handle))
+	t.Cleanup(ps.Close)
+
+	return ps
+}
+
+// Parameter returns the parameter and whether it exists
+func (ps *ParameterStore) Parameter(name string) (Parameter, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	parameter, ok := ps.parameters[name]
+	if !ok {
+		return Parameter{}, false
+	}
+
+	return *parameter, true
+}
+
+// Requests returns the number of requests made for the operation such as 'GetParameters'
+func (ps *ParameterStore) Requests(operation string) int {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	return ps.requests[operation]
+}
+
+// ssmRequest holds the fields of the Parameter Store requests that terracreds sends
+type ssmRequest struct {
+	Description    string
+	KeyId          string
+	MaxResults     int
+	Name           string
+	Names          []string
+	NextToken      string
+	Overwrite      bool
+	Path           string
+	Recursive      bool
+	Type           string
+	Value          string
+	WithDecryption bool
+}
+
+// response returns the parameter in the format of the API
+func (ps *ParameterStore) response(name string, withDecryption bool) map[string]interface{} {
+	parameter := ps.parameters[name]
+	value := parameter.Value
+	if parameter.Type == "SecureString" && !withDecryption {
+		value = "AQICAHg-encrypted"
+	}
+
+	return map[string]interface{}{
+		"ARN":     fmt.Sprintf("arn:aws:ssm:us-east-1:123456789012:parameter/%s", strings.TrimPrefix(name, "/")),
+		"Name":    name,
+		"Type":    parameter.Type,
+		"Value":   value,
+		"Version": parameter.Version,
+	}
+}
+
+func (ps *ParameterStore) handle(w http.ResponseWriter, r *http.Request) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var request ssmRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		awsFault(w, "SerializationException", err.Error())
+		return
+	}
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.")
+	ps.requests[operation]++
+
+	response := map[string]interface{}{}
+	switch operation {
+	case "PutParameter":
+		existing, exists := ps.parameters[request.Name]
+		if exists && !request.Overwrite {
+			awsFault(w, "ParameterAlreadyExists", "The parameter already exists. To overwrite this value, set the overwrite option in the request to true.")
+			return
+		}
+
+		version := 1
+		if exists {
+			version = existing.Version + 1
+		}
+
+		ps.parameters[request.Name] = &Parameter{
+			Description: request.Description,
+			KeyID:       request.KeyId,
+			Type:        request.Type,
+			Value:       request.Value,
+			Version:     version,
+		}
+
+		response["Version"] = version
+		response["Tier"] = "Standard"
+	case "GetParameter":
+		if _, ok := ps.parameters[request.Name]; !ok {
+			awsFault(w, "ParameterNotFound", "")
+			return
+		}
+
+		response["Parameter"] = ps.response(request.Name, request.WithDecryption)
+	case "GetParameters":
+		if len(request.Names) > 10 {
+			awsFault(w, "ValidationException", "1 validation error detected: Value at 'names' failed to satisfy constraint: Member must have length less than or equal to 10")
+			return
+		}
+
+		parameters := []interface{}{}
+		invalid := []string{}
+		for _, name := range request.Names {
+			if _, ok := ps.parameters[name]; !ok {
+				invalid = append(invalid, name)
+				continue
+			}
+
+			parameters = append(parameters, ps.response(name, request.WithDecryption))
+		}
+
+		response["Parameters"] = parameters
+		response["InvalidParameters"] = invalid
+	case "GetParametersByPath":
+		path := strings.TrimSuffix(request.Path, "/") + "/"
+		var names []string
+		for name := range ps.parameters {
+			if !strings.HasPrefix(name, path) {
+				continue
+			}
+
+			if !request.Recursive && strings.Contains(strings.TrimPrefix(name, path), "/") {
+				continue
+			}
+
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		start, _ := strconv.Atoi(request.NextToken)
+		maxResults := request.MaxResults
+		if maxResults == 0 {
+			maxResults = 10
+		}
+
+		end := min(start+maxResults, len(names))
+		parameters := []interface{}{}
+		for _, name := range names[start:end] {
+			parameters = append(parameters, ps.response(name, request.WithDecryption))
+		}
+
+		response["Parameters"] = parameters
+		if end < len(names) {
+			response["NextToken"] = strconv.Itoa(end)
+		}
+	case "DeleteParameter":
+		if _, ok := ps.parameters[request.Name]; !ok {
+			awsFault(w, "ParameterNotFound", "")
+			return
+		}
+
+		delete(ps.parameters, request.Name)
+	default:
+		awsFault(w, "ValidationException", fmt.Sprintf("The operation %s is not supported by the fake", operation))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,135 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SecretsManager is a local fake of the AWS Secrets Manager JSON API. Deleted secrets are
+// scheduled for deletion like they are by the service, and stay so until they're restored
+type SecretsManager struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	deleted map[string]bool
+	secrets map[string]string
+}
+
+// NewSecretsManager starts a fake Secrets Manager that's closed when the test ends. Point the
+// AWS SDK at its URL as the base endpoint
+func NewSecretsManager(t *testing.T) *SecretsManager {
+	sm := &SecretsManager{
+		deleted: make(map[string]bool),
+		secrets: make(map[string]string),
+	}
+
+	sm.Server = httptest.NewServer(http.HandlerFunc(sm.handle))
+	t.Cleanup(sm.Close)
+
+	return sm
+}
+
+// Secret returns the value of the secret and whether it exists. A secret that's scheduled
+// for deletion doesn't exist
+func (sm *SecretsManager) Secret(name string) (string, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	value, ok := sm.secrets[name]
+	return value, ok && !sm.deleted[name]
+}
+
+// Deleted returns whether the secret is scheduled for deletion
+func (sm *SecretsManager) Deleted(name string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	return sm.deleted[name]
+}
+
+// awsRequest holds the fields of the Secrets Manager requests that terracreds sends
+type awsRequest struct {
+	Name         string
+	SecretId     string
+	SecretString string
+}
+
+func (sm *SecretsManager) handle(w http.ResponseWriter, r *http.Request) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var request awsRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		awsFault(w, "SerializationException", err.Error())
+		return
+	}
+
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager.")
+	name := request.SecretId
+	if operation == "CreateSecret" {
+		name = request.Name
+	}
+
+	value, exists := sm.secrets[name]
+	if operation != "CreateSecret" && !exists {
+		awsFault(w, "ResourceNotFoundException", "Secrets Manager can't find the specified secret.")
+		return
+	}
+
+	if operation == "CreateSecret" && sm.deleted[name] {
+		awsFault(w, "InvalidRequestException", "You can't create this secret because a secret with this name is already scheduled for deletion.")
+		return
+	}
+
+	if operation != "CreateSecret" && operation != "RestoreSecret" && sm.deleted[name] {
+		awsFault(w, "InvalidRequestException", "You can't perform this operation on the secret because it was marked for deletion.")
+		return
+	}
+
+	response := map[string]interface{}{
+		"ARN":  fmt.Sprintf("arn:aws:secretsmanager:us-east-1:123456789012:secret:%s", name),
+		"Name": name,
+	}
+
+	switch operation {
+	case "CreateSecret":
+		if exists {
+			awsFault(w, "ResourceExistsException", fmt.Sprintf("The operation failed because the secret %s already exists.", name))
+			return
+		}
+
+		sm.secrets[name] = request.SecretString
+	case "PutSecretValue":
+		sm.secrets[name] = request.SecretString
+	case "GetSecretValue":
+		response["SecretString"] = value
+	case "DeleteSecret":
+		sm.deleted[name] = true
+	case "RestoreSecret":
+		delete(sm.deleted, name)
+	default:
+		awsFault(w, "InvalidRequestException", fmt.Sprintf("The operation %s is not supported by the fake", operation))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(response)
+}
+
+// awsFault writes an error in the format of the AWS JSON protocol
+func awsFault(w http.ResponseWriter, code string, message string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(http.StatusBadRequest)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"__type":  code,
+		"Message": message,
+	})
+}
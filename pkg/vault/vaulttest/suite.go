@@ -0,0 +1,152 @@
+package vaulttest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/vault"
+)
+
+// Factory returns the provider's TerraVault for the secret name. It's called more than once
+// for the same name and each TerraVault must see the secrets stored by the others
+type Factory func(t *testing.T, secretName string) vault.TerraVault
+
+// Run checks that the TerraVaults returned by the factory behave the way terracreds expects.
+// Secret names are hostnames, so providers that can't store periods must normalize the names
+// the same way on every operation
+func Run(t *testing.T, factory Factory) {
+	t.Helper()
+
+	t.Run("MissingSecret", func(t *testing.T) {
+		testMissingSecret(t, factory)
+	})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		testLifecycle(t, factory)
+	})
+
+	t.Run("NameNormalization", func(t *testing.T) {
+		testNameNormalization(t, factory)
+	})
+}
+
+// testContext returns a context with a deadline for a single test
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+// testMissingSecret checks that a secret that doesn't exist is reported with ErrNotFound
+func testMissingSecret(t *testing.T, factory Factory) {
+	ctx := testContext(t)
+	secretName := "missing.terracreds.test"
+	terraVault := factory(t, secretName)
+
+	_, err := terraVault.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("Get of a missing secret returned '%v' expected ErrNotFound", err)
+	}
+
+	_, err = terraVault.List(ctx, []string{secretName})
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("List of a missing secret returned '%v' expected ErrNotFound", err)
+	}
+
+	err = terraVault.Delete(ctx)
+	if err != nil && !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("Delete of a missing secret returned '%v' expected nil or ErrNotFound", err)
+	}
+}
+
+// testLifecycle checks that a secret can be created, updated, read, listed and deleted
+func testLifecycle(t *testing.T, factory Factory) {
+	ctx := testContext(t)
+	secretName := "lifecycle.terracreds.test"
+	terraVault := factory(t, secretName)
+
+	err := terraVault.Create(ctx, "first-token", "Created")
+	if err != nil {
+		t.Fatalf("Create returned '%v'", err)
+	}
+
+	assertSecret(ctx, t, terraVault, "first-token")
+
+	err = terraVault.Create(ctx, "second-token", "Updated")
+	if err != nil {
+		t.Fatalf("Create of an update returned '%v'", err)
+	}
+
+	assertSecret(ctx, t, terraVault, "second-token")
+
+	secrets, err := terraVault.List(ctx, []string{secretName})
+	if err != nil {
+		t.Fatalf("List returned '%v'", err)
+	}
+
+	if len(secrets) != 1 || secrets[0] != "second-token" {
+		t.Errorf("List returned %v expected [second-token]", secrets)
+	}
+
+	_, err = terraVault.List(ctx, []string{secretName, "missing.terracreds.test"})
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("List with a missing secret returned '%v' expected ErrNotFound", err)
+	}
+
+	err = terraVault.Delete(ctx)
+	if err != nil {
+		t.Fatalf("Delete returned '%v'", err)
+	}
+
+	_, err = terraVault.Get(ctx)
+	if !errors.Is(err, terraerrors.ErrNotFound) {
+		t.Errorf("Get of a deleted secret returned '%v' expected ErrNotFound", err)
+	}
+}
+
+// testNameNormalization checks that a secret stored under a hostname can be read back by the
+// same hostname through another TerraVault
+func testNameNormalization(t *testing.T, factory Factory) {
+	ctx := testContext(t)
+	secretName := "app.terraform.io"
+
+	err := factory(t, secretName).Create(ctx, "hostname-token", "Created")
+	if err != nil {
+		t.Fatalf("Create returned '%v'", err)
+	}
+
+	terraVault := factory(t, secretName)
+	assertSecret(ctx, t, terraVault, "hostname-token")
+
+	secrets, err := terraVault.List(ctx, []string{secretName})
+	if err != nil {
+		t.Fatalf("List returned '%v'", err)
+	}
+
+	if len(secrets) != 1 || secrets[0] != "hostname-token" {
+		t.Errorf("List returned %v expected [hostname-token]", secrets)
+	}
+
+	err = terraVault.Delete(ctx)
+	if err != nil {
+		t.Errorf("Delete returned '%v'", err)
+	}
+}
+
+// assertSecret checks the value returned by Get
+func assertSecret(ctx context.Context, t *testing.T, terraVault vault.TerraVault, expected string) {
+	t.Helper()
+
+	value, err := terraVault.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get returned '%v'", err)
+	}
+
+	if string(value) != expected {
+		t.Errorf("Get returned '%s' expected '%s'", value, expected)
+	}
+}
@@ -0,0 +1,157 @@
+package vaulttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// VaultTFCRole is a role of the Terraform Cloud secrets engine that the fake issues tokens for
+type VaultTFCRole struct {
+	// TTL is the duration of the leases of the tokens. The tokens of a role without a TTL aren't
+	// leased like the tokens of organization and team roles
+	TTL time.Duration
+
+	// MaxTTL is the longest a lease lasts when it's renewed. Leases of a role without a maximum
+	// TTL can't be renewed
+	MaxTTL time.Duration
+}
+
+// VaultLease is a lease of a token that the fake issued
+type VaultLease struct {
+	// ID is the lease ID such as 'terraform/creds/ci/1'
+	ID string
+
+	// Token is the token of the lease
+	Token string
+
+	// Expires is the time the lease expires
+	Expires time.Time
+
+	// Renewals is the number of times the lease was renewed
+	Renewals int
+
+	// Revoked is set when the lease was revoked
+	Revoked bool
+
+	issued time.Time
+	maxTTL time.Duration
+	ttl    time.Duration
+}
+
+// AddTFCRole makes the fake issue tokens when the path of the role, such as 'terraform/creds/ci', is read
+func (kv *VaultKV) AddTFCRole(path string, role VaultTFCRole) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.roles[path] = role
+}
+
+// Leases returns the leases of the tokens the fake issued, oldest first
+func (kv *VaultKV) Leases() []VaultLease {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	var leases []VaultLease
+	for _, lease := range kv.leases {
+		leases = append(leases, *lease)
+	}
+
+	return leases
+}
+
+// lease returns the lease with the ID, or nil when the fake didn't issue it
+func (kv *VaultKV) lease(id string) *VaultLease {
+	for _, lease := range kv.leases {
+		if lease.ID == id {
+			return lease
+		}
+	}
+
+	return nil
+}
+
+// tfc answers the requests for the tokens of the roles and the renewal and revocation of their
+// leases, and reports whether the route belongs to them
+func (kv *VaultKV) tfc(w http.ResponseWriter, r *http.Request, route string) bool {
+	role, isRole := kv.roles[route]
+	if !isRole && route != "sys/leases/renew" && route != "sys/leases/revoke" {
+		return false
+	}
+
+	if isRole {
+		if r.Method != http.MethodGet {
+			vaultFault(w, http.StatusMethodNotAllowed, "unsupported operation")
+			return true
+		}
+
+		token := fmt.Sprintf("tfc-token-%d", len(kv.leases)+1)
+		if role.TTL == 0 {
+			vaultResponse(w, map[string]interface{}{"token": token, "token_id": "at-vaulttest"})
+			return true
+		}
+
+		lease := &VaultLease{
+			ID:      fmt.Sprintf("%s/%d", route, len(kv.leases)+1),
+			Token:   token,
+			Expires: time.Now().Add(role.TTL),
+			issued:  time.Now(),
+			maxTTL:  role.MaxTTL,
+			ttl:     role.TTL,
+		}
+
+		kv.leases = append(kv.leases, lease)
+		vaultLease(w, lease, map[string]interface{}{"token": token, "token_id": "at-vaulttest"})
+		return true
+	}
+
+	var body struct {
+		LeaseID string `json:"lease_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (r.Method != http.MethodPut && r.Method != http.MethodPost) {
+		vaultFault(w, http.StatusBadRequest, "invalid request")
+		return true
+	}
+
+	lease := kv.lease(body.LeaseID)
+	if route == "sys/leases/revoke" {
+		if lease != nil {
+			lease.Revoked = true
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	if lease == nil || lease.Revoked || time.Now().After(lease.Expires) {
+		vaultFault(w, http.StatusBadRequest, "lease not found")
+		return true
+	}
+
+	if lease.maxTTL == 0 {
+		vaultFault(w, http.StatusBadRequest, "lease is not renewable")
+		return true
+	}
+
+	lease.Expires = time.Now().Add(lease.ttl)
+	if limit := lease.issued.Add(lease.maxTTL); lease.Expires.After(limit) {
+		lease.Expires = limit
+	}
+
+	lease.Renewals++
+	vaultLease(w, lease, nil)
+	return true
+}
+
+// vaultLease writes the data in the format of a Vault secret with the lease
+func vaultLease(w http.ResponseWriter, lease *VaultLease, data map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":           data,
+		"lease_id":       lease.ID,
+		"lease_duration": int(time.Until(lease.Expires).Round(time.Second).Seconds()),
+		"renewable":      lease.maxTTL > 0,
+	})
+}
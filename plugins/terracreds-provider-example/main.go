@@ -0,0 +1,157 @@
+// Command terracreds-provider-example is the reference terracreds provider plugin. It stores
+// secrets in a JSON file and shows how a plugin uses the plugin package to answer terracreds.
+// Configure it with:
+//
+//	plugin:
+//	  name: example
+//	  file: ~/.terracreds-example.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	terraerrors "github.com/tonedefdev/terracreds/pkg/errors"
+	"github.com/tonedefdev/terracreds/pkg/plugin"
+)
+
+// fileStore stores the secrets in the JSON file set by the 'file' key of the plugin's block
+type fileStore struct{}
+
+func main() {
+	plugin.Serve(&plugin.Server{
+		Handler: &fileStore{},
+	})
+}
+
+// path returns the path to the JSON file
+func (fs *fileStore) path(request *plugin.Request) (string, error) {
+	path, _ := request.Config["file"].(string)
+	if path == "" {
+		path = "~/.terracreds-example.json"
+	}
+
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		path = filepath.Join(home, path[1:])
+	}
+
+	return path, nil
+}
+
+// load reads the secrets from the JSON file
+func (fs *fileStore) load(request *plugin.Request) (map[string]string, error) {
+	path, err := fs.path(request)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string]string)
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
+	}
+
+	err = json.Unmarshal(bytes, &secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
+
+// save writes the secrets to the JSON file
+func (fs *fileStore) save(request *plugin.Request, secrets map[string]string) error {
+	path, err := fs.path(request)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, bytes, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %w", terraerrors.ErrPermissionDenied, err)
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Create stores the secret in the file
+func (fs *fileStore) Create(ctx context.Context, request *plugin.Request) error {
+	secrets, err := fs.load(request)
+	if err != nil {
+		return err
+	}
+
+	secrets[request.SecretName] = request.SecretValue
+	fmt.Fprintf(os.Stderr, "%s the secret '%s'\n", strings.ToLower(request.Method), request.SecretName)
+	return fs.save(request, secrets)
+}
+
+// Delete removes the secret from the file
+func (fs *fileStore) Delete(ctx context.Context, request *plugin.Request) error {
+	secrets, err := fs.load(request)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[request.SecretName]; !ok {
+		return fmt.Errorf("%w: %s", terraerrors.ErrNotFound, request.SecretName)
+	}
+
+	delete(secrets, request.SecretName)
+	fmt.Fprintf(os.Stderr, "deleted the secret '%s'\n", request.SecretName)
+	return fs.save(request, secrets)
+}
+
+// Get returns the secret from the file
+func (fs *fileStore) Get(ctx context.Context, request *plugin.Request) (string, error) {
+	secrets, err := fs.load(request)
+	if err != nil {
+		return "", err
+	}
+
+	secret, ok := secrets[request.SecretName]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", terraerrors.ErrNotFound, request.SecretName)
+	}
+
+	return secret, nil
+}
+
+// List returns the secrets from the file
+func (fs *fileStore) List(ctx context.Context, request *plugin.Request) ([]string, error) {
+	secrets, err := fs.load(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, secretName := range request.SecretNames {
+		secret, ok := secrets[secretName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", terraerrors.ErrNotFound, secretName)
+		}
+
+		values = append(values, secret)
+	}
+
+	return values, nil
+}
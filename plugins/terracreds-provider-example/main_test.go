@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tonedefdev/terracreds/pkg/plugin"
+	"github.com/tonedefdev/terracreds/pkg/plugin/plugintest"
+)
+
+func TestConformance(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "terracreds-provider-example")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	build := exec.Command("go", "build", "-o", binary, ".")
+	output, err := build.CombinedOutput()
+	if err != nil {
+		t.Fatalf("unable to build the plugin: %v\n%s", err, output)
+	}
+
+	var stderr []string
+	client := &plugin.Client{
+		Name: "example",
+		Path: binary,
+		Config: map[string]interface{}{
+			"file": filepath.Join(dir, "secrets.json"),
+		},
+		Stderr: func(line string) {
+			stderr = append(stderr, line)
+		},
+	}
+
+	plugintest.Run(t, client)
+
+	if !strings.Contains(strings.Join(stderr, "\n"), "created the secret") {
+		t.Errorf("expected the plugin's stderr to be captured: %v", stderr)
+	}
+}